@@ -239,10 +239,23 @@ func (s *StorageClient) UploadFile(ctx context.Context, localPath, gcsPath strin
 		return fmt.Errorf("failed to create GCS client: %w", err)
 	}
 
+	// Create upload options from config
+	// In the client library, use config parallelism as a limit for max chunks
+	maxChunks := s.config.Upload.MaxChunks
+	if s.config.Defaults.Parallelism < maxChunks {
+		maxChunks = s.config.Defaults.Parallelism
+	}
+
+	opts := &storage.UploadOptions{
+		ParallelThreshold: s.config.Upload.ParallelThreshold,
+		ChunkSize:         s.config.Upload.ChunkSize,
+		MaxChunks:         maxChunks,
+	}
+
 	// Use simple formatter (no alias reverse mapping for library API)
 	formatter := func(path string) string { return path }
 
-	return storage.UploadFile(ctx, client, localPath, fullPath, false, formatter)
+	return storage.UploadFile(ctx, client, localPath, fullPath, false, formatter, opts)
 }
 
 // RemoveObject removes a single object from GCS.
@@ -268,7 +281,7 @@ func (s *StorageClient) RemoveObject(ctx context.Context, gcsPath string) error
 	// Use simple formatter (no alias reverse mapping for library API)
 	formatter := func(path string) string { return path }
 
-	return storage.RemoveObject(ctx, client, bucket, object, false, formatter)
+	return storage.RemoveObject(ctx, client, bucket, object, false, formatter, storage.LiveVersions, nil)
 }
 
 // BigQueryClient provides methods for interacting with Google BigQuery.
@@ -20,6 +20,10 @@ type ServerConfig struct {
 type BillingConfig struct {
 	Table         string `yaml:"table"`          // BigQuery billing export table (project.dataset.table)
 	DetailedTable string `yaml:"detailed_table"` // Detailed billing export table (optional)
+
+	// StoragePrices overrides the built-in GCS storage prices (USD per
+	// GiB-month, keyed by storage class) used for cost estimates
+	StoragePrices map[string]float64 `yaml:"storage_prices,omitempty"`
 }
 
 // Config represents the application configuration
@@ -28,8 +32,14 @@ type Config struct {
 	Defaults Defaults          `yaml:"defaults"`
 	Server   ServerConfig      `yaml:"server"`
 	Download DownloadConfig    `yaml:"download"`
+	Upload   UploadConfig      `yaml:"upload"`
+	Retry    RetryConfig       `yaml:"retry"`
 	Billing  BillingConfig     `yaml:"billing"`
+	Rm       RmConfig          `yaml:"rm,omitempty"`
 	filePath string            // Store the path where config was loaded from
+
+	// Encryption maps an alias or gs:// location to its encryption keys
+	Encryption map[string]EncryptionConfig `yaml:"encryption,omitempty"`
 }
 
 // GetFilePath returns the path where the config was loaded from
@@ -140,6 +150,17 @@ func getDefaultConfig(filePath string) *Config {
 			ChunkSize:         DefaultChunkSize,
 			MaxChunks:         DefaultMaxChunks,
 		},
+		Upload: UploadConfig{
+			ParallelThreshold: DefaultUploadParallelThreshold,
+			ChunkSize:         DefaultUploadChunkSize,
+			MaxChunks:         DefaultUploadMaxChunks,
+		},
+		Retry: RetryConfig{
+			MaxAttempts:    DefaultRetryMaxAttempts,
+			InitialBackoff: DefaultRetryInitialBackoff,
+			MaxBackoff:     DefaultRetryMaxBackoff,
+			Multiplier:     DefaultRetryMultiplier,
+		},
 		filePath: filePath,
 	}
 }
@@ -170,6 +191,45 @@ func (c *Config) normalize() {
 	if c.Download.MaxChunks > MaxMaxChunks {
 		c.Download.MaxChunks = MaxMaxChunks
 	}
+
+	// Apply upload defaults if missing
+	if c.Upload.ParallelThreshold == 0 {
+		c.Upload.ParallelThreshold = DefaultUploadParallelThreshold
+	}
+	if c.Upload.ChunkSize == 0 {
+		c.Upload.ChunkSize = DefaultUploadChunkSize
+	}
+	if c.Upload.MaxChunks == 0 {
+		c.Upload.MaxChunks = DefaultUploadMaxChunks
+	}
+
+	// Validate and clamp upload values
+	if c.Upload.ChunkSize < MinUploadChunkSize {
+		c.Upload.ChunkSize = MinUploadChunkSize
+	}
+	if c.Upload.ChunkSize > MaxUploadChunkSize {
+		c.Upload.ChunkSize = MaxUploadChunkSize
+	}
+	if c.Upload.MaxChunks < MinMaxChunks {
+		c.Upload.MaxChunks = MinMaxChunks
+	}
+	if c.Upload.MaxChunks > MaxUploadMaxChunks {
+		c.Upload.MaxChunks = MaxUploadMaxChunks
+	}
+
+	// Apply retry defaults if missing
+	if c.Retry.MaxAttempts == 0 {
+		c.Retry.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if c.Retry.InitialBackoff == 0 {
+		c.Retry.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if c.Retry.MaxBackoff == 0 {
+		c.Retry.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if c.Retry.Multiplier == 0 {
+		c.Retry.Multiplier = DefaultRetryMultiplier
+	}
 }
 
 // expandEnvVars expands environment variables in configuration values
@@ -186,6 +246,19 @@ func (c *Config) expandEnvVars() {
 	// Expand in billing
 	c.Billing.Table = os.ExpandEnv(c.Billing.Table)
 	c.Billing.DetailedTable = os.ExpandEnv(c.Billing.DetailedTable)
+
+	// Expand in rm
+	c.Rm.Trash = os.ExpandEnv(c.Rm.Trash)
+
+	// Expand in encryption
+	for k, e := range c.Encryption {
+		e.KeyFile = os.ExpandEnv(e.KeyFile)
+		e.KMSKey = os.ExpandEnv(e.KMSKey)
+		for i, f := range e.DecryptionKeyFiles {
+			e.DecryptionKeyFiles[i] = os.ExpandEnv(f)
+		}
+		c.Encryption[k] = e
+	}
 }
 
 // AddMapping adds or updates a mapping
@@ -233,5 +306,34 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("invalid path for alias %q: must start with 'gs://', 'bq://', 'svc://', 'jobs://', 'worker://', or 'pubsub://'", alias)
 		}
 	}
+	if c.Retry.MaxAttempts < 1 {
+		return fmt.Errorf("invalid retry.max_attempts %d: must be at least 1", c.Retry.MaxAttempts)
+	}
+	if c.Retry.Multiplier < 1 {
+		return fmt.Errorf("invalid retry.multiplier %v: must be at least 1", c.Retry.Multiplier)
+	}
+	if c.Retry.InitialBackoff < 0 || c.Retry.MaxBackoff < c.Retry.InitialBackoff {
+		return fmt.Errorf("invalid retry backoff: need 0 <= initial_backoff <= max_backoff")
+	}
+	if c.Rm.Trash != "" && (!strings.HasPrefix(c.Rm.Trash, "gs://") || strings.TrimPrefix(c.Rm.Trash, "gs://") == "") {
+		return fmt.Errorf("invalid rm.trash %q: must be a gs://bucket/prefix/ location", c.Rm.Trash)
+	}
+	for location, e := range c.Encryption {
+		if !strings.HasPrefix(location, "gs://") {
+			// An alias that is no longer mapped is skipped with a warning,
+			// so removing a mapping does not break every command.
+			if path, ok := c.Mappings[location]; ok && !strings.HasPrefix(path, "gs://") {
+				return fmt.Errorf("invalid encryption entry %q: must be a GCS alias or a gs:// location", location)
+			}
+		}
+		if e.KeyFile != "" && e.KeyEnv != "" {
+			return fmt.Errorf("invalid encryption entry %q: set key_file or key_env, not both", location)
+		}
+	}
+	for class, price := range c.Billing.StoragePrices {
+		if price < 0 {
+			return fmt.Errorf("invalid storage price for %s: %v (must not be negative)", class, price)
+		}
+	}
 	return nil
 }
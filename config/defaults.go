@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 const (
 	// DefaultRegion is the default GCP region for operations
 	DefaultRegion = "europe-west3"
@@ -36,7 +38,7 @@ const (
 	// 8MB per chunk - good balance between memory usage and download speed
 	DefaultChunkSize = 8 * 1024 * 1024 // 8MB
 
-	// DefaultMaxChunks is the maximum number of parallel chunks per file
+	// DefaultMaxChunks is the maximum number of chunks downloaded in parallel per file
 	DefaultMaxChunks = 8
 
 	// MinChunkSize is the minimum allowed chunk size
@@ -50,6 +52,37 @@ const (
 
 	// MaxMaxChunks is the maximum allowed max chunks value
 	MaxMaxChunks = 32
+
+	// DefaultUploadParallelThreshold is the minimum file size (in bytes) to use parallel composite upload
+	// 64MB default - smaller files upload faster as a single stream
+	DefaultUploadParallelThreshold = 64 * 1024 * 1024 // 64MB
+
+	// DefaultUploadChunkSize is the size of each part for parallel composite uploads
+	DefaultUploadChunkSize = 32 * 1024 * 1024 // 32MB
+
+	// DefaultUploadMaxChunks is the maximum number of parts uploaded in parallel per file
+	DefaultUploadMaxChunks = 8
+
+	// MinUploadChunkSize is the minimum allowed upload part size
+	MinUploadChunkSize = 5 * 1024 * 1024 // 5MB
+
+	// MaxUploadChunkSize is the maximum allowed upload part size
+	MaxUploadChunkSize = 1024 * 1024 * 1024 // 1GB
+
+	// MaxUploadMaxChunks is the maximum allowed parallel part uploads (GCS composes at most 32 sources)
+	MaxUploadMaxChunks = 32
+
+	// DefaultRetryMaxAttempts is the default number of attempts per GCS request
+	DefaultRetryMaxAttempts = 5
+
+	// DefaultRetryInitialBackoff is the default bound of the first retry pause
+	DefaultRetryInitialBackoff = time.Second
+
+	// DefaultRetryMaxBackoff is the default upper bound of a retry pause
+	DefaultRetryMaxBackoff = 30 * time.Second
+
+	// DefaultRetryMultiplier is the default growth factor of the retry pause bound
+	DefaultRetryMultiplier = 2.0
 )
 
 // DownloadConfig holds download-specific configuration
@@ -62,11 +95,61 @@ type DownloadConfig struct {
 	MaxChunks int `yaml:"max_chunks"`
 }
 
+// UploadConfig holds upload-specific configuration
+type UploadConfig struct {
+	// ParallelThreshold is the minimum file size (in bytes) to use parallel composite upload
+	ParallelThreshold int64 `yaml:"parallel_threshold"`
+	// ChunkSize is the size of each part for parallel composite uploads
+	ChunkSize int64 `yaml:"chunk_size"`
+	// MaxChunks is the maximum number of parts uploaded in parallel per file
+	MaxChunks int `yaml:"max_chunks"`
+}
+
+// RetryConfig holds the retry policy for transient GCS errors
+type RetryConfig struct {
+	// MaxAttempts is the total number of attempts per request (1 disables retries)
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff bounds the first pause; pauses are randomized (jitter)
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	// MaxBackoff bounds every pause
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// Multiplier grows the pause bound after each attempt
+	Multiplier float64 `yaml:"multiplier"`
+	// RetryCodes lists the HTTP status codes treated as transient
+	// (default 408, 429, 500, 502, 503, 504)
+	RetryCodes []int `yaml:"retry_codes,omitempty"`
+}
+
+// EncryptionConfig holds the encryption keys for the objects of one alias
+// or gs:// location
+type EncryptionConfig struct {
+	// KeyFile or KeyEnv hold a base64 AES-256 customer-supplied key (CSEK)
+	// used to write objects and to read objects encrypted with it
+	KeyFile string `yaml:"key_file,omitempty"`
+	KeyEnv  string `yaml:"key_env,omitempty"`
+	// KMSKey is a Cloud KMS key name new objects are written with (CMEK);
+	// ignored when a CSEK is set
+	KMSKey string `yaml:"kms_key,omitempty"`
+	// DecryptionKeyFiles hold older CSEKs accepted for reading, e.g. while
+	// rotating keys with 'cio rewrite --rotate-key'
+	DecryptionKeyFiles []string `yaml:"decryption_key_files,omitempty"`
+}
+
+// RmConfig holds configuration for cio rm
+type RmConfig struct {
+	// Trash is a gs://bucket/prefix/ location; when set, rm moves GCS
+	// objects there instead of deleting them
+	Trash string `yaml:"trash,omitempty"`
+}
+
 // Defaults holds default configuration values
 type Defaults struct {
 	Region      string `yaml:"region"`
 	ProjectID   string `yaml:"project_id"`
 	Parallelism int    `yaml:"parallelism"`
+	// LimitRate caps the combined throughput of cp transfers, e.g. "20M"
+	// (bytes per second); empty means unlimited
+	LimitRate string `yaml:"limit_rate,omitempty"`
 }
 
 // GetDefaults returns the default configuration values
@@ -0,0 +1,87 @@
+package iam
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/gclient"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iamcredentials/v1"
+	"google.golang.org/api/option"
+)
+
+// credentialsProvider holds the singleton IAM Credentials service used to act
+// as another service account.
+var credentialsProvider gclient.Provider[*iamcredentials.Service]
+
+// credentialsService returns an IAM Credentials client authenticated with
+// Application Default Credentials.
+func credentialsService(ctx context.Context) (*iamcredentials.Service, error) {
+	return credentialsProvider.Get(ctx, func(ctx context.Context) (*iamcredentials.Service, error) {
+		creds, err := google.FindDefaultCredentials(ctx, iamcredentials.CloudPlatformScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get credentials: %w", err)
+		}
+		apilog.Logf("[IAM] iamcredentials.NewService()")
+		svc, err := iamcredentials.NewService(ctx, option.WithTokenSource(creds.TokenSource))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create IAM service: %w", err)
+		}
+		return svc, nil
+	})
+}
+
+// impersonationError explains a failed call made as serviceAccount.
+func impersonationError(what, serviceAccount string, err error) error {
+	return fmt.Errorf("failed to %s as %s: %w\n\nMake sure you have the 'Service Account Token Creator' role (roles/iam.serviceAccountTokenCreator)\non that service account", what, serviceAccount, err)
+}
+
+// GenerateIDToken returns an identity token for audience issued to
+// serviceAccount, which the caller's credentials must be allowed to
+// impersonate.
+func GenerateIDToken(ctx context.Context, serviceAccount, audience string) (string, error) {
+	svc, err := credentialsService(ctx)
+	if err != nil {
+		return "", err
+	}
+	apilog.Logf("[IAM] GenerateIdToken(%s, audience=%s)", serviceAccount, audience)
+	req := &iamcredentials.GenerateIdTokenRequest{Audience: audience, IncludeEmail: true}
+	resp, err := svc.Projects.ServiceAccounts.GenerateIdToken("projects/-/serviceAccounts/"+serviceAccount, req).Context(ctx).Do()
+	if err != nil {
+		return "", impersonationError("generate an identity token", serviceAccount, err)
+	}
+	return resp.Token, nil
+}
+
+// SignBlob signs payload with a system-managed key of serviceAccount, which
+// the caller's credentials must be allowed to impersonate.
+func SignBlob(ctx context.Context, serviceAccount string, payload []byte) ([]byte, error) {
+	svc, err := credentialsService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	apilog.Logf("[IAM] SignBlob(%s)", serviceAccount)
+	req := &iamcredentials.SignBlobRequest{Payload: base64.StdEncoding.EncodeToString(payload)}
+	resp, err := svc.Projects.ServiceAccounts.SignBlob("projects/-/serviceAccounts/"+serviceAccount, req).Context(ctx).Do()
+	if err != nil {
+		return nil, impersonationError("sign", serviceAccount, err)
+	}
+	return base64.StdEncoding.DecodeString(resp.SignedBlob)
+}
+
+// CredentialsFromFile loads the credentials in a JSON key file (such as a
+// service account key) for the cloud-platform scope.
+func CredentialsFromFile(ctx context.Context, path string) (*google.Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	creds, err := google.CredentialsFromJSON(ctx, data, iamcredentials.CloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credentials: %w", err)
+	}
+	return creds, nil
+}
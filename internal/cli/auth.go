@@ -3,11 +3,10 @@ package cli
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/iam"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/iamcredentials/v1"
 	"google.golang.org/api/idtoken"
 	"google.golang.org/api/option"
 )
@@ -70,13 +69,9 @@ Examples:
 
 		if authCredentials != "" {
 			// Load credentials from file
-			data, err := os.ReadFile(authCredentials)
+			creds, err = iam.CredentialsFromFile(ctx, authCredentials)
 			if err != nil {
-				return fmt.Errorf("failed to read credentials file: %w", err)
-			}
-			creds, err = google.CredentialsFromJSON(ctx, data, "https://www.googleapis.com/auth/cloud-platform")
-			if err != nil {
-				return fmt.Errorf("failed to parse credentials: %w", err)
+				return err
 			}
 		} else {
 			// Use ADC
@@ -151,7 +146,7 @@ Examples:
 
 		// Case 2: User credentials with impersonation
 		if authImpersonateServiceAccount != "" {
-			token, err := generateIdentityTokenWithImpersonation(ctx, authImpersonateServiceAccount, authAudience)
+			token, err := iam.GenerateIDToken(ctx, authImpersonateServiceAccount, authAudience)
 			if err != nil {
 				return err
 			}
@@ -197,35 +192,6 @@ service account you want to impersonate.`, authAudience, authAudience, authAudie
 	},
 }
 
-// generateIdentityTokenWithImpersonation generates an identity token by impersonating a service account.
-func generateIdentityTokenWithImpersonation(ctx context.Context, serviceAccount, audience string) (string, error) {
-	// Get credentials for IAM API (uses ADC)
-	creds, err := google.FindDefaultCredentials(ctx, iamcredentials.CloudPlatformScope)
-	if err != nil {
-		return "", fmt.Errorf("failed to get credentials: %w", err)
-	}
-
-	// Create IAM Credentials service
-	iamService, err := iamcredentials.NewService(ctx, option.WithTokenSource(creds.TokenSource))
-	if err != nil {
-		return "", fmt.Errorf("failed to create IAM service: %w", err)
-	}
-
-	// Generate identity token
-	name := fmt.Sprintf("projects/-/serviceAccounts/%s", serviceAccount)
-	req := &iamcredentials.GenerateIdTokenRequest{
-		Audience:     audience,
-		IncludeEmail: true,
-	}
-
-	resp, err := iamService.Projects.ServiceAccounts.GenerateIdToken(name, req).Context(ctx).Do()
-	if err != nil {
-		return "", fmt.Errorf("failed to generate identity token: %w\n\nMake sure you have the 'Service Account Token Creator' role (roles/iam.serviceAccountTokenCreator)\non service account: %s", err, serviceAccount)
-	}
-
-	return resp.Token, nil
-}
-
 func init() {
 	// Add auth command flags
 	authCmd.PersistentFlags().StringVarP(&authCredentials, "credentials", "c", "", "Path to service account JSON file")
@@ -4,12 +4,22 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/thieso2/cio/resolver"
 	"github.com/thieso2/cio/storage"
 )
 
+var (
+	catRange        string
+	catHead         string
+	catTail         string
+	catRaw          bool
+	catWithFilename bool
+)
+
 var catCmd = &cobra.Command{
 	Use:   "cat <path> [<path>...]",
 	Short: "Print GCS object(s) to stdout",
@@ -17,6 +27,11 @@ var catCmd = &cobra.Command{
 
 Supports alias paths, full gs:// paths, and wildcard patterns.
 
+Use --range, --head or --tail to print only part of each object; for plain
+objects only those bytes are fetched. Gzip content (Content-Encoding: gzip or
+a .gz name) is decompressed automatically, and ranges then refer to the
+decompressed bytes; use --raw to print the stored bytes instead.
+
 Examples:
   # Print a single file
   cio cat :am/logs/app.log
@@ -25,44 +40,150 @@ Examples:
   cio cat 'gs://io-db-legacy-exports/spdbbn023-1.ioint.de/*.log'
 
   # Concatenate several files
-  cio cat :am/2024/01/a.csv :am/2024/01/b.csv`,
+  cio cat :am/2024/01/a.csv :am/2024/01/b.csv
+
+  # Bytes 100 through 2000 (inclusive)
+  cio cat --range 100-2000 :am/logs/app.log
+
+  # First 4 KB / last 1 MB
+  cio cat --head 4KB :am/logs/app.log
+  cio cat --tail 1MB :am/logs/app.log
+
+  # Print compressed bytes as stored
+  cio cat --raw :am/logs/app.log.gz > app.log.gz
+
+  # Label each object's output with its path
+  cio cat -H --head 1KB ':am/logs/*.log'`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runCat,
 }
 
 func init() {
+	catCmd.Flags().StringVar(&catRange, "range", "", "print only bytes START-END (inclusive); START- or -N (last N bytes) also work")
+	catCmd.Flags().StringVar(&catHead, "head", "", "print only the first SIZE bytes (e.g. 4KB)")
+	catCmd.Flags().StringVar(&catTail, "tail", "", "print only the last SIZE bytes (e.g. 1MB)")
+	catCmd.Flags().BoolVar(&catRaw, "raw", false, "do not decompress gzip content")
+	catCmd.Flags().BoolVarP(&catWithFilename, "with-filename", "H", false, "print a '==> path <==' header before each object")
+
 	rootCmd.AddCommand(catCmd)
 }
 
 func runCat(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	opts, err := catOptions()
+	if err != nil {
+		return err
+	}
+
 	client, err := storage.GetClient(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to create GCS client: %w", err)
 	}
 
-	for _, arg := range args {
+	for i, arg := range args {
 		// Resolve alias or use path as-is
-		_, fullPath, _, err := resolveInput(arg)
+		r, fullPath, wasAlias, err := resolveInput(arg)
 		if err != nil {
 			return fmt.Errorf("failed to resolve %q: %w", arg, err)
 		}
 
+		opts.Formatter = storage.DefaultPathFormatter
+		if wasAlias {
+			opts.Formatter = r.ReverseResolve
+		}
+		if catWithFilename && i > 0 {
+			fmt.Println()
+		}
+
 		bucket, object, err := resolver.ParseGCSPath(fullPath)
 		if err != nil {
 			return err
 		}
 
 		if resolver.HasWildcard(object) {
-			if err := storage.CatWithPattern(ctx, client, bucket, object, os.Stdout); err != nil {
+			if err := storage.CatWithPattern(ctx, client, bucket, object, os.Stdout, opts); err != nil {
 				return err
 			}
 		} else {
-			if err := storage.CatObject(ctx, client, bucket, object, os.Stdout); err != nil {
+			if err := storage.CatObject(ctx, client, bucket, object, os.Stdout, opts); err != nil {
 				return err
 			}
 		}
 	}
 	return nil
 }
+
+// catOptions builds storage.CatOptions from the --range/--head/--tail/--raw flags.
+func catOptions() (*storage.CatOptions, error) {
+	opts := &storage.CatOptions{Raw: catRaw, WithFilename: catWithFilename}
+
+	set := 0
+	for _, v := range []string{catRange, catHead, catTail} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("--range, --head and --tail are mutually exclusive")
+	}
+
+	switch {
+	case catHead != "":
+		n, err := storage.ParseSize(catHead)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --head: %w", err)
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("invalid --head: size must be positive")
+		}
+		opts.Length = n
+	case catTail != "":
+		n, err := storage.ParseSize(catTail)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tail: %w", err)
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("invalid --tail: size must be positive")
+		}
+		opts.Tail = n
+	case catRange != "":
+		if err := parseByteRange(catRange, opts); err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+// parseByteRange parses an HTTP-style byte range: "START-END" (inclusive),
+// "START-" (to the end) or "-N" (last N bytes).
+func parseByteRange(s string, opts *storage.CatOptions) error {
+	start, end, ok := strings.Cut(s, "-")
+	if !ok || (start == "" && end == "") {
+		return fmt.Errorf("invalid --range %q: expected START-END, START- or -N", s)
+	}
+
+	if start == "" {
+		n, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid --range %q: expected a positive byte count after '-'", s)
+		}
+		opts.Tail = n
+		return nil
+	}
+
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || offset < 0 {
+		return fmt.Errorf("invalid --range %q: bad start offset", s)
+	}
+	opts.Offset = offset
+
+	if end != "" {
+		last, err := strconv.ParseInt(end, 10, 64)
+		if err != nil || last < offset {
+			return fmt.Errorf("invalid --range %q: end must be a number >= start", s)
+		}
+		opts.Length = last - offset + 1
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/thieso2/cio/storage"
+)
+
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    storage.CatOptions
+		wantErr bool
+	}{
+		{in: "0-99", want: storage.CatOptions{Length: 100}},
+		{in: "100-199", want: storage.CatOptions{Offset: 100, Length: 100}},
+		{in: "5-5", want: storage.CatOptions{Offset: 5, Length: 1}},
+		{in: "1000-", want: storage.CatOptions{Offset: 1000}},
+		{in: "-500", want: storage.CatOptions{Tail: 500}},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: "100", wantErr: true},
+		{in: "-0", wantErr: true},
+		{in: "10-5", wantErr: true},
+		{in: "x-5", wantErr: true},
+		{in: "5-y", wantErr: true},
+	}
+	for _, tt := range tests {
+		var got storage.CatOptions
+		err := parseByteRange(tt.in, &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseByteRange(%q) = %+v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got.Offset != tt.want.Offset || got.Length != tt.want.Length || got.Tail != tt.want.Tail {
+			t.Errorf("parseByteRange(%q) = offset %d, length %d, tail %d, %v; want %d, %d, %d",
+				tt.in, got.Offset, got.Length, got.Tail, err, tt.want.Offset, tt.want.Length, tt.want.Tail)
+		}
+	}
+}
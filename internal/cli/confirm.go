@@ -0,0 +1,23 @@
+package cli
+
+import "fmt"
+
+// confirm asks the user whether to proceed unless force is set. It prints
+// prompt, reads a y/N answer, and prints "Cancelled." when declined. It
+// returns whether the caller should proceed.
+//
+// Commands print their own preview of what will change, then gate the
+// mutation on confirm() — the CLI-side counterpart of resource.confirm.
+func confirm(force bool, prompt string) bool {
+	if force {
+		return true
+	}
+	fmt.Print(prompt)
+	var response string
+	fmt.Scanln(&response)
+	if response == "y" || response == "Y" {
+		return true
+	}
+	fmt.Println("Cancelled.")
+	return false
+}
@@ -15,20 +15,62 @@ import (
 var (
 	cpRecursive bool
 	cpForceCopy bool
+	cpNoVerify  bool
+
+	cpIfGenerationMatch int64
+	cpHeaders           []string
+	cpMeta              []string
+	cpLimitRate         string
+
+	// cpRateLimiter is shared by every transfer of one cp invocation
+	cpRateLimiter *storage.RateLimiter
+
+	cpFilterFlags filterFlags
+	cpPathFilter  *storage.PathFilter
+
+	cpResume string
 )
 
+// errCpFilter is returned when --include/--exclude is used outside a
+// recursive directory copy.
+var errCpFilter = fmt.Errorf("--include, --exclude and --exclude-from apply to recursive directory copies (cp -r) only")
+
 // cpCmd represents the cp command
 var cpCmd = &cobra.Command{
-	Use:   "cp <source> <destination>",
+	Use:   "cp <source> <destination> | --resume <id>",
 	Short: "Copy files between local and GCS",
 	Long: `Copy files between local filesystem and Google Cloud Storage.
 
 Supports:
   - Local to GCS: cio cp file.txt :am/path/
   - GCS to local: cio cp :am/path/file.txt ./local/
+  - GCS to GCS (server-side, no local round-trip): cio cp :am/a.txt :archive/
   - Recursive directory copy with -r flag
   - Wildcard patterns: cio cp ':am/logs/*.log' ./local/
   - Directory structure preservation with -r flag
+  - Streaming: "-" as source reads stdin, "-" as destination writes stdout
+
+Uploads and downloads are verified end to end: CRC32C (and MD5 where the
+object has one) is computed while streaming and compared with the object's
+stored checksums. File uploads also send their CRC32C, so GCS rejects corrupt
+data before committing it; computing it reads each file once more before the
+upload. On mismatch the transfer fails; a corrupt local file is removed, an
+uploaded object is kept and reported. Use --no-verify to skip the checks and
+the extra read.
+
+A source of the form "path#<generation>" copies that object version (see
+'cio ls --versions'). --if-generation-match makes a single-object write
+conditional: it only succeeds if the destination's live generation matches,
+and 0 means the destination must not exist yet.
+
+--header "Name: value" and --meta key=value set metadata on uploaded objects
+(see 'cio setmeta' for the supported headers).
+
+Recursive copies between local and GCS are recorded with a transfer ID
+(printed when the copy starts). If the copy is interrupted, --resume <id>
+continues with the items not done yet, from the recorded listing and object
+generations, without relisting or re-checking finished files. See
+'cio transfers' to list records and retry failed items.
 
 Examples:
   # Upload local file to GCS
@@ -50,8 +92,44 @@ Examples:
   cio cp -r ./logs/ :am/logs/2024/
 
   # Recursive download
-  cio cp -r :am/logs/2024/ ./local-logs/`,
-	Args: cobra.MinimumNArgs(2),
+  cio cp -r :am/logs/2024/ ./local-logs/
+
+  # Upload from a pipe
+  pg_dump mydb | gzip | cio cp - :am/dumps/db.sql.gz
+
+  # Stream an object to stdout
+  cio cp :am/dumps/db.sql.gz - | gunzip | psql mydb
+
+  # Copy between buckets (server-side rewrite)
+  cio cp :raw/2024/data.csv :archive/2024/
+  cio cp -r :raw/2024/ :archive/2024/
+  cio cp ':raw/logs/*.log' gs://other-bucket/logs/
+
+  # Copy an older version back under a new name
+  cio cp ':am/config.yaml#1712345678901234' :am/config.yaml.bak
+
+  # Upload with headers and custom metadata
+  cio cp --header "Cache-Control: no-cache" --meta owner=data-team report.html :site/
+
+  # Only create the object if it does not exist yet
+  cio cp --if-generation-match 0 lock.json :am/locks/job.json
+
+  # Copy a tree without build output and temp files
+  cio cp -r --exclude 'node_modules/' --exclude '*.tmp' ./app/ :am/app/
+  cio cp -r --exclude-from .gitignore ./repo/ :am/repo/
+  cio cp -r --include '**/*.parquet' :am/exports/ ./exports/
+
+  # Cap total bandwidth at 20 MiB/s, however many workers (-j) run
+  cio cp -r --limit-rate 20M ./data/ :am/data/
+
+  # Continue an interrupted recursive copy (see 'cio transfers ls')
+  cio cp --resume 20261016-153012-3fa9`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cpResume != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(2)(cmd, args)
+	},
 	RunE: runCp,
 }
 
@@ -59,10 +137,20 @@ func init() {
 	rootCmd.AddCommand(cpCmd)
 	cpCmd.Flags().BoolVarP(&cpRecursive, "recursive", "r", false, "copy directories recursively")
 	cpCmd.Flags().BoolVar(&cpForceCopy, "force-copy", false, "re-download even if destination file already exists with the correct size")
+	cpCmd.Flags().BoolVar(&cpNoVerify, "no-verify", false, "skip CRC32C/MD5 verification of transferred content (saves reading each uploaded file twice)")
+	cpCmd.Flags().StringArrayVarP(&cpHeaders, "header", "H", nil, "set a header on uploaded objects, \"Name: value\" (repeatable)")
+	cpCmd.Flags().StringArrayVar(&cpMeta, "meta", nil, "set custom metadata key=value on uploaded objects (repeatable)")
+	cpCmd.Flags().Int64Var(&cpIfGenerationMatch, "if-generation-match", -1, "only write if the destination's generation matches (0 = must not exist)")
+	cpFilterFlags.register(cpCmd)
+	cpCmd.Flags().StringVar(&cpLimitRate, "limit-rate", "", "cap total throughput across all workers, e.g. 20M (bytes/s; default from config defaults.limit_rate)")
+	cpCmd.Flags().StringVar(&cpResume, "resume", "", "continue the interrupted recursive copy with this transfer ID")
 }
 
 func runCp(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
+	if cpResume != "" {
+		return resumeTransfer(ctx, cpResume, false)
+	}
 	sources := args[:len(args)-1]
 	destination := args[len(args)-1]
 
@@ -92,6 +180,29 @@ func runCp(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create GCS client: %w", err)
 	}
 
+	metadata, err := parseMetadataFlags(cpHeaders, cpMeta)
+	if err != nil {
+		return err
+	}
+	if cpRateLimiter, err = parseLimitRate(cpLimitRate); err != nil {
+		return err
+	}
+	if cpPathFilter, err = cpFilterFlags.build(); err != nil {
+		return err
+	}
+	if metadata != nil && destIsLocal {
+		return fmt.Errorf("--header and --meta only apply to uploads")
+	}
+
+	// Generation preconditions apply to exactly one destination object
+	if cpIfGenerationMatch >= 0 && (destIsLocal || len(sources) > 1) {
+		return fmt.Errorf("--if-generation-match requires a single source and a GCS destination")
+	}
+
+	if err := checkStreamArgs(sources, destination, isCloudPath); err != nil {
+		return err
+	}
+
 	for _, source := range sources {
 		sourceIsLocal := !isCloudPath(source)
 
@@ -105,13 +216,24 @@ func runCp(cmd *cobra.Command, args []string) error {
 			sourcePath = source
 		}
 
+		if cpPathFilter != nil && (source == "-" || destination == "-" || sourceIsLocal == destIsLocal) {
+			return errCpFilter
+		}
+
 		var copyErr error
-		if sourceIsLocal && !destIsLocal {
-			copyErr = uploadPath(ctx, client, r, sourcePath, destPath, destWasAlias)
+		if source == "-" {
+			copyErr = uploadStdin(ctx, client, r, destPath, destWasAlias, metadata)
+		} else if destination == "-" {
+			copyErr = downloadToStdout(ctx, client, r, sourcePath, sourceWasAlias)
+		} else if sourceIsLocal && !destIsLocal {
+			copyErr = uploadPath(ctx, client, r, sourcePath, destPath, destWasAlias, metadata)
 		} else if !sourceIsLocal && destIsLocal {
 			copyErr = downloadPath(ctx, client, r, sourcePath, destPath, sourceWasAlias)
 		} else if !sourceIsLocal && !destIsLocal {
-			return fmt.Errorf("GCS to GCS copy not yet implemented")
+			if metadata != nil {
+				return fmt.Errorf("--header and --meta only apply to uploads (use 'cio setmeta' after copying)")
+			}
+			copyErr = copyPath(ctx, client, r, sourcePath, destPath, sourceWasAlias || destWasAlias)
 		} else {
 			return fmt.Errorf("use system 'cp' command for local to local copy")
 		}
@@ -122,7 +244,7 @@ func runCp(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func uploadPath(ctx context.Context, client *gcs.Client, r *resolver.Resolver, localPath, gcsPath string, destWasAlias bool) error {
+func uploadPath(ctx context.Context, client *gcs.Client, r *resolver.Resolver, localPath, gcsPath string, destWasAlias bool, metadata *storage.MetadataUpdate) error {
 	// Check if source exists
 	fileInfo, err := os.Stat(localPath)
 	if err != nil {
@@ -137,14 +259,23 @@ func uploadPath(ctx context.Context, client *gcs.Client, r *resolver.Resolver, l
 		formatter = func(path string) string { return path }
 	}
 
+	opts := cpUploadOptions()
+	opts.Metadata = metadata
+
 	if fileInfo.IsDir() {
 		if !cpRecursive {
 			return fmt.Errorf("%q is a directory (use -r to copy recursively)", localPath)
 		}
-		return storage.UploadDirectory(ctx, client, localPath, gcsPath, verbose, formatter, GetParallelism())
+		if opts.Preconditions != nil {
+			return fmt.Errorf("--if-generation-match cannot be used with directory uploads")
+		}
+		return storage.UploadDirectory(ctx, client, localPath, gcsPath, verbose, formatter, GetParallelism(), opts)
+	}
+	if opts.Filter != nil {
+		return errCpFilter
 	}
 
-	return storage.UploadFile(ctx, client, localPath, gcsPath, verbose, formatter)
+	return storage.UploadFile(ctx, client, localPath, gcsPath, verbose, formatter, opts)
 }
 
 func downloadPath(ctx context.Context, client *gcs.Client, r *resolver.Resolver, gcsPath, localPath string, sourceWasAlias bool) error {
@@ -162,34 +293,194 @@ func downloadPath(ctx context.Context, client *gcs.Client, r *resolver.Resolver,
 		formatter = func(path string) string { return path }
 	}
 
-	// Create download options from config
-	// Respect parallelism flag as a limit for max chunks
+	opts := cpDownloadOptions()
+	isDir := object == "" || object[len(object)-1] == '/'
+	if opts.Filter != nil && (!isDir || resolver.HasWildcard(object)) {
+		return errCpFilter
+	}
+
+	// Check if path contains wildcards
+	if resolver.HasWildcard(object) {
+		return storage.DownloadWithPattern(ctx, client, bucket, object, localPath, verbose, formatter, GetParallelism(), opts)
+	}
+
+	// Check if this is a directory (ends with / or no object specified)
+	if isDir {
+		if !cpRecursive {
+			return fmt.Errorf("%q appears to be a directory (use -r to copy recursively)", gcsPath)
+		}
+		return storage.DownloadDirectory(ctx, client, bucket, object, localPath, verbose, formatter, GetParallelism(), opts)
+	}
+
+	return storage.DownloadFile(ctx, client, bucket, object, localPath, verbose, formatter, opts)
+}
+
+// checkStreamArgs validates "-" among cp's arguments: as a source it reads
+// stdin into a single GCS object, as the destination it writes GCS objects
+// to stdout.
+func checkStreamArgs(sources []string, destination string, isCloudPath func(string) bool) error {
+	for _, source := range sources {
+		if source == "-" {
+			if len(sources) > 1 {
+				return fmt.Errorf("stdin (-) cannot be combined with other sources")
+			}
+			if !isCloudPath(destination) {
+				return fmt.Errorf("copying from stdin requires a GCS destination")
+			}
+		} else if destination == "-" && !isCloudPath(source) {
+			return fmt.Errorf("use system 'cat' to write local files to stdout")
+		}
+	}
+	return nil
+}
+
+// uploadStdin uploads standard input to a single GCS object.
+func uploadStdin(ctx context.Context, client *gcs.Client, r *resolver.Resolver, gcsPath string, destWasAlias bool, metadata *storage.MetadataUpdate) error {
+	formatter := storage.PathFormatter(func(p string) string { return p })
+	if destWasAlias {
+		formatter = r.ReverseResolve
+	}
+	opts := cpUploadOptions()
+	opts.Metadata = metadata
+	return storage.UploadStream(ctx, client, os.Stdin, gcsPath, verbose, formatter, opts)
+}
+
+// downloadToStdout streams a single GCS object to standard output.
+func downloadToStdout(ctx context.Context, client *gcs.Client, r *resolver.Resolver, gcsPath string, sourceWasAlias bool) error {
+	bucket, object, err := resolver.ParseGCSPath(gcsPath)
+	if err != nil {
+		return err
+	}
+	if object == "" || strings.HasSuffix(object, "/") || resolver.HasWildcard(object) {
+		return fmt.Errorf("cannot stream %q to stdout: not a single object (use 'cio cat' for wildcards)", gcsPath)
+	}
+
+	formatter := storage.PathFormatter(func(p string) string { return p })
+	if sourceWasAlias {
+		formatter = r.ReverseResolve
+	}
+	return storage.DownloadStream(ctx, client, bucket, object, os.Stdout, verbose, formatter, cpDownloadOptions())
+}
+
+// cpUploadOptions builds upload options from config and cp flags.
+// The parallelism flag caps the number of parallel parts.
+func cpUploadOptions() *storage.UploadOptions {
+	maxChunks := cfg.Upload.MaxChunks
+	parallelism := GetParallelism()
+	if parallelism < maxChunks {
+		maxChunks = parallelism
+	}
+
+	return &storage.UploadOptions{
+		ParallelThreshold: cfg.Upload.ParallelThreshold,
+		ChunkSize:         cfg.Upload.ChunkSize,
+		MaxChunks:         maxChunks,
+		NoVerify:          cpNoVerify,
+		Preconditions:     cpPreconditions(),
+		RateLimit:         cpRateLimiter,
+		Filter:            cpPathFilter,
+		Transfers:         transferStore(),
+	}
+}
+
+// cpPreconditions returns the write preconditions requested with
+// --if-generation-match, or nil if the flag was not given.
+func cpPreconditions() *gcs.Conditions {
+	switch {
+	case cpIfGenerationMatch < 0:
+		return nil
+	case cpIfGenerationMatch == 0:
+		return &gcs.Conditions{DoesNotExist: true}
+	default:
+		return &gcs.Conditions{GenerationMatch: cpIfGenerationMatch}
+	}
+}
+
+// cpDownloadOptions builds download options from config and cp flags.
+// The parallelism flag caps the number of parallel chunks.
+func cpDownloadOptions() *storage.DownloadOptions {
 	maxChunks := cfg.Download.MaxChunks
 	parallelism := GetParallelism()
 	if parallelism < maxChunks {
 		maxChunks = parallelism
 	}
 
-	opts := &storage.DownloadOptions{
+	return &storage.DownloadOptions{
 		ParallelThreshold: cfg.Download.ParallelThreshold,
 		ChunkSize:         cfg.Download.ChunkSize,
 		MaxChunks:         maxChunks,
 		PreserveStructure: cpRecursive, // Preserve directory structure when -r flag is used
 		Force:             cpForceCopy,
+		NoVerify:          cpNoVerify,
+		RateLimit:         cpRateLimiter,
+		Filter:            cpPathFilter,
+		Transfers:         transferStore(),
+	}
+}
+
+// parseLimitRate builds the shared rate limiter from --limit-rate, falling
+// back to defaults.limit_rate in the config. "0" or empty means unlimited.
+func parseLimitRate(flag string) (*storage.RateLimiter, error) {
+	value, source := flag, "--limit-rate"
+	if value == "" {
+		value, source = cfg.Defaults.LimitRate, "defaults.limit_rate"
+	}
+	if value == "" {
+		return nil, nil
+	}
+	rate, err := storage.ParseSize(strings.TrimSuffix(strings.TrimSuffix(value, "/s"), "/S"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", source, err)
+	}
+	if verbose && rate > 0 {
+		fmt.Fprintf(os.Stderr, "Limiting transfers to %s/s\n", storage.FormatSize(rate))
+	}
+	return storage.NewRateLimiter(rate), nil
+}
+
+func copyPath(ctx context.Context, client *gcs.Client, r *resolver.Resolver, srcPath, dstPath string, eitherWasAlias bool) error {
+	srcBucket, srcObject, err := resolver.ParseGCSPath(srcPath)
+	if err != nil {
+		return err
+	}
+	dstBucket, dstObject, err := resolver.ParseGCSPath(dstPath)
+	if err != nil {
+		return err
+	}
+	if srcBucket == "" || dstBucket == "" {
+		return fmt.Errorf("GCS to GCS copy requires a bucket in both source and destination")
+	}
+
+	// Create path formatter - reverse-map if either side was an alias
+	var formatter storage.PathFormatter
+	if eitherWasAlias {
+		formatter = r.ReverseResolve
+	} else {
+		formatter = func(path string) string { return path }
+	}
+
+	opts := &storage.CopyOptions{
+		PreserveStructure: cpRecursive, // Preserve directory structure when -r flag is used
+		Preconditions:     cpPreconditions(),
+	}
+
+	isSingleObject := !resolver.HasWildcard(srcObject) && srcObject != "" && srcObject[len(srcObject)-1] != '/'
+	if opts.Preconditions != nil && !isSingleObject {
+		return fmt.Errorf("--if-generation-match requires a single source object")
 	}
 
 	// Check if path contains wildcards
-	if resolver.HasWildcard(object) {
-		return storage.DownloadWithPattern(ctx, client, bucket, object, localPath, verbose, formatter, GetParallelism(), opts)
+	if resolver.HasWildcard(srcObject) {
+		return storage.CopyWithPattern(ctx, client, srcBucket, srcObject, dstBucket, dstObject, verbose, formatter, GetParallelism(), opts)
 	}
 
 	// Check if this is a directory (ends with / or no object specified)
-	if object == "" || object[len(object)-1] == '/' {
+	if srcObject == "" || srcObject[len(srcObject)-1] == '/' {
 		if !cpRecursive {
-			return fmt.Errorf("%q appears to be a directory (use -r to copy recursively)", gcsPath)
+			return fmt.Errorf("%q appears to be a directory (use -r to copy recursively)", srcPath)
 		}
-		return storage.DownloadDirectory(ctx, client, bucket, object, localPath, verbose, formatter, GetParallelism(), opts)
+		return storage.CopyDirectory(ctx, client, srcBucket, srcObject, dstBucket, dstObject, verbose, formatter, GetParallelism(), opts)
 	}
 
-	return storage.DownloadFile(ctx, client, bucket, object, localPath, verbose, formatter, opts)
+	return storage.CopyObject(ctx, client, srcBucket, srcObject, dstBucket, dstObject, verbose, formatter, opts)
 }
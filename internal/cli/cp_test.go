@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thieso2/cio/config"
+)
+
+func TestCheckStreamArgs(t *testing.T) {
+	isCloudPath := func(p string) bool { return strings.HasPrefix(p, "gs://") || strings.HasPrefix(p, ":") }
+	tests := []struct {
+		name        string
+		sources     []string
+		destination string
+		wantErr     string
+	}{
+		{"stdin to object", []string{"-"}, ":am/dump.gz", ""},
+		{"object to stdout", []string{":am/dump.gz"}, "-", ""},
+		{"objects to stdout", []string{"gs://b/a", "gs://b/b"}, "-", ""},
+		{"no streaming", []string{"./a", "./b"}, ":am/dir/", ""},
+		{"stdin with other sources", []string{"./a", "-"}, ":am/dir/", "cannot be combined"},
+		{"stdin to local file", []string{"-"}, "./out", "requires a GCS destination"},
+		{"stdin to stdout", []string{"-"}, "-", "requires a GCS destination"},
+		{"local file to stdout", []string{"./a"}, "-", "use system 'cat'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStreamArgs(tt.sources, tt.destination, isCloudPath)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkStreamArgs(%q, %q) = %v, want nil", tt.sources, tt.destination, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkStreamArgs(%q, %q) = %v, want error containing %q", tt.sources, tt.destination, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseLimitRate(t *testing.T) {
+	saved := cfg
+	defer func() { cfg = saved }()
+
+	tests := []struct {
+		flag, config string
+		limited      bool
+		wantErr      bool
+	}{
+		{flag: "", config: ""},
+		{flag: "0", config: "10MB"},
+		{flag: "10MB/s", limited: true},
+		{flag: "512k", limited: true},
+		{flag: "", config: "1G/s", limited: true},
+		{flag: "fast", wantErr: true},
+		{flag: "", config: "10 parsecs", wantErr: true},
+	}
+	for _, tt := range tests {
+		cfg = &config.Config{}
+		cfg.Defaults.LimitRate = tt.config
+		l, err := parseLimitRate(tt.flag)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseLimitRate(%q) with limit_rate %q: want error", tt.flag, tt.config)
+			}
+			continue
+		}
+		if err != nil || (l != nil) != tt.limited {
+			t.Errorf("parseLimitRate(%q) with limit_rate %q = %v, %v; want limited %t", tt.flag, tt.config, l, err, tt.limited)
+		}
+	}
+}
@@ -15,6 +15,9 @@ var (
 	duSummarize bool
 	duBytes     bool
 	duNoSummary bool
+	duMaxDepth  int
+	duBy        string
+	duCost      bool
 )
 
 var duCmd = &cobra.Command{
@@ -30,6 +33,20 @@ summary (size + file count), followed by a grand total line.
 
 Use --no-summary to suppress the grand total line.
 
+--max-depth N also lists nested subdirectories down to N levels below the
+path (default 1). Nested totals come from the same listings, so deeper
+breakdowns cost no extra API calls.
+
+--by storage-class or --by age (since creation: <30d, 30-90d, 90d-1y, >1y)
+adds a column per class or age range, and --cost adds the estimated monthly
+storage cost of each line. Prices are GCS list prices for the US
+multi-region unless overridden in the config:
+
+  billing:
+    storage_prices:      # USD per GiB-month
+      STANDARD: 0.023
+      NEARLINE: 0.013
+
 Subdirectory sizes are calculated in parallel using SetAttrSelection to fetch
 only Name and Size, significantly reducing API payload and speeding up large
 bucket traversals. Parallelism is controlled by the global -j flag.
@@ -54,6 +71,12 @@ Examples:
   # Show raw byte counts
   cio du --bytes :am/
 
+  # Two levels deep, with storage classes and monthly cost
+  cio du --max-depth 2 --by storage-class --cost :am/
+
+  # What is old enough to move to colder storage?
+  cio du --by age --cost :am/
+
 Note: parallelism is controlled by the global -j flag (default: 50).`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -86,9 +109,25 @@ Note: parallelism is controlled by the global -j flag (default: 50).`,
 			return gcsPath
 		}
 
+		byClass, byAge := false, false
+		switch duBy {
+		case "":
+		case "storage-class", "class":
+			byClass = true
+		case "age":
+			byAge = true
+		default:
+			return fmt.Errorf("invalid --by %q (use storage-class or age)", duBy)
+		}
+		if duMaxDepth < 1 {
+			return fmt.Errorf("--max-depth must be at least 1")
+		}
+		// The cost estimate is computed from the per-class breakdown
+		opts := &storage.DUOptions{Workers: parallelism, MaxDepth: duMaxDepth, ByClass: byClass || duCost, ByAge: byAge}
+
 		// Wildcard path: find all matching entries and sum each in parallel.
 		if strings.ContainsAny(prefix, "*?") {
-			entries, err := storage.DiskUsagePattern(ctx, bucket, prefix, &storage.DUOptions{Workers: parallelism})
+			entries, err := storage.DiskUsagePattern(ctx, bucket, prefix, opts)
 			if err != nil {
 				return fmt.Errorf("failed to calculate disk usage: %w", err)
 			}
@@ -98,41 +137,106 @@ Note: parallelism is controlled by the global -j flag (default: 50).`,
 				}
 				return nil
 			}
-			var total int64
-			var totalCount int64
+			total := &storage.DUEntry{Path: "total"}
+			lines := make([]storage.DUEntry, 0, len(entries)+1)
 			for _, entry := range entries {
-				total += entry.Size
-				totalCount += entry.Count
-				fmt.Printf("%s  %s  %s\n", formatDUSize(entry.Size, duBytes), formatDUCount(entry.Count), displayPath(entry.Path))
+				// Nested entries are already counted in their top-level match
+				if isTopLevelDU(entry.Path, entries) {
+					total.Merge(&entry)
+				}
+				entry.Path = displayPath(entry.Path)
+				lines = append(lines, entry)
 			}
 			if !duNoSummary {
-				fmt.Printf("%s  %s  total\n", formatDUSize(total, duBytes), formatDUCount(totalCount))
+				lines = append(lines, *total)
 			}
+			printDU(lines, byClass, byAge, duCost)
 			return nil
 		}
 
 		// Non-wildcard path: shallow-list subdirs, sum each in parallel.
-		result, err := storage.DiskUsage(ctx, bucket, prefix, &storage.DUOptions{Workers: parallelism})
+		result, err := storage.DiskUsage(ctx, bucket, prefix, opts)
 		if err != nil {
 			return fmt.Errorf("failed to calculate disk usage: %w", err)
 		}
 
-		if duSummarize {
-			fmt.Printf("%s  %s  %s\n", formatDUSize(result.Total, duBytes), formatDUCount(result.Count), displayPath(result.RootPath))
-			return nil
-		}
-
-		for _, entry := range result.Entries {
-			fmt.Printf("%s  %s  %s\n", formatDUSize(entry.Size, duBytes), formatDUCount(entry.Count), displayPath(entry.Path))
+		root := result.Root
+		root.Path = displayPath(result.RootPath)
+		var lines []storage.DUEntry
+		if !duSummarize {
+			for _, entry := range result.Entries {
+				entry.Path = displayPath(entry.Path)
+				lines = append(lines, entry)
+			}
 		}
-		if !duNoSummary {
-			fmt.Printf("%s  %s  %s\n", formatDUSize(result.Total, duBytes), formatDUCount(result.Count), displayPath(result.RootPath))
+		if duSummarize || !duNoSummary {
+			lines = append(lines, root)
 		}
+		printDU(lines, byClass, byAge, duCost)
 
 		return nil
 	},
 }
 
+// printDU prints du lines: "size  count  path" as before, or, with a
+// breakdown or cost column, a table with one column per storage class or
+// age range.
+func printDU(lines []storage.DUEntry, byClass, byAge, cost bool) {
+	if !byAge && !byClass && !cost {
+		for _, e := range lines {
+			fmt.Printf("%s  %s  %s\n", formatDUSize(e.Size, duBytes), formatDUCount(e.Count), e.Path)
+		}
+		return
+	}
+
+	header := []string{"SIZE", "FILES"}
+	var classes []string
+	if byClass {
+		classes = storage.DUClasses(lines...)
+		header = append(header, classes...)
+	}
+	if byAge {
+		header = append(header, storage.DUAgeBuckets...)
+	}
+	if cost {
+		header = append(header, "COST/MONTH")
+	}
+	header = append(header, "PATH")
+
+	rows := make([]string, len(lines))
+	for i, e := range lines {
+		cells := []string{strings.TrimSpace(formatDUSize(e.Size, duBytes)), formatThousands(e.Count)}
+		for _, class := range classes {
+			cells = append(cells, strings.TrimSpace(formatDUSize(e.ByClass[class], duBytes)))
+		}
+		if byAge {
+			for _, bucket := range storage.DUAgeBuckets {
+				cells = append(cells, strings.TrimSpace(formatDUSize(e.ByAge[bucket], duBytes)))
+			}
+		}
+		if cost {
+			if c, ok := e.MonthlyCost(); ok {
+				cells = append(cells, fmt.Sprintf("$%.2f", c))
+			} else {
+				cells = append(cells, "?")
+			}
+		}
+		cells = append(cells, e.Path)
+		rows[i] = strings.Join(cells, "\t")
+	}
+	renderTable(strings.Join(header, "\t"), rows, "")
+}
+
+// isTopLevelDU reports whether path is not nested below another entry.
+func isTopLevelDU(path string, entries []storage.DUEntry) bool {
+	for _, e := range entries {
+		if e.Path != path && strings.HasSuffix(e.Path, "/") && strings.HasPrefix(path, e.Path) {
+			return false
+		}
+	}
+	return true
+}
+
 // formatDUSize right-aligns the size value to a fixed column width, matching
 // the style used by ls -l in this codebase.
 func formatDUSize(bytes int64, rawBytes bool) string {
@@ -174,6 +278,9 @@ func init() {
 	duCmd.Flags().BoolVarP(&duSummarize, "summarize", "s", false, "display only a total for each argument")
 	duCmd.Flags().BoolVarP(&duBytes, "bytes", "b", false, "print raw byte counts instead of human-readable sizes")
 	duCmd.Flags().BoolVar(&duNoSummary, "no-summary", false, "suppress the grand total line")
+	duCmd.Flags().IntVarP(&duMaxDepth, "max-depth", "d", 1, "list subdirectories down to N levels below the path")
+	duCmd.Flags().StringVar(&duBy, "by", "", "break sizes down by storage-class or age")
+	duCmd.Flags().BoolVar(&duCost, "cost", false, "add the estimated monthly storage cost")
 
 	rootCmd.AddCommand(duCmd)
 }
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDuration parses a duration given on the command line. Besides Go
+// durations ("36h", "90m") it accepts whole days, weeks and years ("30d",
+// "2w", "1y"; a year is 365 days), which is how retention and ages are
+// usually stated.
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n := len(s); n > 1 {
+		var unit time.Duration
+		switch s[n-1] {
+		case 'd':
+			unit = 24 * time.Hour
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		case 'y':
+			unit = 365 * 24 * time.Hour
+		}
+		if unit != 0 {
+			count, err := strconv.Atoi(s[:n-1])
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q (e.g. 36h, 30d, 2w, 1y)", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q (e.g. 36h, 30d, 2w, 1y)", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/thieso2/cio/config"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	encryptionKeyFile  string   // CSEK for this command (overrides config)
+	kmsKey             string   // CMEK for this command (overrides config)
+	decryptionKeyFiles []string // further CSEKs accepted for reading
+)
+
+// unavailableKeys holds, by gs:// location, why the config's keys for it
+// could not be loaded (e.g. an unset key_env). Only commands that touch
+// objects there fail; see checkEncryptionKeys.
+var unavailableKeys = map[string]error{}
+
+// applyEncryption registers the keys of the config's encryption section and
+// of --encryption-key-file/--kms-key/--decryption-key-file with the storage
+// package. Aliases are resolved to their gs:// locations; entries for
+// aliases that are no longer mapped are skipped with a warning.
+func applyEncryption() error {
+	for entry, e := range cfg.Encryption {
+		location, ok := encryptionLocation(entry)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring encryption keys for %q: not an alias of a gs:// location\n", entry)
+			continue
+		}
+		keys, err := loadEncryptionKeys(location, e)
+		if err != nil {
+			unavailableKeys[location] = err
+			continue
+		}
+		if err := storage.SetEncryptionKeys(location, keys); err != nil {
+			return err
+		}
+	}
+
+	if encryptionKeyFile == "" && kmsKey == "" && len(decryptionKeyFiles) == 0 {
+		return nil
+	}
+	if encryptionKeyFile != "" && kmsKey != "" {
+		return fmt.Errorf("--encryption-key-file and --kms-key are mutually exclusive")
+	}
+	keys := storage.EncryptionKeys{KMSKeyName: kmsKey}
+	var err error
+	if encryptionKeyFile != "" {
+		if keys.Key, err = readEncryptionKeyFile(encryptionKeyFile); err != nil {
+			return fmt.Errorf("--encryption-key-file: %w", err)
+		}
+	}
+	if keys.DecryptionKeys, err = readEncryptionKeyFiles(decryptionKeyFiles); err != nil {
+		return fmt.Errorf("--decryption-key-file: %w", err)
+	}
+	return storage.SetCommandEncryptionKeys(keys)
+}
+
+// encryptionLocation returns the gs:// location an encryption entry of the
+// config applies to: the entry itself, or the location its alias maps to.
+func encryptionLocation(entry string) (string, bool) {
+	if strings.HasPrefix(entry, "gs://") {
+		return entry, true
+	}
+	path, ok := cfg.Mappings[entry]
+	if !ok || !strings.HasPrefix(path, "gs://") {
+		return "", false
+	}
+	return path, true
+}
+
+// loadEncryptionKeys reads the keys of one encryption entry of the config.
+func loadEncryptionKeys(location string, e config.EncryptionConfig) (storage.EncryptionKeys, error) {
+	keys := storage.EncryptionKeys{KMSKeyName: e.KMSKey}
+	var err error
+	switch {
+	case e.KeyFile != "":
+		keys.Key, err = readEncryptionKeyFile(e.KeyFile)
+	case e.KeyEnv != "":
+		value := os.Getenv(e.KeyEnv)
+		if value == "" {
+			return keys, fmt.Errorf("encryption key for %s: environment variable %s is not set", location, e.KeyEnv)
+		}
+		keys.Key, err = storage.ParseEncryptionKey(value)
+	}
+	if err != nil {
+		return keys, fmt.Errorf("encryption key for %s: %w", location, err)
+	}
+	if keys.DecryptionKeys, err = readEncryptionKeyFiles(e.DecryptionKeyFiles); err != nil {
+		return keys, fmt.Errorf("decryption key for %s: %w", location, err)
+	}
+	return keys, nil
+}
+
+// checkEncryptionKeys returns the error of a configured key that could not
+// be loaded if objects under fullPath could be encrypted with it, so such
+// objects are neither written unencrypted nor read without the key. Keys
+// given on the command line replace the config's.
+func checkEncryptionKeys(fullPath string) error {
+	if len(unavailableKeys) == 0 || encryptionKeyFile != "" || kmsKey != "" {
+		return nil
+	}
+	bucket, object, err := resolver.ParseGCSPath(fullPath)
+	if err != nil {
+		return nil
+	}
+	if i := strings.IndexAny(object, "*?["); i >= 0 {
+		object = object[:i]
+	}
+	for location, keyErr := range unavailableKeys {
+		b, prefix, _ := resolver.ParseGCSPath(location)
+		if b == bucket && (strings.HasPrefix(object, prefix) || strings.HasPrefix(prefix, object)) {
+			return keyErr
+		}
+	}
+	return nil
+}
+
+// readEncryptionKeyFile reads a base64 AES-256 key from path ("~/" is
+// expanded).
+func readEncryptionKeyFile(path string) ([]byte, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = home + "/" + rest
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return storage.ParseEncryptionKey(string(data))
+}
+
+func readEncryptionKeyFiles(paths []string) ([][]byte, error) {
+	var keys [][]byte
+	for _, path := range paths {
+		key, err := readEncryptionKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
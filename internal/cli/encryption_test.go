@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/thieso2/cio/config"
+)
+
+func TestEncryptionLocation(t *testing.T) {
+	saved := cfg
+	defer func() { cfg = saved }()
+	cfg = &config.Config{Mappings: map[string]string{
+		"secure": "gs://vault/secret/",
+		"local":  "/tmp/data",
+	}}
+
+	tests := []struct {
+		entry  string
+		want   string
+		wantOK bool
+	}{
+		{"secure", "gs://vault/secret/", true},
+		{"gs://vault/other/", "gs://vault/other/", true},
+		{"local", "", false},
+		{"unmapped", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := encryptionLocation(tt.entry)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("encryptionLocation(%q) = %q, %v, want %q, %v", tt.entry, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLoadEncryptionKeys(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	oldKey := bytes.Repeat([]byte{2}, 32)
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "secure.key")
+	oldKeyFile := filepath.Join(dir, "old.key")
+	shortKeyFile := filepath.Join(dir, "short.key")
+	os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600)
+	os.WriteFile(oldKeyFile, []byte(base64.StdEncoding.EncodeToString(oldKey)), 0600)
+	os.WriteFile(shortKeyFile, []byte(base64.StdEncoding.EncodeToString(key[:16])), 0600)
+	t.Setenv("CIO_TEST_KEY", base64.StdEncoding.EncodeToString(key))
+	t.Setenv("CIO_TEST_EMPTY_KEY", "")
+
+	tests := []struct {
+		name    string
+		entry   config.EncryptionConfig
+		wantKey []byte
+		wantKMS string
+		wantOld int
+		wantErr string
+	}{
+		{name: "key file", entry: config.EncryptionConfig{KeyFile: keyFile}, wantKey: key},
+		{name: "key env", entry: config.EncryptionConfig{KeyEnv: "CIO_TEST_KEY"}, wantKey: key},
+		{name: "kms key", entry: config.EncryptionConfig{KMSKey: "projects/p/locations/l/keyRings/r/cryptoKeys/k"}, wantKMS: "projects/p/locations/l/keyRings/r/cryptoKeys/k"},
+		{name: "decryption keys", entry: config.EncryptionConfig{KeyFile: keyFile, DecryptionKeyFiles: []string{oldKeyFile}}, wantKey: key, wantOld: 1},
+		{name: "unset env", entry: config.EncryptionConfig{KeyEnv: "CIO_TEST_EMPTY_KEY"}, wantErr: "CIO_TEST_EMPTY_KEY is not set"},
+		{name: "missing key file", entry: config.EncryptionConfig{KeyFile: filepath.Join(dir, "missing.key")}, wantErr: "encryption key for gs://vault/secret/"},
+		{name: "short key", entry: config.EncryptionConfig{KeyFile: shortKeyFile}, wantErr: "16 bytes, want 32"},
+		{name: "bad decryption key", entry: config.EncryptionConfig{DecryptionKeyFiles: []string{shortKeyFile}}, wantErr: "decryption key for gs://vault/secret/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := loadEncryptionKeys("gs://vault/secret/", tt.entry)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadEncryptionKeys() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadEncryptionKeys() = %v", err)
+			}
+			if !bytes.Equal(keys.Key, tt.wantKey) || keys.KMSKeyName != tt.wantKMS || len(keys.DecryptionKeys) != tt.wantOld {
+				t.Errorf("loadEncryptionKeys() = %+v, want key %v, KMS key %q, %d decryption keys", keys, tt.wantKey, tt.wantKMS, tt.wantOld)
+			}
+		})
+	}
+}
+
+func TestCheckEncryptionKeys(t *testing.T) {
+	errMissing := errors.New("key for gs://vault/secret/ is missing")
+	savedKeys, savedFile, savedKMS := unavailableKeys, encryptionKeyFile, kmsKey
+	defer func() { unavailableKeys, encryptionKeyFile, kmsKey = savedKeys, savedFile, savedKMS }()
+	unavailableKeys = map[string]error{"gs://vault/secret/": errMissing}
+
+	tests := []struct {
+		name     string
+		path     string
+		override bool
+		wantErr  bool
+	}{
+		{name: "object under the location", path: "gs://vault/secret/a.txt", wantErr: true},
+		{name: "location itself", path: "gs://vault/secret/", wantErr: true},
+		{name: "parent of the location", path: "gs://vault/", wantErr: true},
+		{name: "wildcard reaching into it", path: "gs://vault/sec*", wantErr: true},
+		{name: "sibling prefix", path: "gs://vault/public/a.txt"},
+		{name: "wildcard elsewhere", path: "gs://vault/pub*/a.txt"},
+		{name: "other bucket", path: "gs://other/secret/a.txt"},
+		{name: "key on the command line", path: "gs://vault/secret/a.txt", override: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptionKeyFile, kmsKey = "", ""
+			if tt.override {
+				kmsKey = "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+			}
+			err := checkEncryptionKeys(tt.path)
+			if tt.wantErr && err != errMissing {
+				t.Errorf("checkEncryptionKeys(%q) = %v, want %v", tt.path, err, errMissing)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("checkEncryptionKeys(%q) = %v, want nil", tt.path, err)
+			}
+		})
+	}
+}
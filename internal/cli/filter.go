@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/storage"
+)
+
+// filterFlags holds the --include/--exclude/--exclude-from flags shared by
+// the recursive commands.
+type filterFlags struct {
+	include     []string
+	exclude     []string
+	excludeFrom []string
+}
+
+// register adds the filter flags to cmd.
+func (f *filterFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&f.include, "include", nil, "only process paths matching this glob (** spans directories; repeatable)")
+	cmd.Flags().StringArrayVar(&f.exclude, "exclude", nil, "skip paths matching this glob (** spans directories; repeatable)")
+	cmd.Flags().StringArrayVar(&f.excludeFrom, "exclude-from", nil, "read exclude patterns from a file in .gitignore syntax (repeatable)")
+}
+
+// set reports whether any filter flag was given.
+func (f *filterFlags) set() bool {
+	return len(f.include) > 0 || len(f.exclude) > 0 || len(f.excludeFrom) > 0
+}
+
+// build compiles the flags into a filter (nil if none were given).
+func (f *filterFlags) build() (*storage.PathFilter, error) {
+	return storage.NewPathFilter(f.include, f.exclude, f.excludeFrom)
+}
@@ -0,0 +1,390 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/resource"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	findForce   bool
+	findNoTrash bool
+)
+
+var findCmd = &cobra.Command{
+	Use:   "find <path> [predicates] [actions]",
+	Short: "Find objects, tables and other resources by size, age, name and type",
+	Long: `Search a recursive listing with find-style predicates.
+
+Predicates (joined by AND; -o for OR, ! or -not to negate):
+  -name GLOB, -iname GLOB   base name (* and ?; -iname ignores case)
+  -path GLOB                path below <path> (** matches any depth)
+  -size [+-]N[K|M|G|T]      larger than (+), smaller than (-) or exactly N bytes
+  -mtime [+-]N              modified more (+) / less (-) than N days ago
+  -mmin [+-]N               modified more (+) / less (-) than N minutes ago
+  -ctime [+-]N              created more (+) / less (-) than N days ago
+  -rows [+-]N               row count (BigQuery tables)
+  -type T                   f (file), d (directory) or a type such as table, view
+  -storage-class CLASS      GCS storage class
+  -empty                    zero size and rows
+
+Actions:
+  -print                    print matching paths (default)
+  -ls                       print matches in long format
+  -delete                   delete matches after confirmation (-f to skip);
+                            GCS objects and BigQuery tables only. With
+                            rm.trash set, GCS objects are moved to the trash
+                            like 'cio rm' (--no-trash deletes permanently)
+  -exec rm                  same as -delete
+  -exec CMD [ARGS] ;        run a local command per match, {} is the path
+  -exec CMD [ARGS] {} +     run a local command with many matches at once
+
+Works on every scheme 'cio ls' lists; predicates on fields a resource does
+not have (e.g. -rows on GCS objects) do not match. On a BigQuery project the
+listing covers its datasets and all their tables. --json prints matches as
+JSON.
+
+Examples:
+  cio find :am/ -size +100M -mtime +90 -storage-class STANDARD -name '*.parquet'
+  cio find :am/tmp/ -mtime +7 -delete
+  cio find :am/ -name '*.csv' -o -name '*.tsv' --json
+  cio find :mydata -type table -rows -1 -ls
+  cio find bq://my-project -type table -size +1T
+  cio find :am/logs/ -name '*.gz' -exec cio cat {} \;`,
+	// find's predicates are single-dash long words (-name, -size), which
+	// cobra would read as bundled short flags; cio's own flags are split out
+	// and parsed in runFind, which then loads the config.
+	DisableFlagParsing: true,
+	PersistentPreRunE:  func(*cobra.Command, []string) error { return nil },
+	RunE:               runFind,
+}
+
+func init() {
+	findCmd.Flags().BoolVarP(&findForce, "force", "f", false, "delete without confirmation")
+	findCmd.Flags().BoolVar(&findNoTrash, "no-trash", false, "delete permanently even when rm.trash is configured (GCS)")
+
+	rootCmd.AddCommand(findCmd)
+}
+
+// findActions holds the actions of a find command line.
+type findActions struct {
+	print  bool
+	long   bool
+	delete bool
+	exec   []string
+	// execBatch runs exec with many paths at once (-exec ... {} +)
+	execBatch bool
+}
+
+func runFind(cmd *cobra.Command, args []string) error {
+	path, predicates, actions, flags, err := splitFindArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Flags().Parse(flags); err != nil {
+		return err
+	}
+	if help, _ := cmd.Flags().GetBool("help"); help {
+		return cmd.Help()
+	}
+	if err := loadConfig(cmd); err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("a path is required, e.g. cio find :am/ -name '*.csv'")
+	}
+
+	res, r, fullPath, wasAlias, err := resolveToResource(path)
+	if err != nil {
+		return err
+	}
+	expr, err := resource.ParseFindExpr(predicates, fullPath)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	listOpts := &resource.ListOptions{
+		Recursive: true,
+		ProjectID: cfg.Defaults.ProjectID,
+		Region:    cfg.Defaults.Region,
+	}
+	var matched []*resource.ResourceInfo
+	listed := 0
+	collect := func(info *resource.ResourceInfo) error {
+		listed++
+		if expr.Match(info) {
+			matched = append(matched, info)
+		}
+		return nil
+	}
+	// GCS listings are filtered as they stream in, so only the matches are
+	// held in memory
+	if sl, ok := res.(resource.StreamLister); ok {
+		err = sl.ListEach(ctx, fullPath, listOpts, collect)
+	} else {
+		var all []*resource.ResourceInfo
+		all, err = res.List(ctx, fullPath, listOpts)
+		for _, info := range all {
+			collect(info)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %w", err)
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "%d of %d resource(s) match\n", len(matched), listed)
+	}
+
+	display := func(p string) string {
+		if wasAlias {
+			return r.ReverseResolve(p)
+		}
+		return p
+	}
+
+	if outputJSON {
+		if err := printResourcesJSON(matched); err != nil {
+			return err
+		}
+	} else if actions.long {
+		rows := make([]string, len(matched))
+		for i, info := range matched {
+			rows[i] = res.FormatLong(info, display(info.Path))
+		}
+		renderTable(res.FormatLongHeader(), rows, "")
+	} else if actions.print || (!actions.delete && actions.exec == nil) {
+		for _, info := range matched {
+			fmt.Println(display(info.Path))
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+	if actions.exec != nil {
+		if actions.execBatch {
+			return findExecBatch(actions.exec, matched, display)
+		}
+		return findExec(actions.exec, matched, display)
+	}
+	if actions.delete {
+		return findDelete(ctx, res, fullPath, matched, display)
+	}
+	return nil
+}
+
+// splitFindArgs separates the find command line into the start path, the
+// predicate expression, the actions and cio's own flags (long flags,
+// single-letter flags and their values).
+func splitFindArgs(cmd *cobra.Command, args []string) (path string, predicates []string, actions findActions, flags []string, err error) {
+	noValue := map[string]bool{"!": true, "-not": true, "-a": true, "-and": true, "-o": true, "-or": true, "-empty": true}
+
+	for i := 0; i < len(args); i++ {
+		tok := args[i]
+		switch {
+		case tok == "-print":
+			actions.print = true
+		case tok == "-ls":
+			actions.long = true
+		case tok == "-delete":
+			actions.delete = true
+		case tok == "-exec":
+			var command []string
+			for i++; i < len(args) && args[i] != ";" && args[i] != "+"; i++ {
+				command = append(command, args[i])
+			}
+			if len(command) == 0 {
+				return "", nil, actions, nil, fmt.Errorf("-exec requires a command")
+			}
+			batch := i < len(args) && args[i] == "+"
+			if batch && command[len(command)-1] != "{}" {
+				return "", nil, actions, nil, fmt.Errorf("-exec ... + requires {} right before the +")
+			}
+			if command[0] == "rm" && (len(command) == 1 || (len(command) == 2 && command[1] == "{}")) {
+				actions.delete = true
+			} else if batch {
+				actions.exec = command[:len(command)-1]
+				actions.execBatch = true
+			} else {
+				actions.exec = command
+			}
+		case noValue[tok]:
+			predicates = append(predicates, tok)
+		case strings.HasPrefix(tok, "--") || (len(tok) == 2 && tok[0] == '-'):
+			flags = append(flags, tok)
+			if strings.Contains(tok, "=") || tok == "--" {
+				continue
+			}
+			var f *pflag.Flag
+			if strings.HasPrefix(tok, "--") {
+				f = cmd.Flags().Lookup(tok[2:])
+			} else {
+				f = cmd.Flags().ShorthandLookup(tok[1:])
+			}
+			if f != nil && f.Value.Type() != "bool" && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		case strings.HasPrefix(tok, "-"):
+			// A predicate and its value (which may itself start with -)
+			predicates = append(predicates, tok)
+			if i+1 < len(args) {
+				i++
+				predicates = append(predicates, args[i])
+			}
+		case path == "":
+			path = tok
+		default:
+			return "", nil, actions, nil, fmt.Errorf("unexpected argument %q (only one path is supported)", tok)
+		}
+	}
+	return path, predicates, actions, flags, nil
+}
+
+// findExec runs command once per match, replacing {} with the match's path
+// (or appending it if there is no {}).
+func findExec(command []string, matched []*resource.ResourceInfo, display func(string) string) error {
+	var firstErr error
+	for _, info := range matched {
+		path := display(info.Path)
+		argv := make([]string, 0, len(command)+1)
+		replaced := false
+		for _, arg := range command {
+			if strings.Contains(arg, "{}") {
+				arg = strings.ReplaceAll(arg, "{}", path)
+				replaced = true
+			}
+			argv = append(argv, arg)
+		}
+		if !replaced {
+			argv = append(argv, path)
+		}
+
+		c := exec.Command(argv[0], argv[1:]...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed: %s - %v\n", strings.Join(argv, " "), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// execBatchBytes caps the length of the paths -exec ... {} + passes to one
+// invocation, well below the system's argument limit; more matches run the
+// command again.
+const execBatchBytes = 128 << 10
+
+// findExecBatch runs command with the paths of as many matches as fit in
+// execBatchBytes appended, as often as needed (like find's -exec ... {} +).
+func findExecBatch(command []string, matched []*resource.ResourceInfo, display func(string) string) error {
+	var firstErr error
+	run := func(paths []string) {
+		argv := append(append([]string{}, command...), paths...)
+		c := exec.Command(argv[0], argv[1:]...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed: %s (%d path(s)) - %v\n", strings.Join(command, " "), len(paths), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	var paths []string
+	size := 0
+	for _, info := range matched {
+		path := display(info.Path)
+		if len(paths) > 0 && size+len(path) > execBatchBytes {
+			run(paths)
+			paths, size = nil, 0
+		}
+		paths = append(paths, path)
+		size += len(path) + 1
+	}
+	if len(paths) > 0 {
+		run(paths)
+	}
+	return firstErr
+}
+
+// findDelete deletes the matches after confirmation: GCS objects in parallel
+// through the rm worker pool (into the trash if rm.trash is set), BigQuery
+// tables one by one.
+func findDelete(ctx context.Context, res resource.Resource, fullPath string, matched []*resource.ResourceInfo, display func(string) string) error {
+	switch {
+	case resolver.IsGCSPath(fullPath):
+		var objects []*storage.ObjectInfo
+		var total int64
+		for _, info := range matched {
+			if obj, ok := info.Details.(*storage.ObjectInfo); ok && !obj.IsPrefix {
+				objects = append(objects, obj)
+				total += obj.Size
+			}
+		}
+		if len(objects) == 0 {
+			return nil
+		}
+		bucket, prefix, err := resolver.ParseGCSPath(fullPath)
+		if err != nil {
+			return err
+		}
+		var trash *storage.Trash
+		if !findNoTrash {
+			if trash, err = configuredTrash(fullPath, prefix); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Found %d matching object(s) (%s):\n", len(objects), storage.FormatSize(total))
+		for _, obj := range objects {
+			fmt.Printf("  - %s\n", display(obj.Path))
+		}
+		fmt.Println()
+		prompt := fmt.Sprintf("Permanently delete %d object(s)? (y/N): ", len(objects))
+		if trash != nil {
+			prompt = fmt.Sprintf("Move %d object(s) to the trash? (y/N): ", len(objects))
+		}
+		if !confirm(findForce, prompt) {
+			return nil
+		}
+
+		client, err := storage.GetClient(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create GCS client: %w", err)
+		}
+		return storage.RemoveObjects(ctx, client, bucket, objects, display, GetParallelism(), trash)
+
+	case resolver.IsBQPath(fullPath):
+		rem, ok := res.(resource.Removable)
+		if !ok {
+			return fmt.Errorf("-delete is not supported for %s resources", res.Type())
+		}
+		fmt.Printf("Found %d matching resource(s):\n", len(matched))
+		for _, info := range matched {
+			fmt.Printf("  - %s\n", display(info.Path))
+		}
+		fmt.Println()
+		if !confirm(findForce, fmt.Sprintf("Permanently delete %d resource(s)? (y/N): ", len(matched))) {
+			return nil
+		}
+		for _, info := range matched {
+			if err := rem.Remove(ctx, info.Path, &resource.RemoveOptions{Force: true, Verbose: verbose}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("-delete supports GCS objects and BigQuery tables only")
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/thieso2/cio/resource"
+)
+
+func TestSplitFindArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		path       string
+		predicates []string
+		actions    findActions
+		flags      []string
+		wantErr    string
+	}{
+		{
+			name:       "predicates and path",
+			args:       []string{":am/", "-name", "*.csv", "-size", "-1M"},
+			path:       ":am/",
+			predicates: []string{"-name", "*.csv", "-size", "-1M"},
+		},
+		{
+			name:       "operators",
+			args:       []string{":am/", "!", "-name", "a", "-o", "-empty"},
+			path:       ":am/",
+			predicates: []string{"!", "-name", "a", "-o", "-empty"},
+		},
+		{
+			name:    "cio flags",
+			args:    []string{"--config", "c.yaml", ":am/", "-f", "--json", "-j", "8", "-delete"},
+			path:    ":am/",
+			actions: findActions{delete: true},
+			flags:   []string{"--config", "c.yaml", "-f", "--json", "-j", "8"},
+		},
+		{
+			name:    "print and ls",
+			args:    []string{":am/", "-print", "-ls"},
+			path:    ":am/",
+			actions: findActions{print: true, long: true},
+		},
+		{
+			name:    "exec per match",
+			args:    []string{":am/", "-exec", "cio", "cat", "{}", ";"},
+			path:    ":am/",
+			actions: findActions{exec: []string{"cio", "cat", "{}"}},
+		},
+		{
+			name:    "exec batch",
+			args:    []string{":am/", "-exec", "echo", "{}", "+"},
+			path:    ":am/",
+			actions: findActions{exec: []string{"echo"}, execBatch: true},
+		},
+		{
+			name:    "exec rm deletes",
+			args:    []string{":am/", "-exec", "rm", "{}", "+"},
+			path:    ":am/",
+			actions: findActions{delete: true},
+		},
+		{
+			name:    "exec batch without {} last",
+			args:    []string{":am/", "-exec", "cp", "{}", "/tmp", "+"},
+			wantErr: "requires {} right before the +",
+		},
+		{
+			name:    "exec without command",
+			args:    []string{":am/", "-exec", ";"},
+			wantErr: "-exec requires a command",
+		},
+		{
+			name:    "two paths",
+			args:    []string{":am/", ":bm/"},
+			wantErr: "only one path is supported",
+		},
+	}
+
+	// cobra merges the root's persistent flags (--config, -j) into the
+	// command's flag set before running it
+	findCmd.InitDefaultHelpFlag()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, predicates, actions, flags, err := splitFindArgs(findCmd, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != tt.path {
+				t.Errorf("path = %q, want %q", path, tt.path)
+			}
+			if !reflect.DeepEqual(predicates, tt.predicates) {
+				t.Errorf("predicates = %q, want %q", predicates, tt.predicates)
+			}
+			if !reflect.DeepEqual(actions, tt.actions) {
+				t.Errorf("actions = %+v, want %+v", actions, tt.actions)
+			}
+			if !reflect.DeepEqual(flags, tt.flags) {
+				t.Errorf("flags = %q, want %q", flags, tt.flags)
+			}
+		})
+	}
+}
+
+func TestFindExecBatch(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "calls")
+	command := []string{"sh", "-c", `echo $# >> "$0"`, log}
+
+	tests := []struct {
+		name    string
+		matches int
+		pathLen int
+		calls   int
+	}{
+		{"one call", 50, 100, 1},
+		{"split at the batch size", 300, 1000, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(log)
+			matched := make([]*resource.ResourceInfo, tt.matches)
+			for i := range matched {
+				matched[i] = &resource.ResourceInfo{Path: strings.Repeat("x", tt.pathLen)}
+			}
+			if err := findExecBatch(command, matched, func(p string) string { return p }); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts := strings.Fields(string(data))
+			if len(counts) != tt.calls {
+				t.Errorf("%d call(s) with %v paths, want %d", len(counts), counts, tt.calls)
+			}
+			total := 0
+			for _, c := range counts {
+				n, err := strconv.Atoi(c)
+				if err != nil {
+					t.Fatal(err)
+				}
+				total += n
+			}
+			if total != tt.matches {
+				t.Errorf("passed %d path(s), want %d", total, tt.matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	grepIgnoreCase       bool
+	grepFixed            bool
+	grepInvert           bool
+	grepFilesWithMatches bool
+	grepCount            bool
+	grepContext          int
+	grepBefore           int
+	grepAfter            int
+	grepNoFilename       bool
+	grepRecursive        bool
+	grepRaw              bool
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep <pattern> <path>",
+	Short: "Search GCS objects for lines matching a pattern",
+	Long: `Search GCS objects for lines matching a regular expression (Go RE2 syntax).
+
+Works on a single object, every object under a prefix (-r), or a wildcard.
+Objects are read in parallel (-j) and printed in listing order, one line per
+match as path:line:text (context lines as path-line-text). Gzip content is
+decompressed transparently; use --raw to search the stored bytes.
+Like grep(1), the exit status is 1 when no line matched.
+
+Examples:
+  cio grep ERROR ':am/logs/2024-01-*'
+  cio grep -i 'timeout|refused' -C 2 ':am/logs/2024-01-*.log.gz'
+  cio grep -l customer_id=4711 -r :am/exports/
+  cio grep -c -F '[WARN]' ':am/logs/*.log'`,
+	Args: cobra.ExactArgs(2),
+	RunE: runGrep,
+}
+
+func init() {
+	grepCmd.Flags().BoolVarP(&grepIgnoreCase, "ignore-case", "i", false, "match case-insensitively")
+	grepCmd.Flags().BoolVarP(&grepFixed, "fixed-strings", "F", false, "treat the pattern as a literal string")
+	grepCmd.Flags().BoolVar(&grepInvert, "invert-match", false, "select non-matching lines")
+	grepCmd.Flags().BoolVarP(&grepFilesWithMatches, "files-with-matches", "l", false, "print only the paths of objects with a match")
+	grepCmd.Flags().BoolVarP(&grepCount, "count", "c", false, "print the number of matching lines per object")
+	grepCmd.Flags().IntVarP(&grepContext, "context", "C", 0, "print N lines of context around each match")
+	grepCmd.Flags().IntVarP(&grepBefore, "before-context", "B", 0, "print N lines of context before each match")
+	grepCmd.Flags().IntVarP(&grepAfter, "after-context", "A", 0, "print N lines of context after each match")
+	grepCmd.Flags().BoolVar(&grepNoFilename, "no-filename", false, "do not prefix output lines with the object path")
+	grepCmd.Flags().BoolVarP(&grepRecursive, "recursive", "r", false, "search every object under a prefix")
+	grepCmd.Flags().BoolVar(&grepRaw, "raw", false, "do not decompress gzip content")
+
+	rootCmd.AddCommand(grepCmd)
+}
+
+func runGrep(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	expr := args[0]
+	if grepFixed {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if grepIgnoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if grepFilesWithMatches && grepCount {
+		return fmt.Errorf("-l and -c are mutually exclusive")
+	}
+
+	r, fullPath, wasAlias, err := resolveInput(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q: %w", args[1], err)
+	}
+	if !resolver.IsGCSPath(fullPath) {
+		return fmt.Errorf("grep only supports GCS paths (gs:// or aliases mapping to GCS)")
+	}
+	bucket, object, err := resolver.ParseGCSPath(fullPath)
+	if err != nil {
+		return err
+	}
+
+	opts := &storage.GrepOptions{
+		Pattern:          pattern,
+		Invert:           grepInvert,
+		FilesWithMatches: grepFilesWithMatches,
+		Count:            grepCount,
+		Before:           grepContext,
+		After:            grepContext,
+		NoFilename:       grepNoFilename,
+		Raw:              grepRaw,
+		Recursive:        grepRecursive,
+		Formatter:        storage.DefaultPathFormatter,
+	}
+	if cmd.Flags().Changed("before-context") {
+		opts.Before = grepBefore
+	}
+	if cmd.Flags().Changed("after-context") {
+		opts.After = grepAfter
+	}
+	if wasAlias {
+		opts.Formatter = r.ReverseResolve
+	}
+
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	matched, err := storage.GrepObjects(ctx, client, bucket, object, os.Stdout, opts, GetParallelism())
+	if verbose {
+		fmt.Fprintf(os.Stderr, "%d object(s) with matches\n", matched)
+	}
+	if err == nil && matched == 0 {
+		return ErrNoMatch
+	}
+	return err
+}
+
+// ErrNoMatch is returned when a search such as grep found nothing. main
+// exits with status 1 without printing it, like grep(1).
+var ErrNoMatch = errors.New("no match")
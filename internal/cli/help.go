@@ -26,14 +26,31 @@ var schemeHelpTopics = []schemeHelpTopic{
 Paths:
   gs://bucket/path/                objects under a prefix
   gs://project-id:                 all buckets in a project (ls-new)
+  gs://bucket/object#generation    a specific object version
   :alias/path/                     via 'cio map <alias> gs://bucket/'
 
 Commands:
-  ls       list objects            -l, -r, --human-readable, --max-results, --json
-  cp       copy local <-> GCS      -r (recursive), -j N (parallel chunks)
+  ls       list objects            -l, -r, --human-readable, --max-results, --versions, --json
+  cp       copy local/GCS <-> GCS  -r (recursive), -j N (parallel chunks), --include/--exclude, --limit-rate 20M, --resume ID
+  mv       move/rename objects     -r, -f, wildcards (preview + confirmation)
+  sync     mirror dirs/prefixes    --delete, --dry-run, --checksum
   cat      print object(s) to stdout
-  du       disk usage of a prefix
-  rm       delete objects          -r, -f, wildcards (preview + confirmation)
+  grep     search object contents  -i, -l, -c, -C N, -F (parallel, gzip-aware)
+  du       disk usage of a prefix  -d N, --by storage-class|age, --cost
+  find     search by predicate     -name, -size +100M, -mtime +90, -storage-class, -delete
+  rm       delete objects          -r, -f, --all-versions, --noncurrent, --include/--exclude, --no-trash, wildcards
+  restore  make a noncurrent version live again
+  info     all metadata of an object
+  setmeta  edit headers/custom metadata in place  -r, --dry-run
+  signurl  temporary signed URLs    -m GET|PUT, -d 7d, -r, --csv, --credentials, --impersonate-service-account
+  rewrite  change storage class/key --storage-class, --rotate-key, -r, --dry-run (cost preview)
+  lifecycle  view/edit bucket lifecycle rules (YAML/JSON)
+  hold     set/clear object holds  --temporary, --event-based, -r, --dry-run
+  retention  show/lock bucket retention policies
+  transfers  list/retry recorded recursive copies (see cp --resume)
+  trash    ls/restore/purge objects removed with rm.trash set
+  mb       create a bucket         -l LOCATION, --storage-class, --versioning, --label, --retention
+  rb       delete a bucket         -r (delete all objects first), -f
   mount    FUSE filesystem (experimental)
 
 Wildcards: * and ? match object names — quote them in the shell.
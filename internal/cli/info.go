@@ -11,6 +11,7 @@ import (
 	"github.com/thieso2/cio/bigquery"
 	"github.com/thieso2/cio/resolver"
 	"github.com/thieso2/cio/resource"
+	"github.com/thieso2/cio/storage"
 )
 
 var infoCmd = &cobra.Command{
@@ -18,11 +19,14 @@ var infoCmd = &cobra.Command{
 	Short: "Show detailed information about resources",
 	Long: `Display detailed information about resources including schema, size, metadata, and dependency graphs.
 
-Supports BigQuery tables/views, Pub/Sub topics/subscriptions, Cloud SQL instances,
-Cloud Scheduler jobs, and GCP projects. GCS objects should use 'ls -l' instead.
+Supports GCS objects (all metadata: content headers, checksums, generation,
+custom key/value pairs), BigQuery tables/views, Pub/Sub topics/subscriptions,
+Cloud SQL instances, Cloud Scheduler jobs, and GCP projects.
 Supports wildcards: cio info 'bq://project.dataset.v_*'
 
 Examples:
+  cio info :am/2024/data.csv
+  cio info --json ':am/config.yaml#1712345678901234'
   cio info :mydata.events
   cio info bq://my-project-id.my-dataset.my-table
   cio info 'bq://my-project-id.my-dataset.v_*'
@@ -181,6 +185,13 @@ func printInfoJSON(info *resource.ResourceInfo, displayPath string) error {
 		enc.SetIndent("", "  ")
 		return enc.Encode(obj.ToJSON(displayPath))
 	}
+	if obj, ok := info.Details.(*storage.ObjectDetails); ok {
+		out := *obj
+		out.Path = displayPath
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
 	// Fallback: serialize ResourceInfo directly
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
@@ -16,14 +16,19 @@ import (
 // whether the input was an alias (so callers know whether to reverse-map output).
 func resolveInput(path string) (r *resolver.Resolver, fullPath string, wasAlias bool, err error) {
 	r = resolver.Create(cfg)
-	if resolver.IsDirectPath(path) {
-		return r, path, false, nil
+	fullPath, wasAlias = path, false
+	if !resolver.IsDirectPath(path) {
+		if fullPath, err = r.Resolve(path); err != nil {
+			return nil, "", false, err
+		}
+		wasAlias = true
 	}
-	fullPath, err = r.Resolve(path)
-	if err != nil {
-		return nil, "", false, err
+	if resolver.IsGCSPath(fullPath) {
+		if err := checkEncryptionKeys(fullPath); err != nil {
+			return nil, "", false, err
+		}
 	}
-	return r, fullPath, true, nil
+	return r, fullPath, wasAlias, nil
 }
 
 // newResourceFactory builds a factory whose output paths reverse-map to aliases
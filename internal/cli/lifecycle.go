@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	gcs "cloud.google.com/go/storage"
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	lifecycleForce bool
+	lifecycleAll   bool
+)
+
+var lifecycleCmd = &cobra.Command{
+	Use:   "lifecycle",
+	Short: "View and edit GCS bucket lifecycle rules",
+	Long: `View and edit the lifecycle rules of a GCS bucket.
+
+Rules are read from YAML or JSON files (use - for stdin), so retention policy
+can be kept in version control next to your cio config:
+
+  rules:
+    - action: SetStorageClass        # or Delete, AbortIncompleteMultipartUpload
+      storage_class: NEARLINE
+      condition:
+        age: 30                      # days since creation
+        prefix: [logs/]              # any of these name prefixes
+        storage_class: [STANDARD]    # any of these current classes
+    - action: Delete
+      condition:
+        age: 365
+        suffix: [.tmp]
+
+Other conditions: created_before (YYYY-MM-DD), live (true/false),
+num_newer_versions, days_since_noncurrent, days_since_custom_time.
+
+Paths may be aliases; rules always apply to the whole bucket.
+
+Examples:
+  cio lifecycle ls :am
+  cio lifecycle ls --json :am > lifecycle.json
+  cio lifecycle add :am rules.yaml
+  cio lifecycle rm :am 2
+  cio lifecycle set :am lifecycle.json`,
+}
+
+var lifecycleLsCmd = &cobra.Command{
+	Use:   "ls <bucket>",
+	Short: "List a bucket's lifecycle rules",
+	Long: `List a bucket's lifecycle rules with their index (used by 'lifecycle rm').
+With --json, prints the rules in the file format accepted by 'lifecycle set'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		client, bucket, err := gcsBucket(ctx, "lifecycle", args[0])
+		if err != nil {
+			return err
+		}
+
+		rules, err := storage.GetLifecycleRules(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+
+		if outputJSON {
+			return printSingleJSON(storage.LifecycleConfig{Rules: rules})
+		}
+		if len(rules) == 0 {
+			fmt.Printf("No lifecycle rules on gs://%s\n", bucket)
+			return nil
+		}
+		printLifecycleRules(rules)
+		return nil
+	},
+}
+
+var lifecycleAddCmd = &cobra.Command{
+	Use:   "add <bucket> <rules-file>",
+	Short: "Add lifecycle rules from a YAML/JSON file",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := readLifecycleFile(args[1])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, bucket, err := gcsBucket(ctx, "lifecycle", args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := storage.AddLifecycleRules(ctx, client, bucket, rules); err != nil {
+			return err
+		}
+		fmt.Printf("Added %d lifecycle rule(s) to gs://%s\n", len(rules), bucket)
+		return nil
+	},
+}
+
+var lifecycleSetCmd = &cobra.Command{
+	Use:   "set <bucket> <rules-file>",
+	Short: "Replace all lifecycle rules with those in a YAML/JSON file",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := readLifecycleFile(args[1])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, bucket, err := gcsBucket(ctx, "lifecycle", args[0])
+		if err != nil {
+			return err
+		}
+
+		current, err := storage.GetLifecycleRules(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+		if len(current) > 0 {
+			fmt.Printf("Current rules on gs://%s:\n", bucket)
+			printLifecycleRules(current)
+			fmt.Println()
+			if !confirm(lifecycleForce, fmt.Sprintf("Replace %d rule(s) with %d from %s? (y/N): ", len(current), len(rules), args[1])) {
+				return nil
+			}
+		}
+
+		if err := storage.SetLifecycleRules(ctx, client, bucket, rules); err != nil {
+			return err
+		}
+		fmt.Printf("Set %d lifecycle rule(s) on gs://%s\n", len(rules), bucket)
+		return nil
+	},
+}
+
+var lifecycleRmCmd = &cobra.Command{
+	Use:   "rm <bucket> [<index>...]",
+	Short: "Remove lifecycle rules by index (see 'lifecycle ls')",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if lifecycleAll == (len(args) > 1) {
+			return fmt.Errorf("give rule indexes (see 'cio lifecycle ls') or --all")
+		}
+		var indexes []int
+		for _, arg := range args[1:] {
+			i, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("invalid rule index %q", arg)
+			}
+			indexes = append(indexes, i)
+		}
+
+		ctx := context.Background()
+		client, bucket, err := gcsBucket(ctx, "lifecycle", args[0])
+		if err != nil {
+			return err
+		}
+
+		rules, err := storage.GetLifecycleRules(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			fmt.Printf("No lifecycle rules on gs://%s\n", bucket)
+			return nil
+		}
+
+		if lifecycleAll {
+			printLifecycleRules(rules)
+			fmt.Println()
+			if !confirm(lifecycleForce, fmt.Sprintf("Remove all %d lifecycle rule(s) from gs://%s? (y/N): ", len(rules), bucket)) {
+				return nil
+			}
+			if err := storage.SetLifecycleRules(ctx, client, bucket, nil); err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d lifecycle rule(s) from gs://%s\n", len(rules), bucket)
+			return nil
+		}
+
+		for _, i := range indexes {
+			if i < 0 || i >= len(rules) {
+				return fmt.Errorf("no lifecycle rule %d (bucket has %d rule(s))", i, len(rules))
+			}
+			action, conditions := rules[i].Describe()
+			fmt.Printf("  %d  %s  %s\n", i, action, conditions)
+		}
+		if !confirm(lifecycleForce, fmt.Sprintf("Remove %d lifecycle rule(s)? (y/N): ", len(indexes))) {
+			return nil
+		}
+		if err := storage.RemoveLifecycleRules(ctx, client, bucket, indexes); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d lifecycle rule(s) from gs://%s\n", len(indexes), bucket)
+		return nil
+	},
+}
+
+// gcsBucket resolves path (alias or gs://) to its bucket for a bucket-level
+// command.
+func gcsBucket(ctx context.Context, command, path string) (*gcs.Client, string, error) {
+	_, fullPath, _, err := resolveInput(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve %q: %w", path, err)
+	}
+	if !resolver.IsGCSPath(fullPath) {
+		return nil, "", fmt.Errorf("%s only supports GCS buckets (gs:// or aliases mapping to GCS)", command)
+	}
+	bucket, _, err := resolver.ParseGCSPath(fullPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if bucket == "" {
+		return nil, "", fmt.Errorf("a bucket is required, e.g. gs://my-bucket")
+	}
+
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return client, bucket, nil
+}
+
+// readLifecycleFile reads lifecycle rules from a YAML/JSON file, or stdin for "-".
+func readLifecycleFile(path string) ([]storage.LifecycleRule, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return storage.ParseLifecycleConfig(data)
+}
+
+func printLifecycleRules(rules []storage.LifecycleRule) {
+	rows := make([]string, len(rules))
+	for i := range rules {
+		action, conditions := rules[i].Describe()
+		rows[i] = fmt.Sprintf("%d\t%s\t%s", i, action, conditions)
+	}
+	renderTable("#\tACTION\tCONDITIONS", rows, "")
+}
+
+func init() {
+	lifecycleSetCmd.Flags().BoolVarP(&lifecycleForce, "force", "f", false, "replace without confirmation")
+	lifecycleRmCmd.Flags().BoolVarP(&lifecycleForce, "force", "f", false, "remove without confirmation")
+	lifecycleRmCmd.Flags().BoolVar(&lifecycleAll, "all", false, "remove all lifecycle rules")
+
+	lifecycleCmd.AddCommand(lifecycleLsCmd)
+	lifecycleCmd.AddCommand(lifecycleAddCmd)
+	lifecycleCmd.AddCommand(lifecycleSetCmd)
+	lifecycleCmd.AddCommand(lifecycleRmCmd)
+
+	rootCmd.AddCommand(lifecycleCmd)
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"os"
@@ -25,6 +26,7 @@ var (
 	lsAll           bool
 	lsMonth         string
 	lsSort          string
+	lsVersions      bool
 )
 
 var lsCmd = &cobra.Command{
@@ -52,6 +54,13 @@ Examples (GCS):
   cio ls -l :am/2024/
   cio ls ':am/logs/*.log'
 
+  # Include noncurrent versions (shown as path#generation)
+  cio ls -l --versions :am/config.yaml
+
+  # Stream a huge listing as it arrives (--raw and -r without -l stream;
+  # -S, -t, -l and --json collect the whole listing first)
+  cio ls -r --raw :am/ > objects.txt
+
 Examples (BigQuery):
   # List datasets in default project
   cio ls bq://
@@ -174,6 +183,11 @@ Examples (Pub/Sub):
 			ActiveOnly:    lsActiveOnly,
 			AllStatuses:   lsAll,
 			Month:         month,
+			Versions:      lsVersions,
+		}
+
+		if sl, ok := res.(resource.StreamLister); ok && lsStreams() {
+			return streamListing(ctx, sl, res, r, fullPath, options, inputWasAlias && !lsNoMap)
 		}
 
 		resources, err := res.List(ctx, fullPath, options)
@@ -283,6 +297,47 @@ Examples (Pub/Sub):
 	},
 }
 
+// lsStreams reports whether ls prints entries as the listing pages arrive
+// (constant memory, listing order): with --raw, and with -r without -l.
+// Sorting (-S, -t), column alignment (-l) and --json need the whole listing
+// and buffer it.
+func lsStreams() bool {
+	if lsSortBySize || lsSortByTime || outputJSON {
+		return false
+	}
+	return lsRaw || (lsRecursive && !lsLongFormat)
+}
+
+// streamListing prints one line per resource as the listing streams in,
+// without holding the listing in memory.
+func streamListing(ctx context.Context, sl resource.StreamLister, res resource.Resource, r *resolver.Resolver, fullPath string, options *resource.ListOptions, reverseMap bool) error {
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	count := 0
+	err := sl.ListEach(ctx, fullPath, options, func(info *resource.ResourceInfo) error {
+		count++
+		line := extractRawPath(info.Path)
+		if !lsRaw {
+			displayPath := info.Path
+			if reverseMap {
+				displayPath = r.ReverseResolve(info.Path)
+			}
+			line = res.FormatShort(info, displayPath)
+		}
+		// A write error (e.g. a closed pipe) stops the listing
+		_, err := fmt.Fprintln(out, line)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %w", err)
+	}
+	if count == 0 && verbose {
+		fmt.Fprintf(os.Stderr, "No resources found\n")
+	}
+	return nil
+}
+
 // hasMixedTypes returns true if resources contain both topics and subscriptions.
 func hasMixedTypes(resources []*resource.ResourceInfo) bool {
 	hasTopic := false
@@ -498,6 +553,7 @@ func init() {
 	lsCmd.Flags().BoolVarP(&lsAll, "all", "a", false, "show all statuses (include completed/failed executions)")
 	lsCmd.Flags().StringVar(&lsMonth, "month", "", "billing month (YYYY-MM or YYYYMM, default: current month)")
 	lsCmd.Flags().StringVar(&lsSort, "sort", "", "sort order for cost output (cost = by cost descending)")
+	lsCmd.Flags().BoolVar(&lsVersions, "versions", false, "include noncurrent object versions, addressed as path#generation (GCS)")
 
 	// Add to root command
 	rootCmd.AddCommand(lsCmd)
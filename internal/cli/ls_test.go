@@ -0,0 +1,34 @@
+package cli
+
+import "testing"
+
+func TestLsStreams(t *testing.T) {
+	defer func(raw, recursive, long, bySize, byTime, js bool) {
+		lsRaw, lsRecursive, lsLongFormat = raw, recursive, long
+		lsSortBySize, lsSortByTime, outputJSON = bySize, byTime, js
+	}(lsRaw, lsRecursive, lsLongFormat, lsSortBySize, lsSortByTime, outputJSON)
+
+	tests := []struct {
+		name                                     string
+		raw, recursive, long, bySize, byTime, js bool
+		want                                     bool
+	}{
+		{name: "plain ls"},
+		{name: "raw", raw: true, want: true},
+		{name: "recursive", recursive: true, want: true},
+		{name: "recursive long", recursive: true, long: true},
+		{name: "raw long", raw: true, long: true, want: true},
+		{name: "recursive sorted by size", recursive: true, bySize: true},
+		{name: "raw sorted by time", raw: true, byTime: true},
+		{name: "recursive json", recursive: true, js: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lsRaw, lsRecursive, lsLongFormat = tt.raw, tt.recursive, tt.long
+			lsSortBySize, lsSortByTime, outputJSON = tt.bySize, tt.byTime, tt.js
+			if got := lsStreams(); got != tt.want {
+				t.Errorf("lsStreams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
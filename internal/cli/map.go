@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sort"
@@ -8,19 +9,27 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
 )
 
+var mapCreateBucket bool
+
 var mapCmd = &cobra.Command{
 	Use:   "map",
 	Short: "Manage GCS and BigQuery path mappings",
 	Long: `Manage alias mappings to GCS bucket paths and BigQuery datasets.
 
 Mappings allow you to use short aliases instead of full gs:// or bq:// paths.
+If the bucket of a GCS mapping does not exist yet, map offers to create it (in
+the configured project and region; use 'cio mb' for more options).
 
 Examples:
   GCS:      mapping 'am' to 'gs://my-bucket/' lets you use 'cio ls :am'
   BigQuery: mapping 'mydata' to 'bq://project.dataset' lets you use 'cio ls :mydata'
 
+  # Create the bucket without asking if it does not exist yet
+  cio map --create scratch gs://my-new-scratch-bucket/
+
 Note: Aliases are created without the : prefix, but must be used with it.`,
 }
 
@@ -56,6 +65,14 @@ Examples:
 		// Normalize path only for GCS (BigQuery doesn't need trailing slash)
 		if resolver.IsGCSPath(path) {
 			path = resolver.NormalizePath(path)
+			ok, err := offerCreateBucket(path)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("Bucket not created; mapping %s not saved\n", alias)
+				return nil
+			}
 		}
 
 		// Check if alias already exists
@@ -153,7 +170,49 @@ var mapDeleteCmd = &cobra.Command{
 	},
 }
 
+// offerCreateBucket checks that the bucket of a GCS mapping exists and offers
+// to create it if not. It returns false if the user declined, which cancels
+// the mapping. If existence cannot be checked (e.g. no credentials or missing
+// permissions), the mapping goes ahead.
+func offerCreateBucket(path string) (bool, error) {
+	bucket, _, err := resolver.ParseGCSPath(path)
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.Background()
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check that bucket gs://%s exists: %v\n", bucket, err)
+		return true, nil
+	}
+
+	exists, err := storage.BucketExists(ctx, client, bucket)
+	if err != nil {
+		if verbose {
+			fmt.Printf("Could not check bucket gs://%s: %v\n", bucket, err)
+		}
+		return true, nil
+	}
+	if exists {
+		return true, nil
+	}
+
+	if !confirm(mapCreateBucket, fmt.Sprintf("Bucket gs://%s does not exist. Create it in project %s (%s)? (y/N): ",
+		bucket, cfg.Defaults.ProjectID, cfg.Defaults.Region)) {
+		return false, nil
+	}
+	opts := &storage.CreateBucketOptions{Location: cfg.Defaults.Region, UniformAccess: true}
+	if err := storage.CreateBucket(ctx, client, cfg.Defaults.ProjectID, bucket, opts); err != nil {
+		return false, err
+	}
+	fmt.Printf("Created bucket: gs://%s (%s)\n", bucket, opts)
+	return true, nil
+}
+
 func init() {
+	mapCmd.Flags().BoolVar(&mapCreateBucket, "create", false, "create the GCS bucket without asking if it does not exist")
+
 	// Add subcommands to map command
 	mapCmd.AddCommand(mapListCmd)
 	mapCmd.AddCommand(mapShowCmd)
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	mbLocation      string
+	mbStorageClass  string
+	mbUniformAccess bool
+	mbVersioning    bool
+	mbLabels        []string
+	mbRetention     string
+)
+
+var mbCmd = &cobra.Command{
+	Use:   "mb gs://<bucket>",
+	Short: "Create a GCS bucket",
+	Long: `Create a GCS bucket in the current project (--project or defaults.project_id).
+
+The location defaults to the configured region (defaults.region); use a
+multi-region such as US or EU, or a region such as europe-west3.
+
+A retention period (--retention) keeps every object for at least that long:
+objects cannot be deleted or overwritten before they reach that age.
+
+Examples:
+  cio mb gs://my-new-bucket
+  cio mb -l EU --storage-class NEARLINE --versioning gs://my-archive
+  cio mb --label team=data --label env=prod gs://my-data
+  cio mb --retention 30d gs://my-audit-logs
+
+  # Then map it
+  cio map data gs://my-data/`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bucket, err := bucketArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		opts := &storage.CreateBucketOptions{
+			Location:      mbLocation,
+			UniformAccess: mbUniformAccess,
+			Versioning:    mbVersioning,
+		}
+		if opts.Location == "" {
+			opts.Location = cfg.Defaults.Region
+		}
+		if mbStorageClass != "" {
+			if opts.StorageClass, err = storage.ValidateStorageClass(mbStorageClass); err != nil {
+				return err
+			}
+		}
+		if len(mbLabels) > 0 {
+			opts.Labels = make(map[string]string, len(mbLabels))
+			for _, l := range mbLabels {
+				key, value, ok := strings.Cut(l, "=")
+				if !ok || key == "" {
+					return fmt.Errorf("invalid label %q (expected key=value)", l)
+				}
+				opts.Labels[key] = value
+			}
+		}
+		if mbRetention != "" {
+			if opts.RetentionPeriod, err = parseDuration(mbRetention); err != nil {
+				return err
+			}
+		}
+
+		ctx := context.Background()
+		client, err := storage.GetClient(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create GCS client: %w", err)
+		}
+
+		if err := storage.CreateBucket(ctx, client, cfg.Defaults.ProjectID, bucket, opts); err != nil {
+			return err
+		}
+		fmt.Printf("Created bucket: gs://%s (%s)\n", bucket, opts)
+		return nil
+	},
+}
+
+// bucketArg parses a gs://bucket argument, rejecting paths below the bucket.
+func bucketArg(path string) (string, error) {
+	if !resolver.IsGCSPath(path) {
+		return "", fmt.Errorf("expected a bucket as gs://<bucket>, got %q", path)
+	}
+	bucket, object, err := resolver.ParseGCSPath(path)
+	if err != nil {
+		return "", err
+	}
+	if bucket == "" || object != "" {
+		return "", fmt.Errorf("expected a bucket as gs://<bucket>, got %q", path)
+	}
+	return bucket, nil
+}
+
+func init() {
+	mbCmd.Flags().StringVarP(&mbLocation, "location", "l", "", "bucket location, e.g. US, EU or europe-west3 (default: configured region)")
+	mbCmd.Flags().StringVar(&mbStorageClass, "storage-class", "", "default storage class (STANDARD, NEARLINE, COLDLINE, ARCHIVE)")
+	mbCmd.Flags().BoolVar(&mbUniformAccess, "uniform-access", true, "enable uniform bucket-level access (IAM only, no object ACLs)")
+	mbCmd.Flags().BoolVar(&mbVersioning, "versioning", false, "enable object versioning")
+	mbCmd.Flags().StringArrayVar(&mbLabels, "label", nil, "set a bucket label key=value (repeatable)")
+	mbCmd.Flags().StringVar(&mbRetention, "retention", "", "retention period, e.g. 30d or 1y")
+
+	rootCmd.AddCommand(mbCmd)
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	mvRecursive bool
+	mvForce     bool
+)
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <source> <destination>",
+	Short: "Move or rename GCS objects and prefixes",
+	Long: `Move or rename Google Cloud Storage objects.
+
+Each object is copied server-side (no local round-trip) and the source is
+deleted only after its copy succeeded. If some objects fail, a report lists
+which ones were not copied and which were copied but not deleted; re-running
+the same command resumes the move, since moved objects are gone from the source.
+
+Supports:
+  - Single object: cio mv :am/a.csv :am/archive/a.csv
+  - Into a prefix: cio mv :am/a.csv :am/archive/
+  - Whole prefix (-r): cio mv -r :am/2024/ :archive/2024/
+  - Wildcards: cio mv ':am/logs/*.log' :am/old-logs/
+
+Examples:
+  # Rename an object
+  cio mv :am/report.pdf :am/report-2024.pdf
+
+  # Move a prefix to another bucket
+  cio mv -r :raw/2024/ :archive/2024/
+
+  # Move matching objects (shows preview, asks for confirmation)
+  cio mv ':am/temp/*.csv' :am/processed/
+
+  # Move matching objects preserving their paths under the destination
+  cio mv -r ':am/*/exports/*.csv' :archive/
+
+  # Skip confirmation
+  cio mv -f -r :am/old/ :am/new/`,
+	Args: cobra.ExactArgs(2),
+	RunE: runMv,
+}
+
+func init() {
+	mvCmd.Flags().BoolVarP(&mvRecursive, "recursive", "r", false, "move directories recursively; also preserves structure for wildcards")
+	mvCmd.Flags().BoolVarP(&mvForce, "force", "f", false, "move without confirmation")
+
+	rootCmd.AddCommand(mvCmd)
+}
+
+func runMv(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	r, srcPath, srcWasAlias, err := resolveInput(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve source: %w", err)
+	}
+	_, dstPath, dstWasAlias, err := resolveInput(args[1])
+	if err != nil {
+		return fmt.Errorf("failed to resolve destination: %w", err)
+	}
+	if !resolver.IsGCSPath(srcPath) || !resolver.IsGCSPath(dstPath) {
+		return fmt.Errorf("mv only supports GCS paths (gs:// or aliases mapping to GCS)")
+	}
+
+	srcBucket, srcObject, err := resolver.ParseGCSPath(srcPath)
+	if err != nil {
+		return err
+	}
+	dstBucket, dstObject, err := resolver.ParseGCSPath(dstPath)
+	if err != nil {
+		return err
+	}
+	if srcBucket == "" || dstBucket == "" {
+		return fmt.Errorf("mv requires a bucket in both source and destination")
+	}
+
+	// Only reverse-map if either side was an alias
+	formatter := storage.PathFormatter(func(p string) string { return p })
+	if srcWasAlias || dstWasAlias {
+		formatter = r.ReverseResolve
+	}
+
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	var plan *storage.MovePlan
+	switch {
+	case resolver.HasWildcard(srcObject):
+		plan, err = storage.PlanMoveWithPattern(ctx, srcBucket, srcObject, dstBucket, dstObject, mvRecursive)
+		if err != nil {
+			return err
+		}
+		if plan.Len() == 0 {
+			fmt.Println("No matching objects found.")
+			return nil
+		}
+		fmt.Printf("Found %d matching object(s):\n", plan.Len())
+
+	case srcObject == "" || strings.HasSuffix(srcObject, "/"):
+		if !mvRecursive {
+			return fmt.Errorf("%q appears to be a directory (use -r to move recursively)", srcPath)
+		}
+		plan, err = storage.PlanMoveDirectory(ctx, client, srcBucket, srcObject, dstBucket, dstObject)
+		if err != nil {
+			return err
+		}
+		if plan.Len() == 0 {
+			return fmt.Errorf("no objects found with prefix %s", formatter(srcPath))
+		}
+		fmt.Printf("Found %d object(s) under %s:\n", plan.Len(), formatter(srcPath))
+	}
+
+	if plan != nil {
+		for _, src := range plan.Sources() {
+			fmt.Printf("  - %s\n", formatter(src))
+		}
+		fmt.Println()
+		if !confirm(mvForce, fmt.Sprintf("Move all %d object(s) to %s? (y/N): ", plan.Len(), formatter(dstPath))) {
+			return nil
+		}
+		return plan.Run(ctx, client, verbose, formatter, GetParallelism())
+	}
+
+	if !confirm(mvForce, fmt.Sprintf("Move file %s to %s? (y/N): ", formatter(srcPath), formatter(dstPath))) {
+		return nil
+	}
+	return storage.MoveObject(ctx, client, srcBucket, srcObject, dstBucket, dstObject, verbose, formatter)
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	rbRecursive bool
+	rbForce     bool
+)
+
+var rbCmd = &cobra.Command{
+	Use:   "rb gs://<bucket>",
+	Short: "Delete a GCS bucket",
+	Long: `Delete a GCS bucket.
+
+A bucket that still holds objects (including noncurrent versions) is refused
+unless -r is given. After confirmation (skip with -f), -r deletes every object
+and version in parallel (-j) before removing the bucket. Objects under a retention policy or hold cannot
+be deleted, so such a bucket cannot be removed until they expire.
+
+Examples:
+  cio rb gs://my-empty-bucket
+  cio rb -r gs://my-scratch-bucket
+  cio rb -rf gs://my-scratch-bucket`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bucket, err := bucketArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		client, err := storage.GetClient(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create GCS client: %w", err)
+		}
+
+		// Only -r destroys data; removing an empty bucket needs no confirmation
+		if rbRecursive && !confirm(rbForce, fmt.Sprintf("Delete bucket gs://%s and ALL objects and versions in it? (y/N): ", bucket)) {
+			return nil
+		}
+
+		return storage.RemoveBucket(ctx, client, bucket, rbRecursive, storage.DefaultPathFormatter, GetParallelism())
+	},
+}
+
+func init() {
+	rbCmd.Flags().BoolVarP(&rbRecursive, "recursive", "r", false, "delete all objects and versions, then the bucket")
+	rbCmd.Flags().BoolVarP(&rbForce, "force", "f", false, "delete without confirmation")
+
+	rootCmd.AddCommand(rbCmd)
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var restoreForce bool
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <path[#generation]>",
+	Short: "Restore a noncurrent version of a GCS object",
+	Long: `Make a noncurrent version of an object in a versioned bucket live again.
+
+The chosen version is copied server-side over the live object; the previously
+live version becomes noncurrent, so nothing is lost. Without a #generation the
+newest noncurrent version is restored — which undoes a plain 'cio rm' or the
+last overwrite. Use 'cio ls --versions' to see the available generations.
+
+The copy only succeeds if the live object has not changed since the versions
+were listed.
+
+Examples:
+  # Undelete an object (restore its newest noncurrent version)
+  cio restore :am/config.yaml
+
+  # Restore a specific version
+  cio restore ':am/config.yaml#1712345678901234'
+
+  # Skip confirmation
+  cio restore -f :am/config.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	restoreCmd.Flags().BoolVarP(&restoreForce, "force", "f", false, "restore without confirmation")
+
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	r, fullPath, wasAlias, err := resolveInput(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q: %w", args[0], err)
+	}
+	if !resolver.IsGCSPath(fullPath) {
+		return fmt.Errorf("restore only supports GCS paths (gs:// or aliases mapping to GCS)")
+	}
+
+	bucket, object, err := resolver.ParseGCSPath(fullPath)
+	if err != nil {
+		return err
+	}
+	name, generation := resolver.SplitGeneration(object)
+	if name == "" || strings.HasSuffix(name, "/") || resolver.HasWildcard(name) {
+		return fmt.Errorf("restore requires a single object path, got %q", args[0])
+	}
+
+	formatter := storage.DefaultPathFormatter
+	if wasAlias {
+		formatter = r.ReverseResolve
+	}
+
+	target := "newest noncurrent version of " + formatter(fmt.Sprintf("gs://%s/%s", bucket, name))
+	if generation != 0 {
+		target = formatter(fullPath)
+	}
+	if !confirm(restoreForce, fmt.Sprintf("Restore %s as the live version? (y/N): ", target)) {
+		return nil
+	}
+
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	return storage.RestoreObject(ctx, client, bucket, name, generation, verbose, formatter)
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/storage"
+)
+
+var retentionLockForce bool
+
+var retentionCmd = &cobra.Command{
+	Use:   "retention",
+	Short: "View and lock GCS bucket retention policies",
+	Long: `View and lock the retention policy of a GCS bucket. While an object is
+younger than the retention period it cannot be deleted or replaced; 'cio ls -l'
+and 'cio info' show when each object's retention ends. Set a policy when
+creating a bucket with 'cio mb --retention'.
+
+Paths may be aliases; the policy always applies to the whole bucket.
+
+Examples:
+  cio retention show :am
+  cio retention show --json gs://compliance-exports
+  cio retention lock gs://compliance-exports`,
+}
+
+var retentionShowCmd = &cobra.Command{
+	Use:   "show <bucket>",
+	Short: "Show a bucket's retention policy and default event-based hold",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		client, bucket, err := gcsBucket(ctx, "retention", args[0])
+		if err != nil {
+			return err
+		}
+
+		info, err := storage.GetRetention(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+		if outputJSON {
+			return printSingleJSON(info)
+		}
+		fmt.Print(info.FormatDetailed())
+		return nil
+	},
+}
+
+var retentionLockCmd = &cobra.Command{
+	Use:   "lock <bucket>",
+	Short: "Permanently lock a bucket's retention policy",
+	Long: `Permanently lock a bucket's retention policy. This cannot be undone: a
+locked policy can never be removed or shortened, only extended, and the bucket
+cannot be deleted until every object in it has reached the retention period.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		client, bucket, err := gcsBucket(ctx, "retention", args[0])
+		if err != nil {
+			return err
+		}
+
+		info, err := storage.GetRetention(ctx, client, bucket)
+		if err != nil {
+			return err
+		}
+		if info.Period == 0 || info.Locked {
+			return storage.LockRetention(ctx, client, info)
+		}
+
+		fmt.Print(info.FormatDetailed())
+		fmt.Println()
+		fmt.Println("CAUTION: locking is irreversible. The policy can then only be extended,")
+		fmt.Println("and the bucket cannot be deleted while it holds objects under retention.")
+		if !confirm(retentionLockForce, fmt.Sprintf("Permanently lock the retention policy of gs://%s? (y/N): ", bucket)) {
+			return nil
+		}
+		if err := storage.LockRetention(ctx, client, info); err != nil {
+			return err
+		}
+		fmt.Printf("Locked the retention policy of gs://%s\n", bucket)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(retentionCmd)
+	retentionCmd.AddCommand(retentionShowCmd)
+	retentionCmd.AddCommand(retentionLockCmd)
+	retentionLockCmd.Flags().BoolVarP(&retentionLockForce, "force", "f", false, "do not ask for confirmation")
+}
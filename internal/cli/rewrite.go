@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	rewriteStorageClass string
+	rewriteRecursive    bool
+	rewriteForce        bool
+	rewriteDryRun       bool
+	rewriteRotateKey    bool
+)
+
+var rewriteCmd = &cobra.Command{
+	Use:   "rewrite (--storage-class <class> | --rotate-key) <path>",
+	Short: "Change the storage class or encryption key of GCS objects",
+	Long: `Rewrite GCS objects server-side into another storage class (STANDARD,
+NEARLINE, COLDLINE, ARCHIVE), or re-encrypt them with a new key
+(--rotate-key), in parallel (-j).
+
+Works on a single object, every object under a prefix (-r), or a wildcard
+(** matches any depth). Before rewriting, a preview shows the selected size
+(as 'cio du' computes it), how much is left to rewrite, and the estimated
+monthly storage cost before and after. Objects already in the target class
+are skipped, so an interrupted rewrite resumes by running it again.
+
+With --rotate-key, each object is re-encrypted with the key configured for
+its location: the config's encryption section or --encryption-key-file /
+--kms-key. Objects are read with whichever configured key they use, so give
+the old CSEK as a decryption key (decryption_key_files in the config, or
+--decryption-key-file). Objects already on the target key are skipped.
+
+Each object keeps its content headers, custom metadata and (unless rotating)
+its encryption key. The rewrite is conditional on the generation just read, so
+a concurrent upload is never replaced by old data. On a versioned bucket the
+previous generation becomes noncurrent, and colder classes bill a minimum
+storage duration.
+
+Examples:
+  # Preview moving 2022 data to Nearline
+  cio rewrite --storage-class NEARLINE --dry-run ':am/2022/**'
+
+  # Move a prefix to Coldline without confirmation
+  cio rewrite -r -f --storage-class COLDLINE :am/archive/
+
+  # Bring one object back to Standard
+  cio rewrite --storage-class STANDARD :am/2022/hot.parquet
+
+  # Rotate a CSEK: encrypt with the new key, read with the old one
+  cio rewrite -r --rotate-key --encryption-key-file new.key --decryption-key-file old.key :secure/
+
+  # Move objects to the KMS key configured for the alias
+  cio rewrite -r --rotate-key :secure/`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRewrite,
+}
+
+func init() {
+	rewriteCmd.Flags().StringVar(&rewriteStorageClass, "storage-class", "", "target storage class (STANDARD, NEARLINE, COLDLINE, ARCHIVE)")
+	rewriteCmd.Flags().BoolVarP(&rewriteRecursive, "recursive", "r", false, "rewrite every object under a prefix; wildcards match at any depth")
+	rewriteCmd.Flags().BoolVarP(&rewriteForce, "force", "f", false, "rewrite without confirmation")
+	rewriteCmd.Flags().BoolVarP(&rewriteDryRun, "dry-run", "n", false, "show the preview only")
+	rewriteCmd.Flags().BoolVar(&rewriteRotateKey, "rotate-key", false, "re-encrypt with the key configured for each object's location (CSEK or CMEK)")
+
+	rootCmd.AddCommand(rewriteCmd)
+}
+
+func runRewrite(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if rewriteStorageClass == "" && !rewriteRotateKey {
+		return fmt.Errorf("--storage-class or --rotate-key is required")
+	}
+	var class string
+	if rewriteStorageClass != "" {
+		var err error
+		if class, err = storage.ValidateStorageClass(rewriteStorageClass); err != nil {
+			return err
+		}
+	}
+
+	r, fullPath, wasAlias, err := resolveInput(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q: %w", args[0], err)
+	}
+	if !resolver.IsGCSPath(fullPath) {
+		return fmt.Errorf("rewrite only supports GCS paths (gs:// or aliases mapping to GCS)")
+	}
+
+	bucket, object, err := resolver.ParseGCSPath(fullPath)
+	if err != nil {
+		return err
+	}
+
+	formatter := storage.DefaultPathFormatter
+	if wasAlias {
+		formatter = r.ReverseResolve
+	}
+
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	var plan *storage.RewritePlan
+	if rewriteRotateKey {
+		plan, err = storage.PlanKeyRotation(ctx, client, bucket, object, class, rewriteRecursive, GetParallelism())
+	} else {
+		plan, err = storage.PlanStorageClassRewrite(ctx, client, bucket, object, class, rewriteRecursive, GetParallelism())
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(plan.FormatPreview(formatter))
+	if len(plan.Objects) == 0 {
+		if rewriteRotateKey {
+			fmt.Println("Nothing to rewrite: all selected objects are already on the target key.")
+		} else {
+			fmt.Printf("Nothing to rewrite: all selected objects are already %s.\n", class)
+		}
+		return nil
+	}
+	if rewriteDryRun {
+		return nil
+	}
+	fmt.Println()
+
+	target := class
+	if rewriteRotateKey {
+		target = "the new key"
+		if class != "" {
+			target = class + " with the new key"
+		}
+	}
+	if !confirm(rewriteForce, fmt.Sprintf("Rewrite %d object(s) to %s? (y/N): ", len(plan.Objects), target)) {
+		return nil
+	}
+
+	return storage.ExecuteRewrite(ctx, client, plan, verbose, formatter, GetParallelism())
+}
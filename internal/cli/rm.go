@@ -9,11 +9,18 @@ import (
 	"github.com/thieso2/cio/bigquery"
 	"github.com/thieso2/cio/resolver"
 	"github.com/thieso2/cio/resource"
+	"github.com/thieso2/cio/storage"
 )
 
 var (
 	rmRecursive bool
 	rmForce     bool
+
+	rmAllVersions bool
+	rmNoncurrent  bool
+	rmNoTrash     bool
+
+	rmFilterFlags filterFlags
 )
 
 var rmCmd = &cobra.Command{
@@ -26,6 +33,21 @@ Examples (GCS):
   cio rm ':am/temp/*.tmp'
   cio rm -rf :am/old-data/
 
+  # On a versioned bucket, rm keeps the old data as a noncurrent version.
+  # Delete one specific version, or all versions, permanently:
+  cio rm ':am/config.yaml#1712345678901234'
+  cio rm --all-versions :am/config.yaml
+  cio rm -r --noncurrent :am/2024/
+
+  # Delete only some objects below a prefix
+  cio rm -r --include '**/*.tmp' :am/scratch/
+  cio rm -r --exclude 'keep/' --exclude '*.json' :am/scratch/
+
+  # With rm.trash set in the config, live objects are moved to the trash
+  # (see 'cio trash'; 'cio find -delete' uses it too); bypass it to delete
+  # permanently:
+  cio rm -r --no-trash :am/scratch/
+
 Examples (BigQuery):
   cio rm :mydata.events
   cio rm ':mydata.temp_*'
@@ -83,7 +105,9 @@ Examples (Projects):
   # Delete projects matching a pattern (lists matches, asks for confirmation)
   cio rm 'projects://staging-*'
 
-CAUTION: Deleted objects, tables, executions, VMs, and Pub/Sub resources cannot be recovered.
+CAUTION: Deleted objects, tables, executions, VMs, and Pub/Sub resources cannot be recovered,
+unless GCS objects went to the trash configured as rm.trash (by rm or find -delete). Deleting
+specific object versions (--all-versions, --noncurrent, name#generation) is always permanent.
 Project deletion is a soft delete: recoverable for ~30 days, but the project shuts down immediately.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -128,6 +152,22 @@ Project deletion is a soft delete: recoverable for ~30 days, but the project shu
 		} else if isBQ {
 			_, _, leaf, _ = bigquery.ParseBQPath(fullPath)
 		}
+		if (rmAllVersions || rmNoncurrent) && !isGCS {
+			return fmt.Errorf("--all-versions and --noncurrent only apply to GCS objects")
+		}
+		filter, err := rmFilterFlags.build()
+		if err != nil {
+			return err
+		}
+		isDir := leaf == "" || leaf[len(leaf)-1] == '/'
+		if filter != nil && (!isGCS || !rmRecursive || !isDir || resolver.HasWildcard(leaf)) {
+			return fmt.Errorf("--include, --exclude and --exclude-from apply to recursive GCS deletes (rm -r <prefix>/) only")
+		}
+
+		trash, err := rmTrash(fullPath, leaf)
+		if err != nil {
+			return err
+		}
 
 		// Only reverse-map if input was an alias
 		displayPath := fullPath
@@ -138,37 +178,58 @@ Project deletion is a soft delete: recoverable for ~30 days, but the project shu
 		hasWildcard := (isGCS || isBQ) && resolver.HasWildcard(leaf)
 
 		if hasWildcard {
-			// List matching resources
-			resources, err := res.List(ctx, fullPath, &resource.ListOptions{})
-			if err != nil {
-				return fmt.Errorf("failed to list matching resources: %w", err)
-			}
-
-			if len(resources) == 0 {
-				fmt.Println("No matching resources found.")
-				return nil
-			}
-
-			// Show matching resources
 			resourceWord := "object(s)"
 			if isBQ {
 				resourceWord = "table(s)"
 			}
 
-			fmt.Printf("Found %d matching %s:\n", len(resources), resourceWord)
-			for _, info := range resources {
+			// Show matching resources (every affected version when deleting
+			// versions). GCS matches are printed as the listing streams in,
+			// so huge matches are not held in memory.
+			matched := 0
+			show := func(info *resource.ResourceInfo) error {
+				if rmNoncurrent && !isNoncurrent(info) {
+					return nil
+				}
+				if matched == 0 {
+					fmt.Printf("Matching %s:\n", resourceWord)
+				}
+				matched++
 				// Only reverse-map if input was an alias
 				displayResourcePath := info.Path
 				if inputWasAlias {
 					displayResourcePath = r.ReverseResolve(info.Path)
 				}
 				fmt.Printf("  - %s\n", displayResourcePath)
+				return nil
+			}
+			listOpts := &resource.ListOptions{Versions: rmAllVersions || rmNoncurrent}
+			if sl, ok := res.(resource.StreamLister); ok {
+				err = sl.ListEach(ctx, fullPath, listOpts, show)
+			} else {
+				var resources []*resource.ResourceInfo
+				resources, err = res.List(ctx, fullPath, listOpts)
+				for _, info := range resources {
+					show(info)
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to list matching resources: %w", err)
+			}
+
+			if matched == 0 {
+				fmt.Println("No matching resources found.")
+				return nil
 			}
 			fmt.Println()
 
 			// Confirm deletion unless force flag is set
 			if !rmForce {
-				fmt.Printf("Remove all %d %s? (y/N): ", len(resources), resourceWord)
+				if trash != nil {
+					fmt.Printf("Move all %d %s to the trash? (y/N): ", matched, resourceWord)
+				} else {
+					fmt.Printf("Remove all %d %s? (y/N): ", matched, resourceWord)
+				}
 				var response string
 				fmt.Scanln(&response)
 				if response != "y" && response != "Y" {
@@ -192,7 +253,21 @@ Project deletion is a soft delete: recoverable for ~30 days, but the project shu
 					}
 				}
 
-				fmt.Printf("Remove %s %s? (y/N): ", resourceType, displayPath)
+				scope := ""
+				if rmAllVersions {
+					scope = "all versions of "
+				} else if rmNoncurrent {
+					scope = "noncurrent versions of "
+				}
+				if filter != nil {
+					scope += "the filtered objects in "
+				}
+
+				if trash != nil {
+					fmt.Printf("Move %s%s %s to the trash? (y/N): ", scope, resourceType, displayPath)
+				} else {
+					fmt.Printf("Remove %s%s %s? (y/N): ", scope, resourceType, displayPath)
+				}
 				var response string
 				fmt.Scanln(&response)
 				if response != "y" && response != "Y" {
@@ -204,10 +279,14 @@ Project deletion is a soft delete: recoverable for ~30 days, but the project shu
 
 		// Execute removal
 		options := &resource.RemoveOptions{
-			Recursive:   rmRecursive,
-			Force:       rmForce,
-			Verbose:     verbose,
-			Parallelism: GetParallelism(),
+			Recursive:      rmRecursive,
+			Force:          rmForce,
+			Verbose:        verbose,
+			Parallelism:    GetParallelism(),
+			AllVersions:    rmAllVersions,
+			NoncurrentOnly: rmNoncurrent,
+			Filter:         filter,
+			Trash:          trash,
 		}
 
 		rem, ok := res.(resource.Removable)
@@ -218,6 +297,48 @@ Project deletion is a soft delete: recoverable for ~30 days, but the project shu
 	},
 }
 
+// isNoncurrent reports whether info is a noncurrent GCS object version.
+func isNoncurrent(info *resource.ResourceInfo) bool {
+	obj, ok := info.Details.(*storage.ObjectInfo)
+	return ok && obj.Noncurrent
+}
+
+// rmTrash returns the configured trash when rm of the GCS object or prefix
+// leaf moves it there rather than deleting it: rm.trash is set, --no-trash
+// is not given, live objects are removed and the target is not inside the
+// trash itself.
+func rmTrash(fullPath, leaf string) (*storage.Trash, error) {
+	if rmNoTrash || rmAllVersions || rmNoncurrent {
+		return nil, nil
+	}
+	return configuredTrash(fullPath, leaf)
+}
+
+// configuredTrash returns the trash configured as rm.trash for removing the
+// live GCS object or prefix leaf of fullPath, or nil if there is none, the
+// path is not a GCS path, leaf names a specific generation or lies inside
+// the trash itself.
+func configuredTrash(fullPath, leaf string) (*storage.Trash, error) {
+	if cfg.Rm.Trash == "" || !resolver.IsGCSPath(fullPath) {
+		return nil, nil
+	}
+	if _, generation := resolver.SplitGeneration(leaf); generation != 0 {
+		return nil, nil
+	}
+	if err := checkEncryptionKeys(cfg.Rm.Trash); err != nil {
+		return nil, err
+	}
+	trash, err := storage.ParseTrash(cfg.Rm.Trash)
+	if err != nil {
+		return nil, err
+	}
+	bucket, _, _ := resolver.ParseGCSPath(fullPath)
+	if trash.Contains(bucket, leaf) {
+		return nil, nil
+	}
+	return trash, nil
+}
+
 // runDiscoverRemove lists matching resources across projects, shows them, and asks for confirmation.
 func runDiscoverRemove(cmd *cobra.Command, scheme, projectPattern, rest string) error {
 	ctx := context.Background()
@@ -332,6 +453,11 @@ func init() {
 	// Add flags
 	rmCmd.Flags().BoolVarP(&rmRecursive, "recursive", "r", false, "remove directories and their contents recursively")
 	rmCmd.Flags().BoolVarP(&rmForce, "force", "f", false, "force removal without confirmation")
+	rmCmd.Flags().BoolVar(&rmAllVersions, "all-versions", false, "permanently delete all versions, live and noncurrent (GCS)")
+	rmFilterFlags.register(rmCmd)
+	rmCmd.Flags().BoolVar(&rmNoncurrent, "noncurrent", false, "permanently delete only noncurrent versions, keeping live objects (GCS)")
+	rmCmd.MarkFlagsMutuallyExclusive("all-versions", "noncurrent")
+	rmCmd.Flags().BoolVar(&rmNoTrash, "no-trash", false, "delete permanently even when rm.trash is configured (GCS)")
 
 	// Add to root command
 	rootCmd.AddCommand(rmCmd)
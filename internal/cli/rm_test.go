@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/thieso2/cio/config"
+	"github.com/thieso2/cio/resolver"
+)
+
+func TestRmTrash(t *testing.T) {
+	savedCfg, savedNoTrash, savedAll := cfg, rmNoTrash, rmAllVersions
+	defer func() { cfg, rmNoTrash, rmAllVersions = savedCfg, savedNoTrash, savedAll }()
+
+	tests := []struct {
+		name     string
+		trash    string
+		fullPath string
+		noTrash  bool
+		all      bool
+		want     string // trash location, "" for a permanent delete
+	}{
+		{name: "object", trash: "gs://t/.cio-trash/", fullPath: "gs://b/a.csv", want: "gs://t/.cio-trash/"},
+		{name: "prefix", trash: "gs://t/.cio-trash", fullPath: "gs://b/dir/", want: "gs://t/.cio-trash/"},
+		{name: "no trash configured", fullPath: "gs://b/a.csv"},
+		{name: "--no-trash", trash: "gs://t/.cio-trash/", fullPath: "gs://b/a.csv", noTrash: true},
+		{name: "--all-versions", trash: "gs://t/.cio-trash/", fullPath: "gs://b/a.csv", all: true},
+		{name: "generation", trash: "gs://t/.cio-trash/", fullPath: "gs://b/a.csv#1712345678901234"},
+		{name: "inside the trash", trash: "gs://t/.cio-trash/", fullPath: "gs://t/.cio-trash/20261016-1/b/a.csv"},
+		{name: "BigQuery", trash: "gs://t/.cio-trash/", fullPath: "bq://p.d.t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg = &config.Config{}
+			cfg.Rm.Trash = tt.trash
+			rmNoTrash, rmAllVersions = tt.noTrash, tt.all
+			var leaf string
+			if resolver.IsGCSPath(tt.fullPath) {
+				_, leaf, _ = resolver.ParseGCSPath(tt.fullPath)
+			}
+			trash, err := rmTrash(tt.fullPath, leaf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if trash != nil {
+				got = trash.String()
+			}
+			if got != tt.want {
+				t.Errorf("rmTrash(%q) = %q, want %q", tt.fullPath, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/thieso2/cio/apilog"
 	"github.com/thieso2/cio/config"
+	"github.com/thieso2/cio/storage"
 )
 
 var (
@@ -19,6 +21,9 @@ var (
 	outputJSON  bool
 	parallelism int // Number of concurrent operations (cp/rm)
 
+	retryAttempts   int           // GCS attempts per request (overrides config)
+	retryMaxBackoff time.Duration // Longest pause between GCS retries (overrides config)
+
 	// Global config instance
 	cfg *config.Config
 
@@ -79,58 +84,89 @@ Examples:
 Run 'cio help schemes' to list all resource schemes, or 'cio help <scheme>://'
 (e.g. 'cio help cost://') for the commands and examples a resource supports.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		// Enable verbose from env var as well as --verbose flag
-		if os.Getenv("VERBOSE") != "" {
-			verbose = true
-		}
-		apilog.Verbose = verbose
+		return loadConfig(cmd)
+	},
+}
 
-		var err error
-		cfg, err = config.Load(cfgFile)
-		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
-		}
+// loadConfig loads the config and applies the global flags and environment
+// variables to it. It runs before every command; commands that parse their
+// own flags (find) override the hook and call it once they have.
+func loadConfig(cmd *cobra.Command) error {
+	// Enable verbose from env var as well as --verbose flag
+	if os.Getenv("VERBOSE") != "" {
+		verbose = true
+	}
+	apilog.Verbose = verbose
 
-		// Override config with flags if provided, then env vars
-		if projectID != "" {
-			cfg.Defaults.ProjectID = projectID
-		} else if envProject := os.Getenv("PROJECT_ID"); envProject != "" {
-			cfg.Defaults.ProjectID = envProject
-		}
-		if region != "" {
-			cfg.Defaults.Region = region
-		}
+	var err error
+	cfg, err = config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
 
-		// Handle parallelism configuration priority:
-		// 1. Command-line flag (if not default)
-		// 2. Environment variable CIO_PARALLEL
-		// 3. Config file value
-		// 4. Default value (50)
-		if cmd.Flags().Changed("parallel") {
-			// Flag was explicitly set, use it
-			cfg.Defaults.Parallelism = parallelism
-		} else if envParallel := os.Getenv("CIO_PARALLEL"); envParallel != "" {
-			// Try environment variable
-			if val, err := strconv.Atoi(envParallel); err == nil {
-				cfg.Defaults.Parallelism = val
-			}
-		}
-		// Otherwise use config file value or default (already set in config)
+	// Override config with flags if provided, then env vars
+	if projectID != "" {
+		cfg.Defaults.ProjectID = projectID
+	} else if envProject := os.Getenv("PROJECT_ID"); envProject != "" {
+		cfg.Defaults.ProjectID = envProject
+	}
+	if region != "" {
+		cfg.Defaults.Region = region
+	}
 
-		// Validate config
-		if err := cfg.Validate(); err != nil {
-			return fmt.Errorf("invalid configuration: %w", err)
+	// Handle parallelism configuration priority:
+	// 1. Command-line flag (if not default)
+	// 2. Environment variable CIO_PARALLEL
+	// 3. Config file value
+	// 4. Default value (50)
+	if cmd.Flags().Changed("parallel") {
+		// Flag was explicitly set, use it
+		cfg.Defaults.Parallelism = parallelism
+	} else if envParallel := os.Getenv("CIO_PARALLEL"); envParallel != "" {
+		// Try environment variable
+		if val, err := strconv.Atoi(envParallel); err == nil {
+			cfg.Defaults.Parallelism = val
 		}
+	}
+	// Otherwise use config file value or default (already set in config)
 
-		if verbose {
-			fmt.Fprintf(os.Stderr, "Config loaded from: %s\n", cfg.GetFilePath())
-			fmt.Fprintf(os.Stderr, "Project: %s\n", cfg.Defaults.ProjectID)
-			fmt.Fprintf(os.Stderr, "Region: %s\n", cfg.Defaults.Region)
-			fmt.Fprintf(os.Stderr, "Parallelism: %d\n", cfg.Defaults.Parallelism)
+	if cmd.Flags().Changed("retry-attempts") {
+		cfg.Retry.MaxAttempts = retryAttempts
+	}
+	if cmd.Flags().Changed("retry-max-backoff") {
+		cfg.Retry.MaxBackoff = retryMaxBackoff
+		if cfg.Retry.InitialBackoff > retryMaxBackoff {
+			cfg.Retry.InitialBackoff = retryMaxBackoff
 		}
+	}
 
-		return nil
-	},
+	// Validate config
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	storage.SetStorageClassPrices(cfg.Billing.StoragePrices)
+	storage.SetRetryPolicy(storage.RetryPolicy{
+		MaxAttempts:    cfg.Retry.MaxAttempts,
+		InitialBackoff: cfg.Retry.InitialBackoff,
+		MaxBackoff:     cfg.Retry.MaxBackoff,
+		Multiplier:     cfg.Retry.Multiplier,
+		RetryCodes:     cfg.Retry.RetryCodes,
+	})
+	if err := applyEncryption(); err != nil {
+		return err
+	}
+	if outputJSON {
+		storage.Progress = storage.ProgressJSON
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Config loaded from: %s\n", cfg.GetFilePath())
+		fmt.Fprintf(os.Stderr, "Project: %s\n", cfg.Defaults.ProjectID)
+		fmt.Fprintf(os.Stderr, "Region: %s\n", cfg.Defaults.Region)
+		fmt.Fprintf(os.Stderr, "Parallelism: %d\n", cfg.Defaults.Parallelism)
+	}
+
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -146,6 +182,11 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 	rootCmd.PersistentFlags().BoolVar(&outputJSON, "json", false, "output in JSON format")
 	rootCmd.PersistentFlags().IntVarP(&parallelism, "parallel", "j", 50, "number of parallel operations for cp/rm (1-200, can also be set via CIO_PARALLEL env var or config file)")
+	rootCmd.PersistentFlags().IntVar(&retryAttempts, "retry-attempts", 0, "attempts per GCS request on transient errors, 1 disables retries (overrides config retry.max_attempts, default 5)")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxBackoff, "retry-max-backoff", 0, "longest pause between GCS retries (overrides config retry.max_backoff, default 30s)")
+	rootCmd.PersistentFlags().StringVar(&encryptionKeyFile, "encryption-key-file", "", "file with a base64 AES-256 customer-supplied key (CSEK) to read and write GCS objects with (overrides config)")
+	rootCmd.PersistentFlags().StringVar(&kmsKey, "kms-key", "", "Cloud KMS key (CMEK) to write GCS objects with (overrides config)")
+	rootCmd.PersistentFlags().StringSliceVar(&decryptionKeyFiles, "decryption-key-file", nil, "file with a further CSEK accepted for reading GCS objects (repeatable)")
 }
 
 // GetConfig returns the global config instance
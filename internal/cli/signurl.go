@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	signurlMethod      string
+	signurlDuration    string
+	signurlContentType string
+	signurlCredentials string
+	signurlImpersonate string
+	signurlRecursive   bool
+	signurlCSV         bool
+)
+
+var signurlCmd = &cobra.Command{
+	Use:   "signurl <path>",
+	Short: "Create signed URLs for GCS objects",
+	Long: `Create V4 signed URLs that give anyone holding them temporary access to GCS
+objects, without a Google account.
+
+Works on a single object, every object under a prefix (-r), or a wildcard;
+one URL is printed per object. GET URLs download an object, PUT URLs upload
+to it (a PUT URL for a single object can be created before the object
+exists). URLs are valid for at most 7 days.
+
+Signing uses, in order:
+  --credentials FILE                   a service account key file
+  --impersonate-service-account SA     IAM signBlob as SA (needs the Service
+                                       Account Token Creator role on SA)
+  (neither)                            the current credentials, which must
+                                       be a service account
+
+Examples:
+  cio signurl :am/reports/2024-q1.pdf
+  cio signurl -d 7d -r --csv :am/reports/ > links.csv
+  cio signurl --json ':am/exports/*.parquet'
+  cio signurl -m PUT --content-type text/csv -d 2h :am/incoming/partner.csv
+  cio signurl --impersonate-service-account signer@my-project.iam.gserviceaccount.com :am/file.zip`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSignurl,
+}
+
+func init() {
+	signurlCmd.Flags().StringVarP(&signurlMethod, "method", "m", "GET", "HTTP method the URL allows: GET or PUT")
+	signurlCmd.Flags().StringVarP(&signurlDuration, "duration", "d", "1h", "validity, e.g. 30m, 12h, 7d (max 7d)")
+	signurlCmd.Flags().StringVar(&signurlContentType, "content-type", "", "Content-Type the client must send (PUT)")
+	signurlCmd.Flags().StringVarP(&signurlCredentials, "credentials", "c", "", "path to service account JSON file to sign with")
+	signurlCmd.Flags().StringVar(&signurlImpersonate, "impersonate-service-account", "", "service account to sign as via IAM signBlob")
+	signurlCmd.Flags().BoolVarP(&signurlRecursive, "recursive", "r", false, "sign every object under a prefix")
+	signurlCmd.Flags().BoolVar(&signurlCSV, "csv", false, "print path,method,expires,url as CSV")
+
+	rootCmd.AddCommand(signurlCmd)
+}
+
+func runSignurl(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if signurlCSV && outputJSON {
+		return fmt.Errorf("--csv and --json are mutually exclusive")
+	}
+	expires, err := parseDuration(signurlDuration)
+	if err != nil {
+		return err
+	}
+
+	r, fullPath, wasAlias, err := resolveInput(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q: %w", args[0], err)
+	}
+	if !resolver.IsGCSPath(fullPath) {
+		return fmt.Errorf("signurl only supports GCS paths (gs:// or aliases mapping to GCS)")
+	}
+	bucket, object, err := resolver.ParseGCSPath(fullPath)
+	if err != nil {
+		return err
+	}
+
+	formatter := storage.DefaultPathFormatter
+	if wasAlias {
+		formatter = r.ReverseResolve
+	}
+
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	urls, err := storage.SignURLs(ctx, client, bucket, object, &storage.SignURLOptions{
+		Method:          signurlMethod,
+		Expires:         expires,
+		ContentType:     signurlContentType,
+		CredentialsFile: signurlCredentials,
+		ServiceAccount:  signurlImpersonate,
+		Recursive:       signurlRecursive,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case outputJSON:
+		for i := range urls {
+			urls[i].Path = formatter(urls[i].Path)
+		}
+		return encodeJSON(urls)
+
+	case signurlCSV:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"path", "method", "expires", "url"})
+		for _, u := range urls {
+			w.Write([]string{formatter(u.Path), u.Method, u.Expires.UTC().Format(time.RFC3339), u.URL})
+		}
+		w.Flush()
+		return w.Error()
+
+	case len(urls) == 1:
+		fmt.Println(urls[0].URL)
+
+	default:
+		for _, u := range urls {
+			fmt.Printf("%s\n  %s\n", formatter(u.Path), u.URL)
+		}
+	}
+	if verbose {
+		fmt.Fprintf(os.Stderr, "%d URL(s), %s, valid until %s\n", len(urls), urls[0].Method, urls[0].Expires.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	syncDelete   bool
+	syncDryRun   bool
+	syncChecksum bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <source> <destination>",
+	Short: "Mirror a local directory and a GCS prefix (rsync-style)",
+	Long: `Synchronize the contents of a source directory/prefix into a destination,
+copying only files that are new or changed.
+
+Directions:
+  - Local to GCS: cio sync ./export/ :am/export/
+  - GCS to local: cio sync :am/export/ ./export/
+  - GCS to GCS:   cio sync :raw/2024/ :archive/2024/ (server-side rewrite)
+
+Files are compared by size first. Same-size files are considered unchanged
+when their modification times match (to the second); otherwise the local
+file's CRC32C is compared with the object's checksum. GCS-to-GCS syncs always
+compare CRC32C/MD5. Uploads record the file mtime in object metadata and
+downloads restore it, so repeated syncs skip unchanged files without hashing.
+
+A local source directory must exist, and --delete refuses an empty source
+instead of deleting every destination file.
+
+Examples:
+  # Upload new/changed files
+  cio sync ./reports/ :am/reports/
+
+  # Show what would happen, change nothing
+  cio sync --dry-run ./reports/ :am/reports/
+
+  # Mirror exactly: also delete destination files missing from the source
+  cio sync --delete :am/reports/ ./reports/
+
+  # Ignore mtimes, always compare checksums
+  cio sync --checksum ./reports/ :am/reports/`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSync,
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncDelete, "delete", false, "delete destination files that do not exist in the source")
+	syncCmd.Flags().BoolVarP(&syncDryRun, "dry-run", "n", false, "print the plan without copying or deleting anything")
+	syncCmd.Flags().BoolVarP(&syncChecksum, "checksum", "c", false, "always compare checksums, even when size and mtime match")
+
+	rootCmd.AddCommand(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	source, destination := args[0], args[1]
+
+	isCloudPath := func(p string) bool {
+		return resolver.IsGCSPath(p) || strings.HasPrefix(p, ":")
+	}
+
+	r := resolver.Create(cfg)
+	resolveSide := func(p string) (string, bool, error) {
+		if !isCloudPath(p) {
+			return p, false, nil
+		}
+		_, full, wasAlias, err := resolveInput(p)
+		if err != nil {
+			return "", false, err
+		}
+		if !resolver.IsGCSPath(full) {
+			return "", false, fmt.Errorf("sync only supports GCS paths (gs:// or aliases mapping to GCS): %s", p)
+		}
+		return full, wasAlias, nil
+	}
+
+	srcPath, srcWasAlias, err := resolveSide(source)
+	if err != nil {
+		return fmt.Errorf("failed to resolve source: %w", err)
+	}
+	dstPath, dstWasAlias, err := resolveSide(destination)
+	if err != nil {
+		return fmt.Errorf("failed to resolve destination: %w", err)
+	}
+
+	formatter := storage.PathFormatter(func(p string) string { return p })
+	if srcWasAlias || dstWasAlias {
+		formatter = r.ReverseResolve
+	}
+
+	opts := &storage.SyncOptions{
+		Delete:   syncDelete,
+		DryRun:   syncDryRun,
+		Checksum: syncChecksum,
+	}
+
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+
+	srcIsLocal := !isCloudPath(source)
+	dstIsLocal := !isCloudPath(destination)
+
+	switch {
+	case srcIsLocal && !dstIsLocal:
+		info, err := os.Stat(srcPath)
+		if err != nil {
+			return fmt.Errorf("cannot access %q: %w", srcPath, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%q is not a directory (use 'cio cp' for single files)", srcPath)
+		}
+		bucket, prefix, err := parseSyncBucket(dstPath)
+		if err != nil {
+			return err
+		}
+		return storage.SyncLocalToGCS(ctx, client, srcPath, bucket, prefix, verbose, formatter, GetParallelism(), opts)
+
+	case !srcIsLocal && dstIsLocal:
+		bucket, prefix, err := parseSyncBucket(srcPath)
+		if err != nil {
+			return err
+		}
+		return storage.SyncGCSToLocal(ctx, client, bucket, prefix, dstPath, verbose, formatter, GetParallelism(), opts)
+
+	case !srcIsLocal && !dstIsLocal:
+		srcBucket, srcPrefix, err := parseSyncBucket(srcPath)
+		if err != nil {
+			return err
+		}
+		dstBucket, dstPrefix, err := parseSyncBucket(dstPath)
+		if err != nil {
+			return err
+		}
+		return storage.SyncGCSToGCS(ctx, client, srcBucket, srcPrefix, dstBucket, dstPrefix, verbose, formatter, GetParallelism(), opts)
+
+	default:
+		return fmt.Errorf("use system 'rsync' for local to local sync")
+	}
+}
+
+// parseSyncBucket parses a GCS sync root, rejecting wildcards and bucket listings.
+func parseSyncBucket(gcsPath string) (bucket, prefix string, err error) {
+	bucket, prefix, err = resolver.ParseGCSPath(gcsPath)
+	if err != nil {
+		return "", "", err
+	}
+	if bucket == "" || strings.HasSuffix(bucket, ":") {
+		return "", "", fmt.Errorf("sync requires a bucket: %s", gcsPath)
+	}
+	if resolver.HasWildcard(prefix) {
+		return "", "", fmt.Errorf("sync does not support wildcards: %s", gcsPath)
+	}
+	return bucket, prefix, nil
+}
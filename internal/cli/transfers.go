@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	transfersAll       bool
+	transfersCompleted bool
+	transfersForce     bool
+)
+
+var transfersCmd = &cobra.Command{
+	Use:   "transfers",
+	Short: "List, retry and remove recorded recursive copies",
+	Long: `Every recursive directory copy ('cio cp -r' between local and GCS) is
+recorded in the transfers directory next to the config file
+(~/.config/cio/transfers). The record holds a snapshot of the source listing
+(with object generations for downloads) and the outcome of each item, so an
+interrupted copy can continue where it stopped without relisting:
+
+  cio cp --resume <id>         run every item not done yet
+  cio transfers retry <id>     run only the items that failed
+
+The record is removed once every item has been copied. A download that is
+resumed fetches exactly the generations listed, even if an object has been
+replaced since.
+
+Examples:
+  cio transfers ls
+  cio transfers ls --all
+  cio transfers retry 20261016-153012-3fa9
+  cio transfers rm 20261016-153012-3fa9
+  cio transfers rm --completed`,
+}
+
+var transfersLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List recorded transfers, newest first",
+	Long: `List recorded transfers, newest first. Completed transfers are hidden
+unless --all is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		transfers, err := transferStore().List()
+		if err != nil {
+			return err
+		}
+		if !transfersAll {
+			shown := transfers[:0]
+			for _, t := range transfers {
+				if t.State() != "complete" {
+					shown = append(shown, t)
+				}
+			}
+			transfers = shown
+		}
+
+		if outputJSON {
+			type transferJSON struct {
+				*storage.TransferStatus
+				State  string            `json:"state"`
+				Errors map[string]string `json:"errors,omitempty"`
+			}
+			out := make([]transferJSON, len(transfers))
+			for i, t := range transfers {
+				out[i] = transferJSON{TransferStatus: t, State: t.State(), Errors: t.Errors}
+			}
+			return printSingleJSON(out)
+		}
+		if len(transfers) == 0 {
+			if transfersAll {
+				fmt.Println("No recorded transfers")
+			} else {
+				fmt.Println("No incomplete transfers (use --all to include completed ones)")
+			}
+			return nil
+		}
+
+		rows := make([]string, len(transfers))
+		for i, t := range transfers {
+			rows[i] = fmt.Sprintf("%s\t%s\t%s\t%d/%d\t%d\t%s\t%s\t%s → %s",
+				t.ID, t.Created.Local().Format("2006-01-02 15:04"), t.Direction,
+				t.Done, t.Total, t.Failed, storage.FormatSize(t.TotalBytes), t.State(),
+				t.Source, t.Destination)
+		}
+		renderTable("ID\tSTARTED\tDIRECTION\tDONE\tFAILED\tSIZE\tSTATE\tSOURCE → DESTINATION", rows, "")
+
+		if verbose {
+			for _, t := range transfers {
+				if len(t.Errors) == 0 {
+					continue
+				}
+				fmt.Printf("\nFailed items of %s:\n", t.ID)
+				paths := make([]string, 0, len(t.Errors))
+				for p := range t.Errors {
+					paths = append(paths, p)
+				}
+				sort.Strings(paths)
+				for _, p := range paths {
+					fmt.Printf("  %s: %s\n", p, t.Errors[p])
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var transfersRetryCmd = &cobra.Command{
+	Use:   "retry <id>",
+	Short: "Re-run the failed items of a transfer",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return resumeTransfer(context.Background(), args[0], true)
+	},
+}
+
+var transfersRmCmd = &cobra.Command{
+	Use:   "rm [<id>...]",
+	Short: "Remove transfer records",
+	Long: `Remove transfer records by ID, or all completed ones with --completed.
+Only the records are removed; copied files and objects are not touched.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := transferStore()
+		if transfersCompleted == (len(args) > 0) {
+			return fmt.Errorf("give transfer IDs (see 'cio transfers ls') or --completed")
+		}
+		ids := args
+		if transfersCompleted {
+			transfers, err := store.List()
+			if err != nil {
+				return err
+			}
+			for _, t := range transfers {
+				if t.State() == "complete" {
+					ids = append(ids, t.ID)
+				}
+			}
+			if len(ids) == 0 {
+				fmt.Println("No completed transfers")
+				return nil
+			}
+			if !confirm(transfersForce, fmt.Sprintf("Remove %d completed transfer record(s)? (y/N): ", len(ids))) {
+				return nil
+			}
+		}
+		for _, id := range ids {
+			if err := store.Remove(id); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Removed %d transfer record(s)\n", len(ids))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(transfersCmd)
+	transfersCmd.AddCommand(transfersLsCmd)
+	transfersCmd.AddCommand(transfersRetryCmd)
+	transfersCmd.AddCommand(transfersRmCmd)
+	transfersLsCmd.Flags().BoolVarP(&transfersAll, "all", "a", false, "include completed transfers")
+	transfersRmCmd.Flags().BoolVar(&transfersCompleted, "completed", false, "remove all completed transfer records")
+	transfersRmCmd.Flags().BoolVarP(&transfersForce, "force", "f", false, "do not ask for confirmation")
+}
+
+// transferStore returns the store of recorded transfers, kept next to the
+// config file.
+func transferStore() *storage.TransferStore {
+	return &storage.TransferStore{Dir: filepath.Join(filepath.Dir(cfg.GetFilePath()), "transfers")}
+}
+
+// resumeTransfer continues transfer id with the cp options (parallelism,
+// --limit-rate, --no-verify) of this invocation. With onlyFailed, only the
+// items that failed are run.
+func resumeTransfer(ctx context.Context, id string, onlyFailed bool) error {
+	store := transferStore()
+	status, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	gcsPath := status.Source
+	if status.Direction == storage.TransferUpload {
+		gcsPath = status.Destination
+	}
+	if err := checkEncryptionKeys(gcsPath); err != nil {
+		return err
+	}
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	if cpRateLimiter, err = parseLimitRate(cpLimitRate); err != nil {
+		return err
+	}
+
+	formatter := storage.PathFormatter(func(p string) string { return p })
+	dlOpts := cpDownloadOptions()
+	dlOpts.Force = true // a failed or interrupted file may have the right size but wrong content
+	return storage.ResumeTransfer(ctx, client, store, id, onlyFailed, verbose, formatter, GetParallelism(), dlOpts, cpUploadOptions())
+}
@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	"github.com/spf13/cobra"
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+var (
+	trashRestoreForce bool
+	trashPurgeOlder   string
+	trashPurgeAll     bool
+	trashPurgeForce   bool
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore and purge objects removed into the rm trash",
+	Long: `When rm.trash is set in the config, 'cio rm' moves live GCS objects into
+that location instead of deleting them:
+
+  rm:
+    trash: gs://my-bucket/.cio-trash/
+
+Each rm run becomes one batch, stored below <trash>/<batch id>/ with the
+original path recorded in the object metadata (cio-trash-source). Batches
+can be listed, restored to their original paths, or purged for good.
+
+Examples:
+  cio trash ls
+  cio trash ls 20261016-153012-3fa9
+  cio trash restore 20261016-153012-3fa9
+  cio trash purge --older-than 7d
+  cio trash purge 20261016-153012-3fa9`,
+}
+
+var trashLsCmd = &cobra.Command{
+	Use:   "ls [<id>]",
+	Short: "List trash batches, or the objects of one batch",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		trash, client, err := openTrash(ctx)
+		if err != nil {
+			return err
+		}
+		r := resolver.Create(cfg)
+
+		if len(args) == 1 {
+			objects, err := storage.ListTrashBatch(ctx, client, trash, args[0])
+			if err != nil {
+				return err
+			}
+			if outputJSON {
+				return printSingleJSON(objects)
+			}
+			rows := make([]string, len(objects))
+			for i, o := range objects {
+				rows[i] = fmt.Sprintf("%s\t%s", storage.FormatSize(o.Size), r.ReverseResolve(o.Source))
+			}
+			renderTable("SIZE\tORIGINAL PATH", rows, "")
+			return nil
+		}
+
+		batches, err := storage.ListTrash(ctx, client, trash)
+		if err != nil {
+			return err
+		}
+		if outputJSON {
+			return printSingleJSON(batches)
+		}
+		if len(batches) == 0 {
+			fmt.Printf("Trash %s is empty\n", trash)
+			return nil
+		}
+		rows := make([]string, len(batches))
+		for i, b := range batches {
+			rows[i] = fmt.Sprintf("%s\t%s\t%d\t%s\t%s",
+				b.ID, b.Deleted.Local().Format("2006-01-02 15:04"), b.Objects,
+				storage.FormatSize(b.Bytes), r.ReverseResolve(b.Source))
+		}
+		renderTable("ID\tDELETED\tOBJECTS\tSIZE\tSOURCE", rows, "")
+		return nil
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Move the objects of a trash batch back to their original paths",
+	Long: `Move the objects of a trash batch back to their original paths.
+An object that has been recreated at its original path since it was removed
+is left in the trash unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		trash, client, err := openTrash(ctx)
+		if err != nil {
+			return err
+		}
+		r := resolver.Create(cfg)
+		return storage.RestoreTrash(ctx, client, trash, args[0], trashRestoreForce, r.ReverseResolve, GetParallelism())
+	},
+}
+
+var trashPurgeCmd = &cobra.Command{
+	Use:   "purge [<id>...]",
+	Short: "Permanently delete trash batches",
+	Long: `Permanently delete trash batches: the given IDs, all batches removed
+longer ago than --older-than, or everything with --all.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		selectors := 0
+		for _, set := range []bool{len(args) > 0, trashPurgeOlder != "", trashPurgeAll} {
+			if set {
+				selectors++
+			}
+		}
+		if selectors != 1 {
+			return fmt.Errorf("give batch IDs (see 'cio trash ls'), --older-than or --all")
+		}
+		var cutoff time.Time
+		if trashPurgeOlder != "" {
+			age, err := parseDuration(trashPurgeOlder)
+			if err != nil {
+				return fmt.Errorf("invalid --older-than: %w", err)
+			}
+			cutoff = time.Now().Add(-age)
+		}
+
+		ctx := context.Background()
+		trash, client, err := openTrash(ctx)
+		if err != nil {
+			return err
+		}
+
+		ids := args
+		if len(ids) == 0 {
+			batches, err := storage.ListTrash(ctx, client, trash)
+			if err != nil {
+				return err
+			}
+			var objects int
+			var bytes int64
+			for _, b := range batches {
+				if trashPurgeAll || b.Deleted.Before(cutoff) {
+					ids = append(ids, b.ID)
+					objects += b.Objects
+					bytes += b.Bytes
+				}
+			}
+			if len(ids) == 0 {
+				fmt.Println("Nothing to purge")
+				return nil
+			}
+			prompt := fmt.Sprintf("Permanently delete %d trash batch(es) (%d objects, %s)? (y/N): ",
+				len(ids), objects, storage.FormatSize(bytes))
+			if !confirm(trashPurgeForce, prompt) {
+				return nil
+			}
+		} else if !confirm(trashPurgeForce, fmt.Sprintf("Permanently delete %d trash batch(es)? (y/N): ", len(ids))) {
+			return nil
+		}
+
+		r := resolver.Create(cfg)
+		return storage.PurgeTrash(ctx, client, trash, ids, r.ReverseResolve, GetParallelism())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashLsCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashPurgeCmd)
+	trashRestoreCmd.Flags().BoolVar(&trashRestoreForce, "force", false, "overwrite objects recreated at their original path")
+	trashPurgeCmd.Flags().StringVar(&trashPurgeOlder, "older-than", "", "purge batches removed longer ago than this (e.g. 7d, 12h)")
+	trashPurgeCmd.Flags().BoolVar(&trashPurgeAll, "all", false, "purge the whole trash")
+	trashPurgeCmd.Flags().BoolVarP(&trashPurgeForce, "force", "f", false, "do not ask for confirmation")
+}
+
+// openTrash returns the trash configured as rm.trash and a GCS client.
+func openTrash(ctx context.Context) (*storage.Trash, *gcs.Client, error) {
+	if cfg.Rm.Trash == "" {
+		return nil, nil, fmt.Errorf("no trash configured (set rm.trash to a gs://bucket/prefix/ location in %s)", cfg.GetFilePath())
+	}
+	if err := checkEncryptionKeys(cfg.Rm.Trash); err != nil {
+		return nil, nil, err
+	}
+	trash, err := storage.ParseTrash(cfg.Rm.Trash)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return trash, client, nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/storage"
+	storagepkg "github.com/thieso2/cio/storage"
 	"google.golang.org/api/iterator"
 )
 
@@ -110,16 +111,19 @@ type ReadAheadBuffer struct {
 	mu         sync.Mutex
 	bucketName string
 	objectName string
+	keySHA256  string // customer-supplied key of the object, if any
 	buffer     []byte
 	offset     int64
 	valid      bool
 }
 
-// NewReadAheadBuffer creates a new read-ahead buffer
-func NewReadAheadBuffer(bucketName, objectName string) *ReadAheadBuffer {
+// NewReadAheadBuffer creates a new read-ahead buffer. keySHA256 identifies
+// the customer-supplied key the object is encrypted with ("" for none).
+func NewReadAheadBuffer(bucketName, objectName, keySHA256 string) *ReadAheadBuffer {
 	return &ReadAheadBuffer{
 		bucketName: bucketName,
 		objectName: objectName,
+		keySHA256:  keySHA256,
 		buffer:     make([]byte, 0, ReadAheadBufferSize),
 	}
 }
@@ -154,7 +158,11 @@ func (b *ReadAheadBuffer) Read(ctx context.Context, bucket *storage.BucketHandle
 
 	// Actual GCS API call
 	apiStart := time.Now()
-	reader, err := bucket.Object(b.objectName).NewRangeReader(ctx, off, int64(readSize))
+	obj, err := storagepkg.WithReadKey(bucket.Object(b.objectName), b.keySHA256)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := obj.NewRangeReader(ctx, off, int64(readSize))
 	if err != nil {
 		logGC("GCS:ReadObject", apiStart, "bucket", b.bucketName, "object", b.objectName,
 			"offset", off, "size", readSize, "ERROR", err)
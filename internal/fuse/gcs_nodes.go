@@ -276,7 +276,11 @@ func (n *ObjectNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off
 	// Initialize read-ahead buffer on first read
 	n.readAheadMu.Lock()
 	if n.readAhead == nil {
-		n.readAhead = NewReadAheadBuffer(n.bucketName, n.objectName)
+		keySHA256 := ""
+		if n.attrs != nil {
+			keySHA256 = n.attrs.CustomerKeySHA256
+		}
+		n.readAhead = NewReadAheadBuffer(n.bucketName, n.objectName, keySHA256)
 	}
 	buffer := n.readAhead
 	n.readAheadMu.Unlock()
@@ -2,6 +2,7 @@ package resolver
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/thieso2/cio/config"
@@ -220,6 +221,22 @@ func ParseGCSPath(gcsPath string) (bucket, object string, err error) {
 	return bucket, object, nil
 }
 
+// SplitGeneration splits a "name#generation" object reference (the gsutil
+// syntax for addressing a specific object version) into its parts.
+// Returns the object unchanged and generation 0 when there is no numeric
+// "#<generation>" suffix.
+func SplitGeneration(object string) (name string, generation int64) {
+	idx := strings.LastIndex(object, "#")
+	if idx <= 0 || idx == len(object)-1 {
+		return object, 0
+	}
+	gen, err := strconv.ParseInt(object[idx+1:], 10, 64)
+	if err != nil || gen <= 0 {
+		return object, 0
+	}
+	return object[:idx], gen
+}
+
 // IsGCSPath checks if a string is a GCS path
 func IsGCSPath(path string) bool {
 	return strings.HasPrefix(path, "gs://")
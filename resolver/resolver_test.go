@@ -0,0 +1,30 @@
+package resolver
+
+import "testing"
+
+func TestSplitGeneration(t *testing.T) {
+	tests := []struct {
+		object     string
+		name       string
+		generation int64
+	}{
+		{"data.csv#1700000000123456", "data.csv", 1700000000123456},
+		{"dir/a#b.txt#42", "dir/a#b.txt", 42},
+		{"data.csv", "data.csv", 0},
+		{"data.csv#", "data.csv#", 0},
+		{"#42", "#42", 0},
+		{"data.csv#abc", "data.csv#abc", 0},
+		{"data.csv#0", "data.csv#0", 0},
+		{"data.csv#-1", "data.csv#-1", 0},
+		{"", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.object, func(t *testing.T) {
+			name, gen := SplitGeneration(tt.object)
+			if name != tt.name || gen != tt.generation {
+				t.Errorf("SplitGeneration(%q) = %q, %d, want %q, %d", tt.object, name, gen, tt.name, tt.generation)
+			}
+		})
+	}
+}
@@ -80,11 +80,21 @@ func (b *BigQueryResource) List(ctx context.Context, path string, options *ListO
 			return nil, err
 		}
 	} else {
-		// List datasets in project
-		bqObjects, err = bigquery.ListDatasets(ctx, projectID)
+		// List datasets in project, and their tables when recursive
+		datasets, err := bigquery.ListDatasets(ctx, projectID)
 		if err != nil {
 			return nil, err
 		}
+		bqObjects = datasets
+		if options != nil && options.Recursive {
+			for _, dataset := range datasets {
+				tables, err := bigquery.ListTables(ctx, projectID, strings.TrimPrefix(dataset.Path, "bq://"+projectID+"."))
+				if err != nil {
+					return nil, err
+				}
+				bqObjects = append(bqObjects, tables...)
+			}
+		}
 	}
 
 	// Convert to ResourceInfo
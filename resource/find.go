@@ -0,0 +1,236 @@
+package resource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thieso2/cio/resolver"
+	"github.com/thieso2/cio/storage"
+)
+
+// FindExpr is a parsed 'cio find' expression: predicates joined by an
+// implicit AND, alternatives separated by -o. It works on any ResourceInfo;
+// predicates on fields a resource type does not fill (e.g. -rows on GCS
+// objects) simply do not match.
+type FindExpr struct {
+	// alternatives are OR-ed; the predicates within one are AND-ed
+	alternatives [][]findPredicate
+	now          time.Time
+}
+
+type findPredicate struct {
+	negate bool
+	match  func(info *ResourceInfo, now time.Time) bool
+}
+
+// ParseFindExpr parses find predicates:
+//
+//	-name GLOB, -iname GLOB   base name (* and ?)
+//	-path GLOB                path below the search root (** matches any depth)
+//	-size [+-]N[K|M|G|T]      larger than (+), smaller than (-) or exactly N bytes
+//	-mtime [+-]N, -mmin [+-]N modified more (+) / less (-) than N days / minutes ago
+//	-ctime [+-]N              created more (+) / less (-) than N days ago
+//	-rows [+-]N               BigQuery row count
+//	-type T                   f (file), d (directory) or a resource type (table, view, ...)
+//	-storage-class CLASS      GCS storage class
+//	-empty                    size and row count are zero
+//	! / -not, -o / -or, -a / -and
+//
+// root is the listed path; -path matches against paths relative to it.
+func ParseFindExpr(args []string, root string) (*FindExpr, error) {
+	expr := &FindExpr{now: time.Now()}
+	var current []findPredicate
+	negate := false
+
+	for i := 0; i < len(args); i++ {
+		tok := args[i]
+		switch tok {
+		case "!", "-not":
+			negate = !negate
+			continue
+		case "-a", "-and":
+			continue
+		case "-o", "-or":
+			if len(current) == 0 || negate {
+				return nil, fmt.Errorf("%s needs a predicate on both sides", tok)
+			}
+			expr.alternatives = append(expr.alternatives, current)
+			current = nil
+			continue
+		case "-empty":
+			current = append(current, findPredicate{negate: negate, match: func(info *ResourceInfo, _ time.Time) bool {
+				return !info.IsDir && info.Size == 0 && info.Rows == 0
+			}})
+			negate = false
+			continue
+		}
+
+		if !strings.HasPrefix(tok, "-") {
+			return nil, fmt.Errorf("unexpected argument %q (predicates start with -)", tok)
+		}
+		if i+1 >= len(args) {
+			return nil, fmt.Errorf("%s requires a value", tok)
+		}
+		value := args[i+1]
+		i++
+
+		match, err := findPredicateFor(tok, value, root)
+		if err != nil {
+			return nil, err
+		}
+		current = append(current, findPredicate{negate: negate, match: match})
+		negate = false
+	}
+
+	if negate {
+		return nil, fmt.Errorf("! must be followed by a predicate")
+	}
+	if len(current) == 0 && len(expr.alternatives) > 0 {
+		return nil, fmt.Errorf("-o needs a predicate on both sides")
+	}
+	expr.alternatives = append(expr.alternatives, current)
+	return expr, nil
+}
+
+func findPredicateFor(name, value, root string) (func(*ResourceInfo, time.Time) bool, error) {
+	switch name {
+	case "-name":
+		return func(info *ResourceInfo, _ time.Time) bool {
+			return resolver.MatchPattern(info.Name, value)
+		}, nil
+
+	case "-iname":
+		pattern := strings.ToLower(value)
+		return func(info *ResourceInfo, _ time.Time) bool {
+			return resolver.MatchPattern(strings.ToLower(info.Name), pattern)
+		}, nil
+
+	case "-path":
+		return func(info *ResourceInfo, _ time.Time) bool {
+			return resolver.MatchDoubleStarPattern(strings.TrimPrefix(info.Path, root), value)
+		}, nil
+
+	case "-size":
+		cmp, n, err := parseFindNumber(value, storage.ParseSize)
+		if err != nil {
+			return nil, fmt.Errorf("-size: %w", err)
+		}
+		return func(info *ResourceInfo, _ time.Time) bool {
+			return !info.IsDir && compareFind(info.Size, cmp, n)
+		}, nil
+
+	case "-rows":
+		cmp, n, err := parseFindNumber(value, parseCount)
+		if err != nil {
+			return nil, fmt.Errorf("-rows: %w", err)
+		}
+		return func(info *ResourceInfo, _ time.Time) bool {
+			return compareFind(info.Rows, cmp, n)
+		}, nil
+
+	case "-mtime", "-mmin", "-ctime":
+		cmp, n, err := parseFindNumber(value, parseCount)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		unit := 24 * time.Hour
+		if name == "-mmin" {
+			unit = time.Minute
+		}
+		created := name == "-ctime"
+		return func(info *ResourceInfo, now time.Time) bool {
+			t := info.Modified
+			if created {
+				t = info.Created
+			}
+			if t.IsZero() {
+				return false
+			}
+			return compareFind(int64(now.Sub(t)/unit), cmp, n)
+		}, nil
+
+	case "-type":
+		kind := strings.ToLower(value)
+		return func(info *ResourceInfo, _ time.Time) bool {
+			switch kind {
+			case "f":
+				return !info.IsDir
+			case "d":
+				return info.IsDir
+			}
+			return strings.EqualFold(info.Type, kind)
+		}, nil
+
+	case "-storage-class":
+		class, err := storage.ValidateStorageClass(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(info *ResourceInfo, _ time.Time) bool {
+			obj, ok := info.Details.(*storage.ObjectInfo)
+			return ok && !obj.IsPrefix && storage.NormalizeStorageClass(obj.StorageClass) == class
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown predicate %s", name)
+}
+
+// Match reports whether info satisfies the expression.
+func (e *FindExpr) Match(info *ResourceInfo) bool {
+	for _, preds := range e.alternatives {
+		matched := true
+		for _, p := range preds {
+			if p.match(info, e.now) == p.negate {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the resources that satisfy the expression.
+func (e *FindExpr) Filter(infos []*ResourceInfo) []*ResourceInfo {
+	var matched []*ResourceInfo
+	for _, info := range infos {
+		if e.Match(info) {
+			matched = append(matched, info)
+		}
+	}
+	return matched
+}
+
+// parseFindNumber parses find's [+-]N syntax: +N means greater than N, -N
+// less than N and N exactly N. parse converts the magnitude.
+func parseFindNumber(s string, parse func(string) (int64, error)) (cmp int, n int64, err error) {
+	switch {
+	case strings.HasPrefix(s, "+"):
+		cmp, s = 1, s[1:]
+	case strings.HasPrefix(s, "-"):
+		cmp, s = -1, s[1:]
+	}
+	n, err = parse(s)
+	return cmp, n, err
+}
+
+func parseCount(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+func compareFind(v int64, cmp int, n int64) bool {
+	switch cmp {
+	case 1:
+		return v > n
+	case -1:
+		return v < n
+	}
+	return v == n
+}
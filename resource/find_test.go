@@ -0,0 +1,98 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thieso2/cio/storage"
+)
+
+func TestParseFindExpr(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	root := "gs://b/data/"
+	obj := func(name string, size int64, age time.Duration, class string) *ResourceInfo {
+		return &ResourceInfo{
+			Path:     root + name,
+			Name:     name[strings.LastIndex(name, "/")+1:],
+			Type:     "file",
+			Size:     size,
+			Modified: now.Add(-age),
+			Created:  now.Add(-age),
+			Details:  &storage.ObjectInfo{Path: root + name, Size: size, StorageClass: class},
+		}
+	}
+	day := 24 * time.Hour
+	big := obj("2024/big.parquet", 200<<20, 100*day, "STANDARD")
+	small := obj("2026/small.csv", 10, time.Hour, "NEARLINE")
+	empty := obj("empty.tsv", 0, 10*day, "STANDARD")
+	dir := &ResourceInfo{Path: root + "2024/", Name: "2024", Type: "directory", IsDir: true}
+	table := &ResourceInfo{Path: "bq://p.d.t", Name: "t", Type: "table", Rows: 500, Size: 4096}
+	all := []*ResourceInfo{big, small, empty, dir, table}
+
+	tests := []struct {
+		expr    string
+		want    []*ResourceInfo
+		wantErr string
+	}{
+		{expr: "", want: all},
+		{expr: "-name *.parquet", want: []*ResourceInfo{big}},
+		{expr: "-iname *.CSV", want: []*ResourceInfo{small}},
+		{expr: "-path 2024/**", want: []*ResourceInfo{big, dir}},
+		{expr: "-size +100M", want: []*ResourceInfo{big}},
+		{expr: "-size -1K -type f", want: []*ResourceInfo{small, empty}},
+		{expr: "-size 10", want: []*ResourceInfo{small}},
+		{expr: "-mtime +90", want: []*ResourceInfo{big}},
+		{expr: "-mmin -120", want: []*ResourceInfo{small}},
+		{expr: "-ctime -30 -type f", want: []*ResourceInfo{small, empty}},
+		{expr: "-rows +100", want: []*ResourceInfo{table}},
+		{expr: "-type d", want: []*ResourceInfo{dir}},
+		{expr: "-type TABLE", want: []*ResourceInfo{table}},
+		{expr: "-storage-class nearline", want: []*ResourceInfo{small}},
+		{expr: "-empty", want: []*ResourceInfo{empty}},
+		{expr: "-name *.csv -o -name *.tsv", want: []*ResourceInfo{small, empty}},
+		{expr: "-type f ! -name *.csv", want: []*ResourceInfo{big, empty, table}},
+		{expr: "-not -type f -a -type d", want: []*ResourceInfo{dir}},
+		{expr: "-name", wantErr: "requires a value"},
+		{expr: "-o -name x", wantErr: "both sides"},
+		{expr: "-name x -o", wantErr: "both sides"},
+		{expr: "-name x !", wantErr: "followed by a predicate"},
+		{expr: "-size +lots", wantErr: "-size"},
+		{expr: "-mtime -x", wantErr: "-mtime"},
+		{expr: "-storage-class WARM", wantErr: "WARM"},
+		{expr: "-color red", wantErr: "unknown predicate"},
+		{expr: "name", wantErr: "unexpected argument"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseFindExpr(strings.Fields(tt.expr), root)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			expr.now = now
+			got := expr.Filter(all)
+			if len(got) != len(tt.want) {
+				t.Fatalf("matched %s, want %s", findPaths(got), findPaths(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("matched %s, want %s", findPaths(got), findPaths(tt.want))
+				}
+			}
+		})
+	}
+}
+
+func findPaths(infos []*ResourceInfo) string {
+	paths := make([]string, len(infos))
+	for i, info := range infos {
+		paths[i] = info.Path
+	}
+	return "[" + strings.Join(paths, " ") + "]"
+}
@@ -28,10 +28,24 @@ func (g *GCSResource) Type() Type {
 
 // List lists GCS buckets or objects at the given path
 func (g *GCSResource) List(ctx context.Context, path string, options *ListOptions) ([]*ResourceInfo, error) {
-	bucket, object, err := resolver.ParseGCSPath(path)
+	var result []*ResourceInfo
+	err := g.ListEach(ctx, path, options, func(info *ResourceInfo) error {
+		result = append(result, info)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
+
+// ListEach lists GCS buckets or objects at the given path, passing each to
+// fn as the listing pages arrive.
+func (g *GCSResource) ListEach(ctx context.Context, path string, options *ListOptions, fn func(*ResourceInfo) error) error {
+	bucket, object, err := resolver.ParseGCSPath(path)
+	if err != nil {
+		return err
+	}
 
 	// Handle bucket listing (gs:// or gs://project-id:)
 	if bucket == "" || (bucket != "" && bucket[len(bucket)-1] == ':') {
@@ -47,28 +61,29 @@ func (g *GCSResource) List(ctx context.Context, path string, options *ListOption
 
 		// Check if we have a project ID
 		if projectID == "" {
-			return nil, fmt.Errorf("project ID required for bucket listing. Use 'gs://project-id:' or set project_id in config")
+			return fmt.Errorf("project ID required for bucket listing. Use 'gs://project-id:' or set project_id in config")
 		}
 
 		// List buckets
 		buckets, err := storage.ListBuckets(ctx, projectID)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// Convert to ResourceInfo
-		result := make([]*ResourceInfo, len(buckets))
-		for i, b := range buckets {
-			result[i] = &ResourceInfo{
+		for _, b := range buckets {
+			err := fn(&ResourceInfo{
 				Path:     fmt.Sprintf("gs://%s/", b.Name),
 				Name:     b.Name,
 				Type:     "bucket",
 				Location: b.Location,
 				Details:  b,
+			})
+			if err != nil {
+				return err
 			}
 		}
-
-		return result, nil
+		return nil
 	}
 
 	// Handle object listing
@@ -78,59 +93,60 @@ func (g *GCSResource) List(ctx context.Context, path string, options *ListOption
 		HumanReadable: options.HumanReadable,
 		MaxResults:    options.MaxResults,
 		Delimiter:     "/", // Use delimiter to group by directories (non-recursive listing)
+		Versions:      options.Versions,
 	}
 
-	var objects []*storage.ObjectInfo
+	each := func(obj *storage.ObjectInfo) error {
+		return fn(objectResourceInfo(obj, options.Versions))
+	}
 	if options.Pattern != "" || resolver.HasWildcard(object) {
 		pattern := options.Pattern
 		if pattern == "" {
 			pattern = object
 		}
-		objects, err = storage.ListWithPattern(ctx, bucket, pattern, storageOpts)
-	} else {
-		objects, err = storage.ListByPath(ctx, path, storageOpts)
+		return storage.ListWithPatternEach(ctx, bucket, pattern, storageOpts, each)
 	}
+	return storage.ListEach(ctx, bucket, object, storageOpts, each)
+}
 
-	if err != nil {
-		return nil, err
+// objectResourceInfo converts a listed object or prefix to a ResourceInfo.
+func objectResourceInfo(obj *storage.ObjectInfo, versions bool) *ResourceInfo {
+	objType := "file"
+	isDir := obj.IsPrefix
+	if isDir {
+		objType = "directory"
 	}
 
-	// Convert to ResourceInfo
-	result := make([]*ResourceInfo, len(objects))
-	for i, obj := range objects {
-		objType := "file"
-		isDir := obj.IsPrefix
-		if isDir {
-			objType = "directory"
+	// Extract name from path (last component after gs://bucket/)
+	name := obj.Path
+	if strings.HasPrefix(name, "gs://") {
+		// Remove gs://bucket/ prefix
+		if idx := strings.Index(name[5:], "/"); idx != -1 {
+			name = name[5+idx+1:]
 		}
-
-		// Extract name from path (last component after gs://bucket/)
-		name := obj.Path
-		if strings.HasPrefix(name, "gs://") {
-			// Remove gs://bucket/ prefix
-			if idx := strings.Index(name[5:], "/"); idx != -1 {
-				name = name[5+idx+1:]
-			}
-			// Remove trailing slash for directories
-			name = strings.TrimSuffix(name, "/")
-			// Get just the last component
-			if idx := strings.LastIndex(name, "/"); idx != -1 {
-				name = name[idx+1:]
-			}
+		// Remove trailing slash for directories
+		name = strings.TrimSuffix(name, "/")
+		// Get just the last component
+		if idx := strings.LastIndex(name, "/"); idx != -1 {
+			name = name[idx+1:]
 		}
+	}
 
-		result[i] = &ResourceInfo{
-			Path:     obj.Path,
-			Name:     name,
-			Type:     objType,
-			Size:     obj.Size,
-			Modified: obj.Updated,
-			IsDir:    isDir,
-			Details:  obj,
-		}
+	// Versions are addressed as gs://bucket/object#generation
+	path := obj.Path
+	if versions && !isDir {
+		path = fmt.Sprintf("%s#%d", obj.Path, obj.Generation)
 	}
 
-	return result, nil
+	return &ResourceInfo{
+		Path:     path,
+		Name:     name,
+		Type:     objType,
+		Size:     obj.Size,
+		Modified: obj.Updated,
+		IsDir:    isDir,
+		Details:  obj,
+	}
 }
 
 // Remove removes GCS object(s) at the given path
@@ -154,19 +170,63 @@ func (g *GCSResource) Remove(ctx context.Context, path string, options *RemoveOp
 		parallelism = storage.DefaultConcurrentDeletes
 	}
 
+	versions := storage.LiveVersions
+	if options.AllVersions {
+		versions = storage.AllVersions
+	} else if options.NoncurrentOnly {
+		versions = storage.NoncurrentVersions
+	}
+
+	// Removing from inside the trash itself is always permanent
+	trash := options.Trash
+	if trash != nil && trash.Contains(bucket, object) {
+		trash = nil
+	}
+
 	// Check if path contains wildcards
 	if resolver.HasWildcard(object) {
-		return storage.RemoveWithPattern(ctx, client, bucket, object, options.Verbose, storageFormatter, parallelism)
+		return storage.RemoveWithPattern(ctx, client, bucket, object, options.Verbose, storageFormatter, parallelism, versions, trash)
 	}
 
 	// Check if this is a directory or single object
 	isDirectory := object == "" || object[len(object)-1] == '/'
 
 	if isDirectory {
-		return storage.RemoveDirectory(ctx, client, bucket, object, options.Verbose, storageFormatter, parallelism)
+		return storage.RemoveDirectory(ctx, client, bucket, object, options.Verbose, storageFormatter, parallelism, versions, options.Filter, trash)
+	}
+
+	return storage.RemoveObject(ctx, client, bucket, object, options.Verbose, storageFormatter, versions, trash)
+}
+
+// Info returns the full metadata of a single GCS object
+func (g *GCSResource) Info(ctx context.Context, path string) (*ResourceInfo, error) {
+	bucket, object, err := resolver.ParseGCSPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if object == "" || strings.HasSuffix(object, "/") {
+		return nil, fmt.Errorf("info requires an object path, not a bucket or prefix (use 'ls -l' or 'du')")
+	}
+
+	client, err := storage.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
 	}
 
-	return storage.RemoveObject(ctx, client, bucket, object, options.Verbose, storageFormatter)
+	details, err := storage.GetObjectDetails(ctx, client, bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResourceInfo{
+		Path:     path,
+		Name:     object,
+		Type:     "file",
+		Size:     details.Size,
+		Created:  details.Created,
+		Modified: details.Updated,
+		Details:  details,
+	}, nil
 }
 
 // FormatShort formats GCS object info in short format
@@ -194,6 +254,9 @@ func (g *GCSResource) FormatLong(info *ResourceInfo, aliasPath string) string {
 
 // FormatDetailed formats GCS object info with full details
 func (g *GCSResource) FormatDetailed(info *ResourceInfo, aliasPath string) string {
+	if details, ok := info.Details.(*storage.ObjectDetails); ok {
+		return details.FormatDetailed(aliasPath)
+	}
 	return g.FormatLong(info, aliasPath)
 }
 
@@ -3,6 +3,8 @@ package resource
 import (
 	"context"
 	"time"
+
+	"github.com/thieso2/cio/storage"
 )
 
 // Type represents the type of resource (GCS, BigQuery, or IAM)
@@ -44,6 +46,7 @@ type ListOptions struct {
 	ActiveOnly    bool   // Only show active resources (for Dataflow)
 	AllStatuses   bool   // Show all statuses (e.g., include completed executions)
 	Month         string // Month filter for billing (YYYYMM format)
+	Versions      bool   // Include noncurrent object versions (GCS)
 }
 
 // RemoveOptions contains options for removing resources
@@ -54,6 +57,19 @@ type RemoveOptions struct {
 	Parallelism int    // Number of parallel operations (for GCS only)
 	Project     string // GCP Project ID (for Cloud Run)
 	Region      string // GCP Region (for Cloud Run)
+
+	// GCS object versions: AllVersions deletes every generation of the matched
+	// objects, NoncurrentOnly deletes only noncurrent generations.
+	AllVersions    bool
+	NoncurrentOnly bool
+
+	// Filter restricts a recursive GCS delete to objects whose path below
+	// the prefix passes --include/--exclude (nil deletes everything)
+	Filter *storage.PathFilter
+
+	// Trash moves live GCS objects into the trash instead of deleting them
+	// (nil deletes permanently)
+	Trash *storage.Trash
 }
 
 // Resource is the deep core every resource type implements: listing and
@@ -82,6 +98,14 @@ type Resource interface {
 	FormatLongHeader() string
 }
 
+// StreamLister is implemented by resource types that can list in constant
+// memory (currently GCS): fn receives each resource as the listing pages
+// arrive, in listing order, instead of List collecting them all first.
+// Returning an error from fn stops the listing and is returned.
+type StreamLister interface {
+	ListEach(ctx context.Context, path string, options *ListOptions, fn func(*ResourceInfo) error) error
+}
+
 // Removable is implemented by resource types that support deletion via `cio rm`.
 type Removable interface {
 	Remove(ctx context.Context, path string, options *RemoveOptions) error
@@ -2,9 +2,13 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"cloud.google.com/go/storage"
 	"github.com/thieso2/cio/apilog"
 	"google.golang.org/api/iterator"
 )
@@ -62,3 +66,144 @@ func FormatBucketLong(bucket *BucketInfo) string {
 		bucket.StorageClass,
 		bucket.Name)
 }
+
+// CreateBucketOptions configures a new bucket. Zero values leave the GCS
+// default in place.
+type CreateBucketOptions struct {
+	// Location is a region, dual-region or multi-region (default US)
+	Location     string
+	StorageClass string
+	// UniformAccess enables uniform bucket-level access (IAM only, no ACLs)
+	UniformAccess bool
+	Versioning    bool
+	Labels        map[string]string
+	// RetentionPeriod is the minimum time objects are kept before they can be
+	// deleted or replaced
+	RetentionPeriod time.Duration
+}
+
+// CreateBucket creates a bucket in the given project.
+func CreateBucket(ctx context.Context, client *storage.Client, projectID, bucket string, opts *CreateBucketOptions) error {
+	if projectID == "" {
+		return fmt.Errorf("a project is required to create a bucket (use --project or set defaults.project_id)")
+	}
+	if opts == nil {
+		opts = &CreateBucketOptions{}
+	}
+
+	attrs := &storage.BucketAttrs{
+		Location:          opts.Location,
+		StorageClass:      opts.StorageClass,
+		VersioningEnabled: opts.Versioning,
+		Labels:            opts.Labels,
+	}
+	if opts.UniformAccess {
+		attrs.UniformBucketLevelAccess = storage.UniformBucketLevelAccess{Enabled: true}
+	}
+	if opts.RetentionPeriod > 0 {
+		attrs.RetentionPolicy = &storage.RetentionPolicy{RetentionPeriod: opts.RetentionPeriod}
+	}
+
+	apilog.Logf("[GCS] Bucket.Create(%s, project=%s, location=%q, class=%q)", bucket, projectID, opts.Location, opts.StorageClass)
+	if err := client.Bucket(bucket).Create(ctx, projectID, attrs); err != nil {
+		return fmt.Errorf("failed to create bucket gs://%s: %w", bucket, err)
+	}
+	return nil
+}
+
+// String returns a one-line summary of the options, e.g.
+// "location=EU, class=NEARLINE, versioning".
+func (o *CreateBucketOptions) String() string {
+	var parts []string
+	if o.Location != "" {
+		parts = append(parts, "location="+o.Location)
+	}
+	if o.StorageClass != "" {
+		parts = append(parts, "class="+o.StorageClass)
+	}
+	if o.UniformAccess {
+		parts = append(parts, "uniform access")
+	}
+	if o.Versioning {
+		parts = append(parts, "versioning")
+	}
+	if o.RetentionPeriod > 0 {
+		parts = append(parts, "retention="+o.RetentionPeriod.String())
+	}
+	keys := make([]string, 0, len(o.Labels))
+	for k := range o.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("label %s=%s", k, o.Labels[k]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// BucketExists reports whether the bucket exists. Errors other than "not
+// found" (e.g. missing permissions) are returned as is.
+func BucketExists(ctx context.Context, client *storage.Client, bucket string) (bool, error) {
+	apilog.Logf("[GCS] Bucket.Attrs(%s)", bucket)
+	_, err := client.Bucket(bucket).Attrs(ctx)
+	if errors.Is(err, storage.ErrBucketNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BucketIsEmpty reports whether the bucket holds no objects, counting
+// noncurrent versions (GCS refuses to delete a bucket that has any).
+func BucketIsEmpty(ctx context.Context, client *storage.Client, bucket string) (bool, error) {
+	apilog.Logf("[GCS] Objects.List(bucket=%s, versions=true, max=1)", bucket)
+	it := client.Bucket(bucket).Objects(ctx, &storage.Query{Versions: true})
+	it.PageInfo().MaxSize = 1
+	_, err := it.Next()
+	if err == iterator.Done {
+		return true, nil
+	}
+	if errors.Is(err, storage.ErrBucketNotExist) {
+		return false, fmt.Errorf("bucket gs://%s does not exist", bucket)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to list objects: %w", err)
+	}
+	return false, nil
+}
+
+// RemoveBucket deletes a bucket. A non-empty bucket is refused unless
+// recursive is set, in which case every object and noncurrent version is
+// deleted first, in parallel.
+func RemoveBucket(ctx context.Context, client *storage.Client, bucket string, recursive bool, formatter PathFormatter, maxWorkers int) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	empty, err := BucketIsEmpty(ctx, client, bucket)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		if !recursive {
+			return fmt.Errorf("bucket gs://%s is not empty (use -r to delete all objects and versions first)", bucket)
+		}
+		enumerate := func(ctx context.Context, send func(item workItem)) error {
+			return enumerateForDelete(ctx, client, bucket, "", AllVersions, nil, send)
+		}
+		if err := deleteObjectsStream(ctx, client, bucket, enumerate,
+			fmt.Sprintf("no objects found in gs://%s", bucket),
+			formatter, maxWorkers); err != nil {
+			return err
+		}
+	}
+
+	apilog.Logf("[GCS] Bucket.Delete(%s)", bucket)
+	if err := client.Bucket(bucket).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to delete bucket gs://%s: %w", bucket, err)
+	}
+	fmt.Printf("Removed bucket: gs://%s\n", bucket)
+	return nil
+}
@@ -1,37 +1,73 @@
 package storage
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"strings"
 
 	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
 	"google.golang.org/api/iterator"
 )
 
+// CatOptions controls which bytes of an object cat prints and how.
+// The zero value prints whole objects, decompressing gzip content.
+type CatOptions struct {
+	// Offset is the first byte to print
+	Offset int64
+	// Length is the number of bytes to print; 0 or negative means to the end
+	Length int64
+	// Tail, if positive, prints only the last Tail bytes (overrides Offset/Length)
+	Tail int64
+	// Raw disables transparent gunzip of compressed objects
+	Raw bool
+	// WithFilename prints a "==> path <==" header before each object
+	WithFilename bool
+	// Formatter formats object paths in headers (nil prints gs:// paths)
+	Formatter PathFormatter
+}
+
+// hasRange reports whether only part of each object should be printed.
+func (o *CatOptions) hasRange() bool {
+	return o.Offset > 0 || o.Length > 0 || o.Tail > 0
+}
+
 // CatObject streams a single GCS object to w.
-func CatObject(ctx context.Context, client *storage.Client, bucket, object string, w io.Writer) error {
-	reader, err := client.Bucket(bucket).Object(object).NewReader(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to open gs://%s/%s: %w", bucket, object, err)
+// An object of the form "name#generation" prints that object version.
+func CatObject(ctx context.Context, client *storage.Client, bucket, object string, w io.Writer, opts *CatOptions) error {
+	if opts == nil {
+		opts = &CatOptions{}
 	}
-	defer reader.Close()
 
-	if _, err := io.Copy(w, reader); err != nil {
-		return fmt.Errorf("failed to read gs://%s/%s: %w", bucket, object, err)
+	name, generation := resolver.SplitGeneration(object)
+	obj := client.Bucket(bucket).Object(name)
+	if generation != 0 {
+		obj = obj.Generation(generation)
 	}
-	return nil
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", bucket, object)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open gs://%s/%s: %w", bucket, object, err)
+	}
+	return catObject(ctx, client, attrs, w, opts, false)
 }
 
 // CatWithPattern streams all GCS objects matching a wildcard pattern to w.
 // Objects are streamed in the order they are returned by the API.
-func CatWithPattern(ctx context.Context, client *storage.Client, bucket, pattern string, w io.Writer) error {
+func CatWithPattern(ctx context.Context, client *storage.Client, bucket, pattern string, w io.Writer, opts *CatOptions) error {
+	if opts == nil {
+		opts = &CatOptions{}
+	}
 	prefix, wildcardPattern := splitPattern(pattern)
 
 	bkt := client.Bucket(bucket)
 	query := &storage.Query{Prefix: prefix}
 
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for cat", bucket, prefix)
 	it := bkt.Objects(ctx, query)
 	found := 0
 	for {
@@ -49,10 +85,10 @@ func CatWithPattern(ctx context.Context, client *storage.Client, bucket, pattern
 		if !matchesPattern(attrs.Name, wildcardPattern) {
 			continue
 		}
-		found++
-		if err := CatObject(ctx, client, bucket, attrs.Name, w); err != nil {
+		if err := catObject(ctx, client, attrs, w, opts, found > 0); err != nil {
 			return err
 		}
+		found++
 	}
 
 	if found == 0 {
@@ -60,3 +96,200 @@ func CatWithPattern(ctx context.Context, client *storage.Client, bucket, pattern
 	}
 	return nil
 }
+
+// catObject prints the object described by attrs according to opts.
+//
+// Uncompressed objects are read with a range request, so --range, --head and
+// --tail only transfer the requested bytes. Gzip content (Content-Encoding:
+// gzip or a .gz name with a gzip header) is decompressed unless opts.Raw is
+// set; ranges then apply to the decompressed bytes, which means reading from
+// the start of the object, and --tail reads the whole object.
+func catObject(ctx context.Context, client *storage.Client, attrs *storage.ObjectAttrs, w io.Writer, opts *CatOptions, separate bool) error {
+	gcsPath := fmt.Sprintf("gs://%s/%s", attrs.Bucket, attrs.Name)
+
+	if opts.WithFilename {
+		formatter := opts.Formatter
+		if formatter == nil {
+			formatter = DefaultPathFormatter
+		}
+		if separate {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "==> %s <==\n", formatter(gcsPath))
+	}
+
+	// Always fetch stored bytes; gunzip is done here so that it also
+	// covers .gz objects without a Content-Encoding header.
+	obj, err := readHandle(client.Bucket(attrs.Bucket).Object(attrs.Name), attrs)
+	if err != nil {
+		return err
+	}
+	obj = obj.ReadCompressed(true)
+
+	gunzip := !opts.Raw && (attrs.ContentEncoding == "gzip" || strings.HasSuffix(attrs.Name, ".gz"))
+
+	var reader *storage.Reader
+	switch {
+	case gunzip || !opts.hasRange():
+		apilog.Logf("[GCS] Object.NewReader(%s)", gcsPath)
+		reader, err = obj.NewReader(ctx)
+	case opts.Tail > 0:
+		apilog.Logf("[GCS] Object.NewRangeReader(%s, -%d)", gcsPath, opts.Tail)
+		reader, err = obj.NewRangeReader(ctx, -opts.Tail, -1)
+	default:
+		length := opts.Length
+		if length <= 0 {
+			length = -1
+		}
+		apilog.Logf("[GCS] Object.NewRangeReader(%s, %d, %d)", gcsPath, opts.Offset, length)
+		reader, err = obj.NewRangeReader(ctx, opts.Offset, length)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", gcsPath, err)
+	}
+	defer reader.Close()
+
+	var src io.Reader = reader
+	if gunzip {
+		gz, err := gunzipReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", gcsPath, err)
+		}
+		defer gz.Close()
+		src = gz
+
+		// The range was not applied by the server; apply it to the output.
+		if err := copyRange(w, src, opts); err != nil {
+			return fmt.Errorf("failed to read %s: %w", gcsPath, err)
+		}
+		return nil
+	}
+
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("failed to read %s: %w", gcsPath, err)
+	}
+	return nil
+}
+
+// gunzipReader decompresses r if its content really is gzip (a .gz name or
+// Content-Encoding header alone is not proof) and passes it through otherwise.
+// Closing the result closes the decompressor, not r.
+func gunzipReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return io.NopCloser(br), nil
+}
+
+// openContent opens an object's content for reading, decompressing gzip
+// content (Content-Encoding: gzip or a .gz name) unless raw is set.
+func openContent(ctx context.Context, obj *storage.ObjectHandle, attrs *storage.ObjectAttrs, raw bool) (io.ReadCloser, error) {
+	obj, err := WithReadKey(obj, attrs.CustomerKeySHA256)
+	if err != nil {
+		return nil, err
+	}
+	apilog.Logf("[GCS] Object.NewReader(gs://%s/%s)", attrs.Bucket, attrs.Name)
+	reader, err := obj.ReadCompressed(true).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if raw || (attrs.ContentEncoding != "gzip" && !strings.HasSuffix(attrs.Name, ".gz")) {
+		return reader, nil
+	}
+	src, err := gunzipReader(reader)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{src, closerFunc(func() error {
+		src.Close()
+		return reader.Close()
+	})}, nil
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// copyRange copies the part of src selected by opts to w.
+func copyRange(w io.Writer, src io.Reader, opts *CatOptions) error {
+	if opts.Tail > 0 {
+		tail := newTailBuffer(opts.Tail)
+		if _, err := io.Copy(tail, src); err != nil {
+			return err
+		}
+		_, err := w.Write(tail.bytes())
+		return err
+	}
+
+	if opts.Offset > 0 {
+		if _, err := io.CopyN(io.Discard, src, opts.Offset); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+	if opts.Length > 0 {
+		if _, err := io.CopyN(w, src, opts.Length); err != nil && err != io.EOF {
+			return err
+		}
+		return nil
+	}
+	_, err := io.Copy(w, src)
+	return err
+}
+
+// tailBuffer is a ring buffer that keeps the last max bytes written to it.
+// It grows as data arrives, so a large tail of a small stream stays small.
+type tailBuffer struct {
+	max int
+	buf []byte
+	pos int // oldest byte once len(buf) == max
+}
+
+func newTailBuffer(max int64) *tailBuffer {
+	return &tailBuffer{max: int(max)}
+}
+
+// Write implements io.Writer.
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if n >= t.max {
+		t.buf = append(t.buf[:0], p[n-t.max:]...)
+		t.pos = 0
+		return n, nil
+	}
+	if room := t.max - len(t.buf); room > 0 {
+		c := min(room, len(p))
+		if len(t.buf)+c > cap(t.buf) {
+			grown := make([]byte, len(t.buf), min(max(2*cap(t.buf), len(t.buf)+c), t.max))
+			copy(grown, t.buf)
+			t.buf = grown
+		}
+		t.buf = append(t.buf, p[:c]...)
+		p = p[c:]
+	}
+	// Full: overwrite the oldest bytes
+	for len(p) > 0 {
+		c := copy(t.buf[t.pos:], p)
+		t.pos = (t.pos + c) % t.max
+		p = p[c:]
+	}
+	return n, nil
+}
+
+// bytes returns the buffered tail in order.
+func (t *tailBuffer) bytes() []byte {
+	if t.pos == 0 {
+		return t.buf
+	}
+	out := make([]byte, 0, len(t.buf))
+	out = append(out, t.buf[t.pos:]...)
+	return append(out, t.buf[:t.pos]...)
+}
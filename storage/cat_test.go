@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTailBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    int64
+		writes []string
+		want   string
+	}{
+		{"empty", 4, nil, ""},
+		{"shorter than tail", 10, []string{"abc", "de"}, "abcde"},
+		{"exactly the tail", 5, []string{"abc", "de"}, "abcde"},
+		{"wraps", 4, []string{"abc", "def"}, "cdef"},
+		{"wraps several times", 3, []string{"ab", "cd", "ef", "g"}, "efg"},
+		{"single write longer than tail", 3, []string{"abcdefg"}, "efg"},
+		{"long write after wrap", 3, []string{"ab", "cd", "wxyz"}, "xyz"},
+		{"byte by byte", 2, strings.Split("abcde", ""), "de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tail := newTailBuffer(tt.max)
+			for _, w := range tt.writes {
+				if n, err := tail.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+			if got := string(tail.bytes()); got != tt.want {
+				t.Errorf("bytes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailBufferGrowsWithInput(t *testing.T) {
+	tail := newTailBuffer(1 << 30)
+	tail.Write([]byte("small"))
+	if c := cap(tail.buf); c > 1<<10 {
+		t.Errorf("buffer capacity %d for 5 bytes of a 1 GiB tail", c)
+	}
+	if got := string(tail.bytes()); got != "small" {
+		t.Errorf("bytes() = %q, want %q", got, "small")
+	}
+}
@@ -17,7 +17,12 @@ var provider gclient.Provider[*storage.Client]
 func GetClient(ctx context.Context) (*storage.Client, error) {
 	return provider.Get(ctx, func(ctx context.Context) (*storage.Client, error) {
 		apilog.Logf("[GCS] NewClient()")
-		return storage.NewClient(ctx)
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		applyRetryPolicy(client)
+		return client, nil
 	})
 }
 
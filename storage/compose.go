@@ -0,0 +1,374 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"google.golang.org/api/iterator"
+)
+
+// maxComposeSources is the maximum number of source objects GCS accepts in a
+// single compose request.
+const maxComposeSources = 32
+
+// PartsPrefix is the hidden prefix, at the root of the destination bucket,
+// under which parallel composite upload parts are staged. Listings and syncs
+// of a prefix never see the parts; a sync of the bucket root skips them
+// (see isStagedPart), while ls -r and du of the root show them, as they are
+// billed like any other object.
+const PartsPrefix = ".cio-parts/"
+
+// isStagedPart reports whether object name, found listing prefix, is a
+// composite upload part staged under PartsPrefix. Only a listing of the
+// bucket root sees them; listing PartsPrefix itself asks for them.
+func isStagedPart(prefix, name string) bool {
+	return prefix == "" && strings.HasPrefix(name, PartsPrefix)
+}
+
+// UploadOptions contains configuration for upload operations
+type UploadOptions struct {
+	// ParallelThreshold is the minimum file size (in bytes) to use parallel composite upload
+	ParallelThreshold int64
+	// ChunkSize is the size of each part for parallel composite uploads
+	ChunkSize int64
+	// MaxChunks is the maximum number of parts uploaded in parallel per file
+	MaxChunks int
+	// NoVerify skips CRC32C/MD5 verification of uploaded content
+	NoVerify bool
+	// Preconditions, if set, are applied to the destination object write
+	// (e.g. GenerationMatch or DoesNotExist for optimistic concurrency)
+	Preconditions *storage.Conditions
+	// Metadata, if set, is applied to every uploaded object (Content-Type,
+	// Cache-Control, custom key/value pairs, ...)
+	Metadata *MetadataUpdate
+	// RateLimit, if set, caps the combined throughput of all uploads and
+	// parts sharing it
+	RateLimit *RateLimiter
+	// Filter, if set, selects the files UploadDirectory uploads by their
+	// path below the local directory
+	Filter *PathFilter
+	// Transfers, if set, records UploadDirectory runs so they can be
+	// resumed (see ResumeTransfer)
+	Transfers *TransferStore
+
+	transfer *transferRecorder // log of the transfer being run, if any
+}
+
+// limit returns r throttled by the options' rate limiter, if any.
+func (o *UploadOptions) limit(ctx context.Context, r io.Reader) io.Reader {
+	if o == nil {
+		return r
+	}
+	return o.RateLimit.Reader(ctx, r)
+}
+
+// destination returns the handle to write an uploaded object to, with any
+// preconditions from opts applied; without them the upload is retried as
+// an unconditional write (see retryUpload).
+func (o *UploadOptions) destination(obj *storage.ObjectHandle) *storage.ObjectHandle {
+	obj = withWriteKey(obj)
+	if o != nil && o.Preconditions != nil {
+		return obj.If(*o.Preconditions)
+	}
+	return retryUpload(obj)
+}
+
+// useComposite reports whether a file of the given size should be uploaded
+// as parallel parts.
+func (o *UploadOptions) useComposite(size int64) bool {
+	return o != nil && o.ChunkSize > 0 && size >= o.ParallelThreshold && size > o.ChunkSize
+}
+
+// uploadPart is one byte range of the local file staged as its own object.
+type uploadPart struct {
+	index  int
+	offset int64
+	length int64
+	name   string
+}
+
+// composePartSize returns the part size for a composite upload of size
+// bytes: chunkSize, grown if needed to stay within maxComposeSources parts.
+func composePartSize(size, chunkSize int64) int64 {
+	if (size+chunkSize-1)/chunkSize > maxComposeSources {
+		return (size + maxComposeSources - 1) / maxComposeSources
+	}
+	return chunkSize
+}
+
+// stagedPartsPrefix returns the prefix the parts of an upload of the local
+// file absPath to objectPath are staged under. Its key changes with the
+// file's path, size, mtime and the part size, so a resumed upload only ever
+// reuses parts cut from the same file the same way.
+func stagedPartsPrefix(objectPath, absPath string, size int64, mtime time.Time, partSize int64) string {
+	key := crc32.Checksum([]byte(fmt.Sprintf("%s|%d|%d|%d", absPath, size, mtime.UnixNano(), partSize)), crc32cTable)
+	return fmt.Sprintf("%s%s/%08x/", PartsPrefix, objectPath, key)
+}
+
+// planParts cuts size bytes into parts of partSize (the last one shorter)
+// named below partPrefix.
+func planParts(size, partSize int64, partPrefix string) []uploadPart {
+	parts := make([]uploadPart, (size+partSize-1)/partSize)
+	for i := range parts {
+		offset := int64(i) * partSize
+		parts[i] = uploadPart{
+			index:  i,
+			offset: offset,
+			length: min(partSize, size-offset),
+			name:   fmt.Sprintf("%spart-%05d", partPrefix, i),
+		}
+	}
+	return parts
+}
+
+// uploadFileComposite uploads a large file as parallel part objects and
+// composes them server-side into objectPath, then deletes the parts.
+//
+// Parts are staged under ".cio-parts/<objectPath>/<key>/", where key is
+// derived from the local file's path, size and mtime. If an upload is
+// interrupted, the next run for the same unchanged file finds the parts
+// already staged, checks each one's size and CRC32C against the local bytes,
+// and uploads only what is missing or different. A changed file gets a
+// different key, so stale parts are never composed into the result. When the
+// upload fails with an error (rather than being interrupted), the staged
+// parts are deleted.
+//
+// Returns the number of parts the object was composed from.
+func uploadFileComposite(ctx context.Context, client *storage.Client, bucket, objectPath, localPath string, metadata map[string]string, verbose bool, opts *UploadOptions) (n int, err error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	size := info.Size()
+
+	partSize := composePartSize(size, opts.ChunkSize)
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		absPath = localPath
+	}
+	partPrefix := stagedPartsPrefix(objectPath, absPath, size, info.ModTime(), partSize)
+	parts := planParts(size, partSize, partPrefix)
+	numParts := len(parts)
+
+	bkt := client.Bucket(bucket)
+
+	// An error leaves no parts behind; an interrupted run (cancelled
+	// context) keeps them so the next run can resume.
+	defer func() {
+		if err != nil && ctx.Err() == nil {
+			deleteStagedParts(ctx, bkt, bucket, parts, opts.MaxChunks)
+		}
+	}()
+
+	// Parts left behind by an interrupted run of the same file.
+	staged, err := listStagedParts(ctx, bkt, bucket, partPrefix)
+	if err != nil {
+		return 0, err
+	}
+
+	workers := opts.MaxChunks
+	if workers < 1 {
+		workers = 1
+	}
+	if verbose {
+		fmt.Printf("Using %d parallel workers for %d parts (%d bytes each)\n", workers, numParts, partSize)
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	resumed := 0
+	sums := make([]uint32, numParts) // CRC32C of each part's local bytes
+
+	// Parts are encrypted like the destination: compose requires every
+	// source to use the destination's key.
+	partKey, kmsKey := writeKeyFor(bucket, objectPath), kmsKeyFor(bucket, objectPath)
+
+	apilog.Logf("[GCS] Object.NewWriter(gs://%s/%s, parts=%d)", bucket, partPrefix, numParts)
+	for _, part := range parts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(p uploadPart) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fail := func(err error) {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("part %d: %w", p.index, err)
+				}
+				mu.Unlock()
+			}
+
+			// Checksum the local range; it lets the server verify the upload
+			// and tells us whether an already-staged part can be reused.
+			h := crc32.New(crc32cTable)
+			if _, err := io.Copy(h, io.NewSectionReader(file, p.offset, p.length)); err != nil {
+				fail(err)
+				return
+			}
+			sum := h.Sum32()
+			sums[p.index] = sum
+
+			if attrs, ok := staged[p.name]; ok && attrs.Size == p.length && attrs.CRC32C == sum {
+				mu.Lock()
+				resumed++
+				mu.Unlock()
+				return
+			}
+
+			partObj := retryUpload(bkt.Object(p.name))
+			if partKey != nil {
+				partObj = partObj.Key(partKey)
+			}
+			w := partObj.NewWriter(ctx)
+			w.KMSKeyName = kmsKey
+			w.CRC32C = sum
+			w.SendCRC32C = true
+			if _, err := io.Copy(w, opts.limit(ctx, io.NewSectionReader(file, p.offset, p.length))); err != nil {
+				w.Close()
+				fail(err)
+				return
+			}
+			if err := w.Close(); err != nil {
+				fail(err)
+			}
+		}(part)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return 0, fmt.Errorf("failed to upload parts: %w", firstErr)
+	}
+	if resumed > 0 {
+		fmt.Printf("Resumed upload: %d/%d parts already staged\n", resumed, numParts)
+	}
+
+	// Compose the parts into the destination object
+	sources := make([]*storage.ObjectHandle, numParts)
+	for i, p := range parts {
+		sources[i] = bkt.Object(p.name)
+	}
+	// Not opts.destination: an unconditional compose is not retried
+	dst := withWriteKey(bkt.Object(objectPath))
+	if opts.Preconditions != nil {
+		dst = dst.If(*opts.Preconditions)
+	}
+	composer := dst.ComposerFrom(sources...)
+	composer.KMSKeyName = kmsKey
+	// A single-stream upload sniffs the content type; do the same so the
+	// type does not depend on the file size. --header Content-Type wins.
+	if composer.ContentType, err = sniffContentType(file); err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(metadata) > 0 {
+		composer.Metadata = metadata
+	}
+	if opts.Metadata != nil {
+		opts.Metadata.applyTo(&composer.ObjectAttrs)
+	}
+	apilog.Logf("[GCS] Object.Compose(gs://%s/%s, sources=%d)", bucket, objectPath, numParts)
+	attrs, err := composer.Run(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compose parts: %w", err)
+	}
+	if attrs.Size != size {
+		return 0, fmt.Errorf("composed object size %d does not match local file size %d", attrs.Size, size)
+	}
+
+	// Each part was checked by the server on upload; verify the composed
+	// result against the whole file as well, combining the parts' checksums
+	// rather than reading the file again. Composite objects have no MD5.
+	if !opts.NoVerify {
+		crc := sums[0]
+		for i := 1; i < numParts; i++ {
+			crc = crc32cCombine(crc, sums[i], parts[i].length)
+		}
+		if err := verifyUploadedCRC32C(attrs, crc); err != nil {
+			return 0, err
+		}
+	}
+
+	deleteStagedParts(ctx, bkt, bucket, parts, workers)
+	return numParts, nil
+}
+
+// deleteStagedParts deletes the staged part objects of an upload in
+// parallel. Failure leaves garbage but does not affect the upload, so it
+// only warns; parts that were never staged are ignored.
+func deleteStagedParts(ctx context.Context, bkt *storage.BucketHandle, bucket string, parts []uploadPart, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for _, p := range parts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := bkt.Object(name).Delete(ctx)
+			if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+				fmt.Fprintf(os.Stderr, "Warning: failed to delete upload part gs://%s/%s: %v\n", bucket, name, err)
+			}
+		}(p.name)
+	}
+	wg.Wait()
+}
+
+// sniffContentType detects the content type of a file from its first 512
+// bytes, as the single-stream Writer does.
+func sniffContentType(f *os.File) (string, error) {
+	buf := make([]byte, 512)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// listStagedParts returns the part objects already present under partPrefix,
+// keyed by object name.
+func listStagedParts(ctx context.Context, bkt *storage.BucketHandle, bucket, partPrefix string) (map[string]*storage.ObjectAttrs, error) {
+	staged := make(map[string]*storage.ObjectAttrs)
+
+	query := &storage.Query{Prefix: partPrefix}
+	if err := query.SetAttrSelection([]string{"Name", "Size", "CRC32C"}); err != nil {
+		return nil, fmt.Errorf("SetAttrSelection: %w", err)
+	}
+
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for upload resume", bucket, partPrefix)
+	it := bkt.Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list staged parts: %w", err)
+		}
+		if strings.HasPrefix(attrs.Name, partPrefix) {
+			staged[attrs.Name] = attrs
+		}
+	}
+	return staged, nil
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestComposePartSize(t *testing.T) {
+	const mb = 1 << 20
+	tests := []struct {
+		size, chunkSize int64
+		want            int64
+	}{
+		{100 * mb, 32 * mb, 32 * mb},
+		{32 * 32 * mb, 32 * mb, 32 * mb},
+		{32*32*mb + 1, 32 * mb, 32*mb + 1},
+		{10 << 30, 32 * mb, (10<<30 + 31) / 32},
+	}
+	for _, tt := range tests {
+		got := composePartSize(tt.size, tt.chunkSize)
+		if got != tt.want {
+			t.Errorf("composePartSize(%d, %d) = %d, want %d", tt.size, tt.chunkSize, got, tt.want)
+		}
+		if n := (tt.size + got - 1) / got; n > maxComposeSources {
+			t.Errorf("composePartSize(%d, %d): %d parts, more than %d", tt.size, tt.chunkSize, n, maxComposeSources)
+		}
+	}
+}
+
+func TestPlanParts(t *testing.T) {
+	tests := []struct {
+		name           string
+		size, partSize int64
+		lengths        []int64
+	}{
+		{"exact multiple", 30, 10, []int64{10, 10, 10}},
+		{"short last part", 25, 10, []int64{10, 10, 5}},
+		{"single part", 7, 10, []int64{7}},
+		{"one byte over", 11, 10, []int64{10, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := planParts(tt.size, tt.partSize, "p/")
+			if len(parts) != len(tt.lengths) {
+				t.Fatalf("planParts(%d, %d) = %d parts, want %d", tt.size, tt.partSize, len(parts), len(tt.lengths))
+			}
+			var offset int64
+			for i, p := range parts {
+				if p.index != i || p.offset != offset || p.length != tt.lengths[i] {
+					t.Errorf("part %d = {index %d, offset %d, length %d}, want {%d, %d, %d}", i, p.index, p.offset, p.length, i, offset, tt.lengths[i])
+				}
+				offset += p.length
+			}
+			if offset != tt.size {
+				t.Errorf("parts cover %d bytes, want %d", offset, tt.size)
+			}
+			if parts[0].name != "p/part-00000" {
+				t.Errorf("part name = %q, want p/part-00000", parts[0].name)
+			}
+		})
+	}
+}
+
+func TestStagedPartsPrefix(t *testing.T) {
+	mtime := time.Unix(1700000000, 0)
+	base := stagedPartsPrefix("dir/big.bin", "/data/big.bin", 100, mtime, 10)
+	if !strings.HasPrefix(base, PartsPrefix+"dir/big.bin/") || !strings.HasSuffix(base, "/") {
+		t.Errorf("stagedPartsPrefix = %q, want %sdir/big.bin/<key>/", base, PartsPrefix)
+	}
+	if again := stagedPartsPrefix("dir/big.bin", "/data/big.bin", 100, mtime, 10); again != base {
+		t.Errorf("same file: prefix %q, want %q", again, base)
+	}
+
+	changed := map[string]string{
+		"path":      stagedPartsPrefix("dir/big.bin", "/other/big.bin", 100, mtime, 10),
+		"size":      stagedPartsPrefix("dir/big.bin", "/data/big.bin", 101, mtime, 10),
+		"mtime":     stagedPartsPrefix("dir/big.bin", "/data/big.bin", 100, mtime.Add(time.Nanosecond), 10),
+		"part size": stagedPartsPrefix("dir/big.bin", "/data/big.bin", 100, mtime, 20),
+	}
+	for what, prefix := range changed {
+		if prefix == base {
+			t.Errorf("changed %s: prefix %q, want a new key", what, prefix)
+		}
+	}
+}
+
+func TestIsStagedPart(t *testing.T) {
+	tests := []struct {
+		prefix, name string
+		want         bool
+	}{
+		{"", ".cio-parts/big.bin/0badc0de/part-00000", true},
+		{"", "data/big.bin", false},
+		{"data/", "data/.cio-parts/x", false},
+		{".cio-parts/", ".cio-parts/big.bin/0badc0de/part-00000", false},
+	}
+	for _, tt := range tests {
+		if got := isStagedPart(tt.prefix, tt.name); got != tt.want {
+			t.Errorf("isStagedPart(%q, %q) = %t, want %t", tt.prefix, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	// DefaultConcurrentCopies is the default number of concurrent server-side copy operations
+	DefaultConcurrentCopies = 50
+)
+
+// CopyOptions contains configuration for GCS-to-GCS copy operations
+type CopyOptions struct {
+	// PreserveStructure preserves directory structure when copying with wildcards
+	PreserveStructure bool
+	// Preconditions, if set, guard the destination of a single-object copy
+	// (e.g. GenerationMatch) so a concurrent write is not silently overwritten
+	Preconditions *storage.Conditions
+}
+
+// fileCopy represents an object to be copied server-side
+type fileCopy struct {
+	srcObject  string
+	dstObject  string
+	srcGCSPath string
+	dstGCSPath string
+}
+
+// CopyObject copies a single object between (or within) buckets using a
+// server-side rewrite, so no data passes through the local machine.
+// If dstObject is empty or ends with "/", the source object's base name is appended.
+// A source of the form "name#generation" copies that object version.
+func CopyObject(ctx context.Context, client *storage.Client, srcBucket, srcObject, dstBucket, dstObject string, verbose bool, formatter PathFormatter, opts *CopyOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	srcName, srcGeneration := resolver.SplitGeneration(srcObject)
+	dstObject = copyDestination(srcName, dstObject)
+
+	srcGCSPath := fmt.Sprintf("gs://%s/%s", srcBucket, srcObject)
+	dstGCSPath := fmt.Sprintf("gs://%s/%s", dstBucket, dstObject)
+
+	if verbose {
+		fmt.Printf("Copying %s to %s\n", formatter(srcGCSPath), formatter(dstGCSPath))
+	}
+
+	startTime := time.Now()
+
+	src := client.Bucket(srcBucket).Object(srcName)
+	if srcGeneration != 0 {
+		src = src.Generation(srcGeneration)
+	}
+	dst := client.Bucket(dstBucket).Object(dstObject)
+	if opts != nil && opts.Preconditions != nil {
+		dst = dst.If(*opts.Preconditions)
+	}
+
+	written, err := rewriteObject(ctx, src, dst)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", formatter(srcGCSPath), err)
+	}
+
+	if verbose {
+		elapsed := time.Since(startTime)
+		fmt.Printf("Copied: %s → %s (%s in %.2fs)\n",
+			formatter(srcGCSPath), formatter(dstGCSPath), FormatSize(written), elapsed.Seconds())
+	} else {
+		fmt.Printf("Copied: %s → %s (%s)\n", formatter(srcGCSPath), formatter(dstGCSPath), FormatSize(written))
+	}
+	return nil
+}
+
+// CopyDirectory copies all objects under srcPrefix to dstPrefix, keeping the
+// layout relative to srcPrefix (the same semantics as DownloadDirectory).
+func CopyDirectory(ctx context.Context, client *storage.Client, srcBucket, srcPrefix, dstBucket, dstPrefix string, verbose bool, formatter PathFormatter, maxWorkers int, opts *CopyOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	// First pass: collect all objects to copy
+	filesToCopy, err := collectPrefixCopies(ctx, client, srcBucket, srcPrefix, dstBucket, dstPrefix)
+	if err != nil {
+		return err
+	}
+
+	totalCount := len(filesToCopy)
+	if totalCount == 0 {
+		return fmt.Errorf("no objects found with prefix gs://%s/%s", srcBucket, srcPrefix)
+	}
+
+	// Second pass: copy in parallel with progress counter
+	return copyObjectsParallel(ctx, client, srcBucket, dstBucket, filesToCopy, totalCount, verbose, formatter, maxWorkers)
+}
+
+// CopyWithPattern copies all objects matching a wildcard pattern to dstPrefix.
+// Uses ListWithPattern for level-by-level expansion (same as ls and cp downloads).
+// Without PreserveStructure the matches are flattened to their base names.
+func CopyWithPattern(ctx context.Context, client *storage.Client, srcBucket, pattern, dstBucket, dstPrefix string, verbose bool, formatter PathFormatter, maxWorkers int, opts *CopyOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	recursive := opts != nil && opts.PreserveStructure
+	filesToCopy, err := collectPatternCopies(ctx, srcBucket, pattern, dstBucket, dstPrefix, recursive)
+	if err != nil {
+		return err
+	}
+
+	totalCount := len(filesToCopy)
+	if totalCount == 0 {
+		return fmt.Errorf("no objects found matching pattern: %s", pattern)
+	}
+
+	return copyObjectsParallel(ctx, client, srcBucket, dstBucket, filesToCopy, totalCount, verbose, formatter, maxWorkers)
+}
+
+// copyDestination returns the object a single-object copy or move of
+// srcObject to dstObject writes: dstObject itself, or the source's base name
+// below it if dstObject is empty or ends with "/".
+func copyDestination(srcObject, dstObject string) string {
+	if dstObject == "" || strings.HasSuffix(dstObject, "/") {
+		return dstObject + path.Base(srcObject)
+	}
+	return dstObject
+}
+
+// dirPrefix returns prefix ending with "/", or "" for the bucket root.
+func dirPrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		return prefix + "/"
+	}
+	return prefix
+}
+
+// prefixCopy maps the object name, listed under srcPrefix, to the same
+// relative path under dstPrefix (see dirPrefix). It reports false for
+// directory markers and for the prefix object itself.
+func prefixCopy(srcBucket, srcPrefix, dstBucket, dstPrefix, name string) (fileCopy, bool) {
+	relPath := strings.TrimPrefix(name, srcPrefix)
+	if strings.HasSuffix(name, "/") || relPath == "" {
+		return fileCopy{}, false
+	}
+	dstObject := dstPrefix + relPath
+	return fileCopy{
+		srcObject:  name,
+		dstObject:  dstObject,
+		srcGCSPath: fmt.Sprintf("gs://%s/%s", srcBucket, name),
+		dstGCSPath: fmt.Sprintf("gs://%s/%s", dstBucket, dstObject),
+	}, true
+}
+
+// patternCopy maps a wildcard match under dstPrefix (see dirPrefix), keeping
+// its full object path when recursive is set or flattening it to its base
+// name otherwise. It reports false for prefixes and directory markers.
+func patternCopy(srcBucket, dstBucket, dstPrefix string, obj *ObjectInfo, recursive bool) (fileCopy, bool) {
+	if obj.IsPrefix || strings.HasSuffix(obj.Path, "/") {
+		return fileCopy{}, false
+	}
+	srcObject := strings.TrimPrefix(obj.Path, "gs://"+srcBucket+"/")
+	dstObject := dstPrefix + path.Base(srcObject)
+	if recursive {
+		dstObject = dstPrefix + srcObject
+	}
+	return fileCopy{
+		srcObject:  srcObject,
+		dstObject:  dstObject,
+		srcGCSPath: obj.Path,
+		dstGCSPath: fmt.Sprintf("gs://%s/%s", dstBucket, dstObject),
+	}, true
+}
+
+// collectPrefixCopies lists every object under srcPrefix and maps it to the
+// same relative path under dstPrefix. Directory markers are skipped.
+func collectPrefixCopies(ctx context.Context, client *storage.Client, srcBucket, srcPrefix, dstBucket, dstPrefix string) ([]fileCopy, error) {
+	dstPrefix = dirPrefix(dstPrefix)
+
+	bkt := client.Bucket(srcBucket)
+	query := &storage.Query{Prefix: srcPrefix}
+
+	var filesToCopy []fileCopy
+
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for copy", srcBucket, srcPrefix)
+	it := bkt.Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		if fc, ok := prefixCopy(srcBucket, srcPrefix, dstBucket, dstPrefix, attrs.Name); ok {
+			filesToCopy = append(filesToCopy, fc)
+		}
+	}
+	return filesToCopy, nil
+}
+
+// collectPatternCopies expands a wildcard pattern via ListWithPattern and maps
+// each matching object under dstPrefix — keeping its full object path when
+// recursive is set, or flattening it to its base name otherwise.
+func collectPatternCopies(ctx context.Context, srcBucket, pattern, dstBucket, dstPrefix string, recursive bool) ([]fileCopy, error) {
+	dstPrefix = dirPrefix(dstPrefix)
+
+	matched, err := ListWithPattern(ctx, srcBucket, pattern, &ListOptions{Recursive: recursive})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	var filesToCopy []fileCopy
+	for _, obj := range matched {
+		if fc, ok := patternCopy(srcBucket, dstBucket, dstPrefix, obj, recursive); ok {
+			filesToCopy = append(filesToCopy, fc)
+		}
+	}
+	return filesToCopy, nil
+}
+
+// copyObjectsParallel rewrites objects server-side with controlled concurrency,
+// using the same semaphore + progress-reporter model as downloadFilesParallel.
+// maxWorkers < 1 means DefaultConcurrentCopies.
+func copyObjectsParallel(ctx context.Context, client *storage.Client, srcBucket, dstBucket string, filesToCopy []fileCopy, totalCount int, verbose bool, formatter PathFormatter, maxWorkers int) error {
+	startTime := time.Now()
+	var totalBytes int64
+
+	// Create a semaphore to limit concurrent copies
+	if maxWorkers < 1 {
+		maxWorkers = DefaultConcurrentCopies
+	}
+	sem := make(chan struct{}, maxWorkers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	var completedCount int32
+
+	// Channel for completed copies (for progress tracking)
+	type copied struct {
+		srcGCSPath   string
+		dstGCSPath   string
+		bytesWritten int64
+		err          error
+	}
+	copies := make(chan copied, totalCount)
+
+	// Start progress reporter goroutine
+	done := make(chan struct{})
+	go func() {
+		for c := range copies {
+			count := atomic.AddInt32(&completedCount, 1)
+
+			if c.err != nil {
+				fmt.Printf("Failed %d/%d: %s - %v\n", count, totalCount, formatter(c.srcGCSPath), c.err)
+
+				// Store first error
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = c.err
+				}
+				mu.Unlock()
+			} else {
+				atomic.AddInt64(&totalBytes, c.bytesWritten)
+				fmt.Printf("Copied %d/%d: %s → %s (%s)\n", count, totalCount, formatter(c.srcGCSPath), formatter(c.dstGCSPath), FormatSize(c.bytesWritten))
+			}
+		}
+		close(done)
+	}()
+
+	for _, fc := range filesToCopy {
+		wg.Add(1)
+
+		// Acquire semaphore
+		sem <- struct{}{}
+
+		go func(fileCopy fileCopy) {
+			defer wg.Done()
+			defer func() { <-sem }() // Release semaphore
+
+			written, err := rewriteObject(ctx, client.Bucket(srcBucket).Object(fileCopy.srcObject), client.Bucket(dstBucket).Object(fileCopy.dstObject))
+			copies <- copied{srcGCSPath: fileCopy.srcGCSPath, dstGCSPath: fileCopy.dstGCSPath, bytesWritten: written, err: err}
+		}(fc)
+	}
+
+	// Wait for all copies to complete
+	wg.Wait()
+	close(copies)
+
+	// Wait for progress reporter to finish
+	<-done
+
+	if firstErr != nil {
+		return fmt.Errorf("copy failed: %w", firstErr)
+	}
+
+	elapsed := time.Since(startTime)
+	if totalCount > 1 {
+		if verbose {
+			bytes := atomic.LoadInt64(&totalBytes)
+			fmt.Printf("\nTotal files copied: %d (%s in %.2fs)\n", totalCount, FormatSize(bytes), elapsed.Seconds())
+		} else {
+			fmt.Printf("\nTotal files copied: %d\n", totalCount)
+		}
+	}
+	return nil
+}
+
+// rewriteObject performs a server-side copy of one object and returns the
+// number of bytes written. The Copier transparently continues multi-call
+// rewrites for large objects or cross-location/storage-class copies.
+func rewriteObject(ctx context.Context, src, dst *storage.ObjectHandle) (int64, error) {
+	src, err := withObjectKey(ctx, src)
+	if err != nil {
+		return 0, err
+	}
+	apilog.Logf("[GCS] Object.Rewrite(gs://%s/%s → gs://%s/%s)", src.BucketName(), src.ObjectName(), dst.BucketName(), dst.ObjectName())
+	attrs, err := encryptedCopier(dst, src).Run(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return attrs.Size, nil
+}
@@ -0,0 +1,100 @@
+package storage
+
+import "testing"
+
+func TestCopyDestination(t *testing.T) {
+	tests := []struct {
+		name      string
+		srcObject string
+		dstObject string
+		want      string
+	}{
+		{"bucket root", "dir/file.txt", "", "file.txt"},
+		{"trailing slash", "dir/file.txt", "backup/", "backup/file.txt"},
+		{"explicit name", "dir/file.txt", "backup/renamed.txt", "backup/renamed.txt"},
+		{"no trailing slash is a name", "dir/file.txt", "backup", "backup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := copyDestination(tt.srcObject, tt.dstObject); got != tt.want {
+				t.Errorf("copyDestination(%q, %q) = %q, want %q", tt.srcObject, tt.dstObject, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixCopy(t *testing.T) {
+	tests := []struct {
+		name      string
+		srcPrefix string
+		dstPrefix string
+		object    string
+		wantOK    bool
+		wantDst   string
+	}{
+		{"prefix to prefix", "logs/", "archive/", "logs/2024/a.log", true, "archive/2024/a.log"},
+		{"destination without slash", "logs/", "archive", "logs/a.log", true, "archive/a.log"},
+		{"bucket root destination", "logs/", "", "logs/a.log", true, "a.log"},
+		{"bucket root source", "", "copy/", "a.log", true, "copy/a.log"},
+		{"directory marker", "logs/", "archive/", "logs/2024/", false, ""},
+		{"prefix object itself", "logs/a.log", "archive/", "logs/a.log", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, ok := prefixCopy("src", tt.srcPrefix, "dst", dirPrefix(tt.dstPrefix), tt.object)
+			if ok != tt.wantOK {
+				t.Fatalf("prefixCopy(%q) ok = %v, want %v", tt.object, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			want := fileCopy{
+				srcObject:  tt.object,
+				dstObject:  tt.wantDst,
+				srcGCSPath: "gs://src/" + tt.object,
+				dstGCSPath: "gs://dst/" + tt.wantDst,
+			}
+			if fc != want {
+				t.Errorf("prefixCopy(%q) = %+v, want %+v", tt.object, fc, want)
+			}
+		})
+	}
+}
+
+func TestPatternCopy(t *testing.T) {
+	tests := []struct {
+		name      string
+		dstPrefix string
+		obj       *ObjectInfo
+		recursive bool
+		wantOK    bool
+		wantDst   string
+	}{
+		{"flattened to base name", "out/", &ObjectInfo{Path: "gs://src/logs/2024/a.log"}, false, true, "out/a.log"},
+		{"structure preserved", "out/", &ObjectInfo{Path: "gs://src/logs/2024/a.log"}, true, true, "out/logs/2024/a.log"},
+		{"destination without slash", "out", &ObjectInfo{Path: "gs://src/a.log"}, false, true, "out/a.log"},
+		{"bucket root destination", "", &ObjectInfo{Path: "gs://src/logs/a.log"}, false, true, "a.log"},
+		{"prefix entry", "out/", &ObjectInfo{Path: "gs://src/logs/", IsPrefix: true}, true, false, ""},
+		{"directory marker", "out/", &ObjectInfo{Path: "gs://src/logs/"}, true, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, ok := patternCopy("src", "dst", dirPrefix(tt.dstPrefix), tt.obj, tt.recursive)
+			if ok != tt.wantOK {
+				t.Fatalf("patternCopy(%q) ok = %v, want %v", tt.obj.Path, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if fc.dstObject != tt.wantDst {
+				t.Errorf("patternCopy(%q).dstObject = %q, want %q", tt.obj.Path, fc.dstObject, tt.wantDst)
+			}
+			if want := "gs://dst/" + tt.wantDst; fc.dstGCSPath != want {
+				t.Errorf("patternCopy(%q).dstGCSPath = %q, want %q", tt.obj.Path, fc.dstGCSPath, want)
+			}
+			if fc.srcGCSPath != tt.obj.Path {
+				t.Errorf("patternCopy(%q).srcGCSPath = %q, want %q", tt.obj.Path, fc.srcGCSPath, tt.obj.Path)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 
 	"cloud.google.com/go/storage"
 	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
 	"google.golang.org/api/iterator"
 )
 
@@ -35,6 +36,27 @@ type DownloadOptions struct {
 	PreserveStructure bool
 	// Force skips the size-based existence check and always re-downloads
 	Force bool
+	// NoVerify skips CRC32C/MD5 verification of downloaded content
+	NoVerify bool
+	// RateLimit, if set, caps the combined throughput of all downloads
+	// and chunks sharing it
+	RateLimit *RateLimiter
+	// Filter, if set, selects the objects DownloadDirectory fetches by
+	// their path below the prefix
+	Filter *PathFilter
+	// Transfers, if set, records DownloadDirectory runs so they can be
+	// resumed (see ResumeTransfer)
+	Transfers *TransferStore
+
+	transfer *transferRecorder // log of the transfer being run, if any
+}
+
+// limit returns r throttled by the options' rate limiter, if any.
+func (o *DownloadOptions) limit(ctx context.Context, r io.Reader) io.Reader {
+	if o == nil {
+		return r
+	}
+	return o.RateLimit.Reader(ctx, r)
 }
 
 // fileDownload represents a file to be downloaded
@@ -42,7 +64,13 @@ type fileDownload struct {
 	objectName    string
 	localFilePath string
 	fullGCSPath   string
-	size          int64 // GCS object size, used for skip-if-exists check
+	size          int64     // GCS object size, used for skip-if-exists check
+	mtime         time.Time // if set, applied to the local file after download
+	generation    int64     // if set, download exactly this generation
+	// keySHA256 identifies the object's CSEK from the listing ("" for none);
+	// without keyListed the key is looked up before the download
+	keySHA256 string
+	keyListed bool
 }
 
 // chunkDownload represents a chunk of a file to be downloaded
@@ -53,18 +81,20 @@ type chunkDownload struct {
 }
 
 // DownloadFile downloads a single file from GCS to local filesystem
-// Uses parallel chunked download for large files if opts is provided
+// Uses parallel chunked download for large files if opts is provided.
+// An object of the form "name#generation" downloads that object version.
 func DownloadFile(ctx context.Context, client *storage.Client, bucket, object, localPath string, verbose bool, formatter PathFormatter, opts *DownloadOptions) error {
 	if formatter == nil {
 		formatter = DefaultPathFormatter
 	}
 
 	fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, object)
+	name, generation := resolver.SplitGeneration(object)
 
 	// If localPath is a directory, append the object's filename
 	fileInfo, err := os.Stat(localPath)
 	if err == nil && fileInfo.IsDir() {
-		filename := filepath.Base(object)
+		filename := filepath.Base(name)
 		localPath = filepath.Join(localPath, filename)
 	}
 
@@ -75,9 +105,12 @@ func DownloadFile(ctx context.Context, client *storage.Client, bucket, object, l
 	}
 
 	// Get object attributes to check size
-	obj := client.Bucket(bucket).Object(object)
+	obj := client.Bucket(bucket).Object(name)
+	if generation != 0 {
+		obj = obj.Generation(generation)
+	}
 	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", bucket, object)
-	attrs, err := obj.Attrs(ctx)
+	attrs, err := objectAttrs(ctx, obj)
 	if err != nil {
 		return fmt.Errorf("failed to get object attributes: %w", err)
 	}
@@ -89,7 +122,7 @@ func DownloadFile(ctx context.Context, client *storage.Client, bucket, object, l
 		if verbose {
 			fmt.Printf("Downloading %s to %s (parallel mode, %d bytes)\n", formatter(fullGCSPath), localPath, attrs.Size)
 		}
-		return downloadFileParallel(ctx, client, bucket, object, localPath, attrs.Size, verbose, formatter, opts)
+		return downloadFileParallel(ctx, client, bucket, name, localPath, attrs, verbose, formatter, opts)
 	}
 
 	// Simple single-threaded download for small files
@@ -107,54 +140,88 @@ func DownloadFile(ctx context.Context, client *storage.Client, bucket, object, l
 	}
 	defer file.Close()
 
-	// Get GCS object reader
+	// Get GCS object reader, pinned to the generation whose checksums we hold
+	readObj, err := readHandle(obj, attrs)
+	if err != nil {
+		return err
+	}
 	apilog.Logf("[GCS] Object.NewReader(gs://%s/%s)", bucket, object)
-	reader, err := obj.NewReader(ctx)
+	reader, err := readObj.NewReader(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to read from GCS: %w", err)
 	}
 	defer reader.Close()
 
-	// Copy contents to local file
-	written, err := io.Copy(file, reader)
+	// Copy contents to local file, hashing as we go
+	h := newTransferHash()
+	written, err := io.Copy(io.MultiWriter(file, h), opts.limit(ctx, reader))
 	if err != nil {
 		return fmt.Errorf("failed to download file: %w", err)
 	}
 
+	// Objects served with decompressive transcoding don't match their stored
+	// checksums, which describe the compressed bytes.
+	verified := false
+	if opts == nil || !opts.NoVerify {
+		if reader.Attrs.Decompressed {
+			if verbose {
+				fmt.Printf("Skipping verification of %s (decompressed during download)\n", formatter(fullGCSPath))
+			}
+		} else if err := h.verify(attrs.CRC32C, attrs.MD5); err != nil {
+			file.Close()
+			os.Remove(localPath)
+			return fmt.Errorf("download of %s failed verification, partial file removed: %w", formatter(fullGCSPath), err)
+		} else {
+			verified = true
+		}
+	}
+
 	// Calculate elapsed time and transfer rate
 	elapsed := time.Since(startTime)
 	if verbose {
 		rate := float64(written) / elapsed.Seconds()
-		fmt.Printf("Downloaded: %s → %s (%d bytes in %.2fs, %.2f MB/s)\n",
-			formatter(fullGCSPath), localPath, written, elapsed.Seconds(), rate/1024/1024)
+		fmt.Printf("Downloaded: %s → %s (%d bytes in %.2fs, %.2f MB/s%s)\n",
+			formatter(fullGCSPath), localPath, written, elapsed.Seconds(), rate/1024/1024, verifiedSuffix(verified))
 	} else {
-		fmt.Printf("Downloaded: %s → %s (%d bytes)\n", formatter(fullGCSPath), localPath, written)
+		fmt.Printf("Downloaded: %s → %s (%d bytes%s)\n", formatter(fullGCSPath), localPath, written, verifiedSuffix(verified))
 	}
 	return nil
 }
 
-// downloadFileParallel downloads a file using parallel chunked download
-func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, object, localPath string, fileSize int64, verbose bool, formatter PathFormatter, opts *DownloadOptions) error {
+// downloadFileParallel downloads a file using parallel chunked download.
+// Chunk completion is recorded in a sidecar journal (see downloadJournal), so
+// re-running an interrupted download fetches only the missing chunks. All
+// chunks are read from the given generation; if the object has changed since
+// the journal was written, the partial file is discarded and the download
+// starts over rather than splicing two versions together.
+//
+// Chunks arrive out of order, so unless opts.NoVerify is set the reassembled
+// file is hashed once complete and checked against the object's CRC32C/MD5.
+func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, object, localPath string, attrs *storage.ObjectAttrs, verbose bool, formatter PathFormatter, opts *DownloadOptions) error {
 	fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, object)
-
-	// Calculate optimal number of chunks
-	numChunks := int(fileSize / opts.ChunkSize)
-	if fileSize%opts.ChunkSize != 0 {
+	fileSize, generation := attrs.Size, attrs.Generation
+
+	// Split into ChunkSize pieces, downloaded by up to MaxChunks workers.
+	// Fixed-size chunks keep memory per worker bounded and make the journal's
+	// resume granularity independent of the file size.
+	actualChunkSize := opts.ChunkSize
+	numChunks := int(fileSize / actualChunkSize)
+	if fileSize%actualChunkSize != 0 {
 		numChunks++
 	}
-	// Limit to MaxChunks
-	if numChunks > opts.MaxChunks {
-		numChunks = opts.MaxChunks
-	}
 	if numChunks < 1 {
 		numChunks = 1
 	}
-
-	// Calculate actual chunk size
-	actualChunkSize := fileSize / int64(numChunks)
+	workers := opts.MaxChunks
+	if workers > numChunks {
+		workers = numChunks
+	}
+	if workers < 1 {
+		workers = 1
+	}
 
 	if verbose && numChunks > 1 {
-		fmt.Printf("Using %d parallel chunks (%d bytes each)\n", numChunks, actualChunkSize)
+		fmt.Printf("Using %d parallel workers for %d chunks (%d bytes each)\n", workers, numChunks, actualChunkSize)
 	}
 
 	// Track start time
@@ -166,7 +233,7 @@ func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, o
 		offset := int64(i) * actualChunkSize
 		length := actualChunkSize
 		// Last chunk gets any remaining bytes
-		if i == numChunks-1 {
+		if offset+length > fileSize {
 			length = fileSize - offset
 		}
 		chunks[i] = chunkDownload{
@@ -176,24 +243,70 @@ func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, o
 		}
 	}
 
-	// Create local file
-	file, err := os.Create(localPath)
+	// Resume from a matching journal, or start a fresh download.
+	journal, err := loadDownloadJournal(localPath)
 	if err != nil {
-		return fmt.Errorf("failed to create local file: %w", err)
+		return err
+	}
+	resuming := false
+	if journal != nil {
+		if info, statErr := os.Stat(localPath); statErr == nil && info.Size() == fileSize &&
+			journal.matches(fullGCSPath, generation, fileSize, actualChunkSize, numChunks) {
+			resuming = true
+		} else if journal.Generation != generation {
+			fmt.Printf("Object %s changed since the interrupted download (generation %d → %d), restarting\n",
+				formatter(fullGCSPath), journal.Generation, generation)
+		}
+	}
+
+	var file *os.File
+	if resuming {
+		file, err = os.OpenFile(localPath, os.O_RDWR, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open partial file: %w", err)
+		}
+	} else {
+		file, err = os.Create(localPath)
+		if err != nil {
+			return fmt.Errorf("failed to create local file: %w", err)
+		}
 	}
 	defer file.Close()
 
-	// Pre-allocate file size
-	if err := file.Truncate(fileSize); err != nil {
-		return fmt.Errorf("failed to allocate file: %w", err)
+	if !resuming {
+		// Pre-allocate file size
+		if err := file.Truncate(fileSize); err != nil {
+			return fmt.Errorf("failed to allocate file: %w", err)
+		}
+		journal = newDownloadJournal(localPath, fullGCSPath, generation, fileSize, actualChunkSize, numChunks)
+		if err := journal.save(); err != nil {
+			return err
+		}
 	}
 
 	// Download chunks in parallel
+	sem := make(chan struct{}, workers)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var firstErr error
 	var completedBytes int64
-	obj := client.Bucket(bucket).Object(object)
+	obj, err := readHandle(client.Bucket(bucket).Object(object), attrs)
+	if err != nil {
+		return err
+	}
+
+	// Account for chunks finished by a previous run
+	pending := 0
+	for _, c := range chunks {
+		if journal.done(c.index) {
+			completedBytes += c.length
+		} else {
+			pending++
+		}
+	}
+	if resuming {
+		fmt.Printf("Resuming %s: %d/%d chunks already downloaded\n", formatter(fullGCSPath), numChunks-pending, numChunks)
+	}
 
 	// Progress ticker for verbose mode
 	var ticker *time.Ticker
@@ -221,11 +334,16 @@ func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, o
 	}
 
 	// Download each chunk
-	apilog.Logf("[GCS] Object.NewRangeReader(gs://%s/%s, chunks=%d)", bucket, object, numChunks)
+	apilog.Logf("[GCS] Object.NewRangeReader(gs://%s/%s#%d, chunks=%d)", bucket, object, generation, pending)
 	for _, chunk := range chunks {
+		if journal.done(chunk.index) {
+			continue
+		}
 		wg.Add(1)
+		sem <- struct{}{}
 		go func(c chunkDownload) {
 			defer wg.Done()
+			defer func() { <-sem }()
 
 			// Create range reader for this chunk
 			reader, err := obj.NewRangeReader(ctx, c.offset, c.length)
@@ -239,10 +357,11 @@ func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, o
 			}
 			defer reader.Close()
 
-			// Read chunk data
+			// Read chunk data. A short read is an error: journaling a partial
+			// chunk as done would leave a hole in the resumed file.
 			buf := make([]byte, c.length)
-			n, err := io.ReadFull(reader, buf)
-			if err != nil && err != io.ErrUnexpectedEOF {
+			n, err := io.ReadFull(opts.limit(ctx, reader), buf)
+			if err != nil {
 				mu.Lock()
 				if firstErr == nil {
 					firstErr = fmt.Errorf("failed to read chunk %d: %w", c.index, err)
@@ -252,9 +371,7 @@ func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, o
 			}
 
 			// Write to file at correct offset
-			mu.Lock()
 			_, err = file.WriteAt(buf[:n], c.offset)
-			mu.Unlock()
 			if err != nil {
 				mu.Lock()
 				if firstErr == nil {
@@ -264,13 +381,27 @@ func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, o
 				return
 			}
 
+			// The journal records the chunk as done once it is synced
+			if err := journal.markWritten(file, c.index); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to record chunk %d: %w", c.index, err)
+				}
+				mu.Unlock()
+				return
+			}
+
 			// Update progress
 			atomic.AddInt64(&completedBytes, int64(n))
 		}(chunk)
 	}
 
-	// Wait for all chunks to complete
+	// Wait for all chunks to complete, then persist the last batch (also
+	// after an error, so a re-run resumes from every chunk written)
 	wg.Wait()
+	if err := journal.flush(file); err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("failed to record downloaded chunks: %w", err)
+	}
 
 	// Stop progress ticker
 	if verbose && ticker != nil {
@@ -279,25 +410,42 @@ func downloadFileParallel(ctx context.Context, client *storage.Client, bucket, o
 	}
 
 	if firstErr != nil {
-		return firstErr
+		return fmt.Errorf("%w (partial download kept, re-run to resume)", firstErr)
+	}
+
+	verified := false
+	if !opts.NoVerify {
+		h, err := hashLocalFile(localPath)
+		if err != nil {
+			return fmt.Errorf("failed to verify download: %w", err)
+		}
+		if err := h.verify(attrs.CRC32C, attrs.MD5); err != nil {
+			file.Close()
+			os.Remove(localPath)
+			journal.remove()
+			return fmt.Errorf("download of %s failed verification, partial file removed: %w", formatter(fullGCSPath), err)
+		}
+		verified = true
 	}
 
+	journal.remove()
+
 	// Calculate elapsed time and transfer rate
 	elapsed := time.Since(startTime)
 	if verbose {
 		rate := float64(fileSize) / elapsed.Seconds()
 		if numChunks > 1 {
-			fmt.Printf("Downloaded: %s → %s (%d bytes, %d chunks in %.2fs, %.2f MB/s)\n",
-				formatter(fullGCSPath), localPath, fileSize, numChunks, elapsed.Seconds(), rate/1024/1024)
+			fmt.Printf("Downloaded: %s → %s (%d bytes, %d chunks in %.2fs, %.2f MB/s%s)\n",
+				formatter(fullGCSPath), localPath, fileSize, numChunks, elapsed.Seconds(), rate/1024/1024, verifiedSuffix(verified))
 		} else {
-			fmt.Printf("Downloaded: %s → %s (%d bytes in %.2fs, %.2f MB/s)\n",
-				formatter(fullGCSPath), localPath, fileSize, elapsed.Seconds(), rate/1024/1024)
+			fmt.Printf("Downloaded: %s → %s (%d bytes in %.2fs, %.2f MB/s%s)\n",
+				formatter(fullGCSPath), localPath, fileSize, elapsed.Seconds(), rate/1024/1024, verifiedSuffix(verified))
 		}
 	} else {
 		if numChunks > 1 {
-			fmt.Printf("Downloaded: %s → %s (%d bytes, %d chunks)\n", formatter(fullGCSPath), localPath, fileSize, numChunks)
+			fmt.Printf("Downloaded: %s → %s (%d bytes, %d chunks%s)\n", formatter(fullGCSPath), localPath, fileSize, numChunks, verifiedSuffix(verified))
 		} else {
-			fmt.Printf("Downloaded: %s → %s (%d bytes)\n", formatter(fullGCSPath), localPath, fileSize)
+			fmt.Printf("Downloaded: %s → %s (%d bytes%s)\n", formatter(fullGCSPath), localPath, fileSize, verifiedSuffix(verified))
 		}
 	}
 	return nil
@@ -319,8 +467,10 @@ func DownloadDirectory(ctx context.Context, client *storage.Client, bucket, pref
 		Prefix: prefix,
 	}
 
-	// First pass: collect all objects to download
+	// First pass: collect all objects to download. The generations listed
+	// are recorded for a resume, which fetches exactly those.
 	var filesToDownload []fileDownload
+	var generations []int64
 
 	it := bkt.Objects(ctx, query)
 	for {
@@ -342,6 +492,9 @@ func DownloadDirectory(ctx context.Context, client *storage.Client, bucket, pref
 		if relPath == "" {
 			continue
 		}
+		if opts != nil && !opts.Filter.Match(relPath) {
+			continue
+		}
 		localFilePath := filepath.Join(localPath, filepath.FromSlash(relPath))
 		fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, attrs.Name)
 
@@ -350,14 +503,30 @@ func DownloadDirectory(ctx context.Context, client *storage.Client, bucket, pref
 			localFilePath: localFilePath,
 			fullGCSPath:   fullGCSPath,
 			size:          attrs.Size,
+			keySHA256:     attrs.CustomerKeySHA256,
+			keyListed:     true,
 		})
+		generations = append(generations, attrs.Generation)
 	}
 
 	totalCount := len(filesToDownload)
 	if totalCount == 0 {
+		if opts != nil && opts.Filter != nil {
+			return fmt.Errorf("no objects under gs://%s/%s match the include/exclude filters", bucket, prefix)
+		}
 		return fmt.Errorf("no objects found with prefix gs://%s/%s", bucket, prefix)
 	}
 
+	if opts != nil && opts.Transfers != nil {
+		items := make([]TransferItem, len(filesToDownload))
+		for i, fd := range filesToDownload {
+			items[i] = TransferItem{Object: fd.objectName, Local: fd.localFilePath, Size: fd.size, Generation: generations[i]}
+		}
+		o := *opts
+		o.transfer = startTransfer(opts.Transfers, TransferDownload, fmt.Sprintf("gs://%s/%s", bucket, prefix), localPath, bucket, items)
+		opts = &o
+	}
+
 	// Second pass: download in parallel with progress counter
 	return downloadFilesParallel(ctx, client, bucket, filesToDownload, totalCount, verbose, formatter, maxWorkers, opts)
 }
@@ -410,6 +579,8 @@ func DownloadWithPattern(ctx context.Context, client *storage.Client, bucket, pa
 			localFilePath: localFilePath,
 			fullGCSPath:   obj.Path,
 			size:          obj.Size,
+			keySHA256:     obj.CustomerKeySHA256,
+			keyListed:     true,
 		})
 	}
 
@@ -429,6 +600,8 @@ func downloadFilesParallel(ctx context.Context, client *storage.Client, bucket s
 	// Track start time for overall transfer rate
 	startTime := time.Now()
 	var totalBytes int64
+	var verifiedBytes int64
+	var verifiedCount int32
 
 	// Create a semaphore to limit concurrent downloads
 	sem := make(chan struct{}, maxWorkers)
@@ -442,21 +615,39 @@ func downloadFilesParallel(ctx context.Context, client *storage.Client, bucket s
 		fullGCSPath   string
 		localFilePath string
 		bytesWritten  int64
+		verified      bool
 		err           error
 		warning       string // non-fatal, e.g. GCS path conflict with local filesystem
+		upToDate      bool   // skipped because the local file is already complete
 	}
 	downloads := make(chan download, totalCount)
 
+	var totalSize int64
+	for _, fd := range filesToDownload {
+		totalSize += fd.size
+	}
+	progress := newProgressTracker("download", int64(totalCount), totalSize)
+
 	// Start progress reporter goroutine
 	done := make(chan struct{})
 	go func() {
 		for d := range downloads {
 			count := atomic.AddInt32(&completedCount, 1)
+			if opts != nil {
+				switch {
+				case d.warning != "" && !d.upToDate:
+					opts.transfer.record(d.localFilePath, errors.New(d.warning))
+				default:
+					opts.transfer.record(d.localFilePath, d.err)
+				}
+			}
 
 			if d.warning != "" {
-				fmt.Printf("Skipped %d/%d: %s (%s)\n", count, totalCount, formatter(d.fullGCSPath), d.warning)
+				progress.objectSkipped()
+				progress.Printf("Skipped %d/%d: %s (%s)\n", count, totalCount, formatter(d.fullGCSPath), d.warning)
 			} else if d.err != nil {
-				fmt.Printf("Failed %d/%d: %s - %v\n", count, totalCount, formatter(d.fullGCSPath), d.err)
+				progress.objectDone(d.err)
+				progress.Printf("Failed %d/%d: %s - %v\n", count, totalCount, formatter(d.fullGCSPath), d.err)
 
 				// Store first error
 				mu.Lock()
@@ -466,12 +657,17 @@ func downloadFilesParallel(ctx context.Context, client *storage.Client, bucket s
 				mu.Unlock()
 			} else {
 				// Track total bytes downloaded
+				progress.objectDone(nil)
 				atomic.AddInt64(&totalBytes, d.bytesWritten)
+				if d.verified {
+					verifiedBytes += d.bytesWritten
+					verifiedCount++
+				}
 
 				if verbose {
-					fmt.Printf("Downloaded %d/%d: %s to %s (%d bytes)\n", count, totalCount, formatter(d.fullGCSPath), d.localFilePath, d.bytesWritten)
+					progress.Printf("Downloaded %d/%d: %s to %s (%d bytes)\n", count, totalCount, formatter(d.fullGCSPath), d.localFilePath, d.bytesWritten)
 				} else {
-					fmt.Printf("Downloaded %d/%d: %s → %s (%d bytes)\n", count, totalCount, formatter(d.fullGCSPath), d.localFilePath, d.bytesWritten)
+					progress.Printf("Downloaded %d/%d: %s → %s (%d bytes)\n", count, totalCount, formatter(d.fullGCSPath), d.localFilePath, d.bytesWritten)
 				}
 			}
 		}
@@ -492,11 +688,16 @@ func downloadFilesParallel(ctx context.Context, client *storage.Client, bucket s
 
 			// Skip if local file already exists with the correct size (unless Force).
 			if opts == nil || !opts.Force {
-				if info, err := os.Stat(fileDownload.localFilePath); err == nil && info.Size() == fileDownload.size {
+				// A pre-allocated partial download has the right size too; its
+				// journal marks it as unfinished.
+				if info, err := os.Stat(fileDownload.localFilePath); err == nil && info.Size() == fileDownload.size && !hasDownloadJournal(fileDownload.localFilePath) {
+					// Count the bytes as done so the byte total adds up
+					progress.addBytes(fileDownload.size)
 					downloads <- download{
 						fullGCSPath:   fileDownload.fullGCSPath,
 						localFilePath: fileDownload.localFilePath,
 						warning:       "skipped (already exists with correct size)",
+						upToDate:      true,
 					}
 					return
 				}
@@ -553,22 +754,57 @@ func downloadFilesParallel(ctx context.Context, client *storage.Client, bucket s
 
 			// Get GCS object reader
 			obj := bkt.Object(fileDownload.objectName)
-			reader, err := obj.NewReader(ctx)
+			if fileDownload.generation != 0 {
+				obj = obj.Generation(fileDownload.generation)
+			}
+			if fileDownload.keyListed {
+				obj, err = WithReadKey(obj, fileDownload.keySHA256)
+			} else {
+				obj, err = withObjectKey(ctx, obj)
+			}
+			var reader *storage.Reader
+			if err == nil {
+				reader, err = obj.NewReader(ctx)
+			}
+			if errors.Is(err, storage.ErrObjectNotExist) && fileDownload.generation != 0 {
+				err = fmt.Errorf("generation %d no longer exists (object replaced or deleted since it was listed)", fileDownload.generation)
+			}
 			if err != nil {
 				downloads <- download{fullGCSPath: fileDownload.fullGCSPath, localFilePath: fileDownload.localFilePath, err: err}
 				return
 			}
 			defer reader.Close()
 
-			// Copy contents
-			written, err := io.Copy(file, reader)
+			// Copy contents, hashing as we go
+			h := newTransferHash()
+			written, err := io.Copy(io.MultiWriter(file, h), progress.reader(opts.limit(ctx, reader)))
 			if err != nil {
 				downloads <- download{fullGCSPath: fileDownload.fullGCSPath, localFilePath: fileDownload.localFilePath, err: err}
 				return
 			}
 
+			// The reader reports the CRC32C of the generation it served; MD5
+			// would need an extra metadata request per object.
+			verified := false
+			if (opts == nil || !opts.NoVerify) && !reader.Attrs.Decompressed {
+				if err := h.verify(reader.Attrs.CRC32C, nil); err != nil {
+					file.Close()
+					os.Remove(fileDownload.localFilePath)
+					downloads <- download{fullGCSPath: fileDownload.fullGCSPath, localFilePath: fileDownload.localFilePath, err: fmt.Errorf("%w (partial file removed)", err)}
+					return
+				}
+				verified = true
+			}
+
+			if !fileDownload.mtime.IsZero() {
+				if err := os.Chtimes(fileDownload.localFilePath, fileDownload.mtime, fileDownload.mtime); err != nil {
+					downloads <- download{fullGCSPath: fileDownload.fullGCSPath, localFilePath: fileDownload.localFilePath, err: err}
+					return
+				}
+			}
+
 			// Send result to progress reporter
-			downloads <- download{fullGCSPath: fileDownload.fullGCSPath, localFilePath: fileDownload.localFilePath, bytesWritten: written, err: nil}
+			downloads <- download{fullGCSPath: fileDownload.fullGCSPath, localFilePath: fileDownload.localFilePath, bytesWritten: written, verified: verified, err: nil}
 		}(fd)
 	}
 
@@ -578,6 +814,10 @@ func downloadFilesParallel(ctx context.Context, client *storage.Client, bucket s
 
 	// Wait for progress reporter to finish
 	<-done
+	progress.finish()
+	if opts != nil {
+		finishTransfer(opts.transfer, firstErr != nil)
+	}
 
 	if firstErr != nil {
 		return fmt.Errorf("download failed: %w", firstErr)
@@ -594,6 +834,7 @@ func downloadFilesParallel(ctx context.Context, client *storage.Client, bucket s
 		} else {
 			fmt.Printf("\nTotal files downloaded: %d\n", totalCount)
 		}
+		printVerifiedSummary(verifiedBytes, verifiedCount)
 	}
 	return nil
 }
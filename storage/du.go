@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/thieso2/cio/apilog"
@@ -16,24 +17,149 @@ import (
 type DUOptions struct {
 	// Workers is the number of parallel goroutines for subdirectory summation (default 8).
 	Workers int
+	// MaxDepth is how many directory levels below the root get their own
+	// entry (default 1: immediate subdirectories only). Deeper levels cost no
+	// extra API calls; they are tallied from the same recursive listings.
+	MaxDepth int
+	// ByClass breaks each entry's bytes down by storage class (also needed
+	// for cost estimates).
+	ByClass bool
+	// ByAge breaks each entry's bytes down by object age (see DUAgeBuckets).
+	ByAge bool
 }
 
+// DUAgeBuckets are the age ranges of a ByAge breakdown, youngest first. Age
+// is counted from object creation, as lifecycle rules do.
+var DUAgeBuckets = []string{"<30d", "30-90d", "90d-1y", ">1y"}
+
+// duClassOrder lists storage classes from hot to cold for breakdown columns.
+var duClassOrder = []string{"STANDARD", "NEARLINE", "COLDLINE", "ARCHIVE"}
+
 // DefaultDUOptions returns sensible defaults.
 func DefaultDUOptions() *DUOptions {
 	return &DUOptions{Workers: 8}
 }
 
-// DUEntry holds the size and file count of a single immediate subdirectory.
+// DUEntry holds the size and file count of a single subdirectory.
 type DUEntry struct {
 	Path  string
 	Size  int64
 	Count int64
+	// ByClass holds bytes per storage class (DUOptions.ByClass)
+	ByClass map[string]int64
+	// ByAge holds bytes per DUAgeBuckets range (DUOptions.ByAge)
+	ByAge map[string]int64
+}
+
+// add counts one object in the entry.
+func (e *DUEntry) add(size int64, class string, created time.Time, opts *DUOptions, now time.Time) {
+	e.Size += size
+	e.Count++
+	if opts.ByClass {
+		if e.ByClass == nil {
+			e.ByClass = make(map[string]int64)
+		}
+		e.ByClass[NormalizeStorageClass(class)] += size
+	}
+	if opts.ByAge {
+		if e.ByAge == nil {
+			e.ByAge = make(map[string]int64)
+		}
+		e.ByAge[ageBucket(now.Sub(created))] += size
+	}
+}
+
+// Merge adds the totals and breakdowns of o to e.
+func (e *DUEntry) Merge(o *DUEntry) {
+	e.Size += o.Size
+	e.Count += o.Count
+	for class, size := range o.ByClass {
+		if e.ByClass == nil {
+			e.ByClass = make(map[string]int64)
+		}
+		e.ByClass[class] += size
+	}
+	for bucket, size := range o.ByAge {
+		if e.ByAge == nil {
+			e.ByAge = make(map[string]int64)
+		}
+		e.ByAge[bucket] += size
+	}
+}
+
+// MonthlyCost estimates the entry's monthly storage cost from its ByClass
+// breakdown and StorageClassPrices. Returns false if a class has no price.
+func (e *DUEntry) MonthlyCost() (float64, bool) {
+	var total float64
+	for class, size := range e.ByClass {
+		cost, ok := MonthlyStorageCost(class, size)
+		if !ok {
+			return total, false
+		}
+		total += cost
+	}
+	return total, true
+}
+
+// DUClasses returns the storage classes present in entries, hot to cold,
+// for use as breakdown columns.
+func DUClasses(entries ...DUEntry) []string {
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		for class := range e.ByClass {
+			seen[class] = true
+		}
+	}
+	var classes []string
+	for _, class := range duClassOrder {
+		if seen[class] {
+			classes = append(classes, class)
+			delete(seen, class)
+		}
+	}
+	var other []string
+	for class := range seen {
+		other = append(other, class)
+	}
+	sort.Strings(other)
+	return append(classes, other...)
+}
+
+func ageBucket(age time.Duration) string {
+	days := age.Hours() / 24
+	switch {
+	case days < 30:
+		return DUAgeBuckets[0]
+	case days < 90:
+		return DUAgeBuckets[1]
+	case days < 365:
+		return DUAgeBuckets[2]
+	}
+	return DUAgeBuckets[3]
+}
+
+// sortDUEntries orders entries by path, with nested directories listed
+// before the directory that contains them (like Unix du).
+func sortDUEntries(entries []DUEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].Path, entries[j].Path
+		if strings.HasPrefix(b, a) {
+			return false
+		}
+		if strings.HasPrefix(a, b) {
+			return true
+		}
+		return a < b
+	})
 }
 
 // DUResult holds the output of a disk usage calculation.
 type DUResult struct {
-	// Entries contains one entry per immediate subdirectory, sorted by path.
+	// Entries contains one entry per subdirectory up to DUOptions.MaxDepth,
+	// sorted by path with nested directories before their parent.
 	Entries []DUEntry
+	// Root is the grand total as an entry, including breakdowns.
+	Root DUEntry
 	// RootPath is the queried root gs:// path.
 	RootPath string
 	// Total is the grand total across all entries plus any root-level files.
@@ -46,11 +172,15 @@ type DUResult struct {
 // immediate subdirectory (the natural unit for du-style output).
 //
 // Algorithm:
-//  1. Shallow-list the prefix to discover immediate children.
+//  1. Shallow-list the prefix to discover immediate children, streaming the
+//     pages so a prefix with millions of direct children stays in constant
+//     memory.
 //  2. Root-level files are counted directly from the listing (their sizes are
 //     already in the ObjectInfo struct, so no extra API calls are needed).
-//  3. Each subdirectory is summed by a goroutine that does a recursive listing
-//     with SetAttrSelection(["Name","Size"]) to minimise payload and cost.
+//  3. Each subdirectory is summed by a goroutine, started as soon as it is
+//     listed, that does a recursive listing with SetAttrSelection(["Name","Size"])
+//     to minimise payload and cost; nested directories down to opts.MaxDepth
+//     are tallied from the same listing.
 //  4. Results are collected, sorted by path, and returned.
 func DiskUsage(ctx context.Context, bucket, prefix string, opts *DUOptions) (*DUResult, error) {
 	if opts == nil {
@@ -59,6 +189,9 @@ func DiskUsage(ctx context.Context, bucket, prefix string, opts *DUOptions) (*DU
 	if opts.Workers <= 0 {
 		opts.Workers = 8
 	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 1
+	}
 
 	client, err := GetClient(ctx)
 	if err != nil {
@@ -66,102 +199,88 @@ func DiskUsage(ctx context.Context, bucket, prefix string, opts *DUOptions) (*DU
 	}
 
 	rootPath := fmt.Sprintf("gs://%s/%s", bucket, prefix)
+	now := time.Now()
+
+	// Steps 1-3: stream the shallow listing. Root-level files are tallied
+	// as they arrive; each subdirectory goes to a worker right away, bounded
+	// by a semaphore.
+	root := DUEntry{Path: rootPath}
+	var (
+		mu        sync.Mutex // guards root, duEntries and sumErr
+		duEntries []DUEntry
+		sumErr    error
+		wg        sync.WaitGroup
+		children  int
+	)
+	sem := make(chan struct{}, opts.Workers)
 
-	// Step 1: shallow list to discover immediate children.
-	entries, err := List(ctx, bucket, prefix, &ListOptions{
+	listErr := ListEach(ctx, bucket, prefix, &ListOptions{
 		Recursive: false,
 		Delimiter: "/",
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	// If there are no results at all and a prefix was specified, the path may
-	// be a single object rather than a "directory". Handle it gracefully.
-	if len(entries) == 0 && prefix != "" {
-		obj := client.Bucket(bucket).Object(prefix)
-		apilog.Logf("[GCS] Object.Attrs(gs://%s/%s) [du single-file probe]", bucket, prefix)
-		attrs, attrErr := obj.Attrs(ctx)
-		if attrErr == nil {
-			return &DUResult{
-				RootPath: rootPath,
-				Total:    attrs.Size,
-				Count:    1,
-			}, nil
-		}
-		// Object not found either – return a zero result.
-		return &DUResult{RootPath: rootPath}, nil
-	}
-
-	// Step 2: separate subdirectories from root-level files.
-	var subdirPrefixes []string
-	var rootFileTotal int64
-	var rootFileCount int64
-	for _, e := range entries {
-		if e.IsPrefix {
-			// Strip gs://bucket/ to get the raw GCS prefix string.
-			subPrefix := strings.TrimPrefix(e.Path, "gs://"+bucket+"/")
-			subdirPrefixes = append(subdirPrefixes, subPrefix)
-		} else {
-			rootFileTotal += e.Size
-			rootFileCount++
+	}, func(e *ObjectInfo) error {
+		children++
+		if !e.IsPrefix {
+			mu.Lock()
+			root.add(e.Size, e.StorageClass, e.Created, opts, now)
+			mu.Unlock()
+			return nil
 		}
-	}
-
-	// Step 3: fan-out – one goroutine per subdirectory, bounded by a semaphore.
-	type subdirResult struct {
-		path  string
-		size  int64
-		count int64
-		err   error
-	}
 
-	resultCh := make(chan subdirResult, len(subdirPrefixes))
-	sem := make(chan struct{}, opts.Workers)
-	var wg sync.WaitGroup
-
-	for _, subPrefix := range subdirPrefixes {
+		// Strip gs://bucket/ to get the raw GCS prefix string.
+		subPrefix := strings.TrimPrefix(e.Path, "gs://"+bucket+"/")
 		wg.Add(1)
 		sem <- struct{}{}
 		go func(sp string) {
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			size, count, err := sumPrefix(ctx, client, bucket, sp)
-			resultCh <- subdirResult{
-				path:  fmt.Sprintf("gs://%s/%s", bucket, sp),
-				size:  size,
-				count: count,
-				err:   err,
+			tallied, err := sumPrefix(ctx, client, bucket, sp, opts, now)
+
+			// Step 4: collect and aggregate results.
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if sumErr == nil {
+					sumErr = err
+				}
+				return
 			}
+			for _, e := range tallied {
+				duEntries = append(duEntries, *e)
+			}
+			root.Merge(tallied[fmt.Sprintf("gs://%s/%s", bucket, sp)])
 		}(subPrefix)
+		return nil
+	})
+	wg.Wait()
+	if listErr != nil {
+		return nil, listErr
+	}
+	if sumErr != nil {
+		return nil, sumErr
 	}
 
-	wg.Wait()
-	close(resultCh)
-
-	// Step 4: collect and aggregate results.
-	var duEntries []DUEntry
-	total := rootFileTotal
-	totalCount := rootFileCount
-	for r := range resultCh {
-		if r.err != nil {
-			return nil, r.err
+	// If there are no results at all and a prefix was specified, the path may
+	// be a single object rather than a "directory". Handle it gracefully.
+	if children == 0 && prefix != "" {
+		obj := client.Bucket(bucket).Object(prefix)
+		apilog.Logf("[GCS] Object.Attrs(gs://%s/%s) [du single-file probe]", bucket, prefix)
+		attrs, attrErr := obj.Attrs(ctx)
+		// Object not found either – the result stays zero.
+		if attrErr == nil {
+			root.add(attrs.Size, attrs.StorageClass, attrs.Created, opts, now)
 		}
-		duEntries = append(duEntries, DUEntry{Path: r.path, Size: r.size, Count: r.count})
-		total += r.size
-		totalCount += r.count
+		return &DUResult{RootPath: rootPath, Root: root, Total: root.Size, Count: root.Count}, nil
 	}
 
-	sort.Slice(duEntries, func(i, j int) bool {
-		return duEntries[i].Path < duEntries[j].Path
-	})
+	sortDUEntries(duEntries)
 
 	return &DUResult{
 		Entries:  duEntries,
+		Root:     root,
 		RootPath: rootPath,
-		Total:    total,
-		Count:    totalCount,
+		Total:    root.Size,
+		Count:    root.Count,
 	}, nil
 }
 
@@ -179,104 +298,132 @@ func DiskUsagePattern(ctx context.Context, bucket, pattern string, opts *DUOptio
 	if opts.Workers <= 0 {
 		opts.Workers = 8
 	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 1
+	}
 
 	client, err := GetClient(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create storage client: %w", err)
 	}
+	now := time.Now()
 
 	// Strip trailing slash for pattern matching – ListWithPattern works on names
 	// without it and will return the directory as a prefix (IsPrefix=true).
 	pattern = strings.TrimSuffix(pattern, "/")
 
-	matches, err := ListWithPattern(ctx, bucket, pattern, &ListOptions{
+	// Matches are summed by workers as the listing streams in
+	var (
+		mu      sync.Mutex // guards entries and sumErr
+		entries []DUEntry
+		sumErr  error
+		wg      sync.WaitGroup
+	)
+	sem := make(chan struct{}, opts.Workers)
+
+	listErr := ListWithPatternEach(ctx, bucket, pattern, &ListOptions{
 		Recursive: false,
 		Delimiter: "/",
-	})
-	if err != nil {
-		return nil, err
-	}
-	if len(matches) == 0 {
-		return nil, nil
-	}
-
-	type subdirResult struct {
-		path  string
-		size  int64
-		count int64
-		err   error
-	}
-
-	resultCh := make(chan subdirResult, len(matches))
-	sem := make(chan struct{}, opts.Workers)
-	var wg sync.WaitGroup
+	}, func(m *ObjectInfo) error {
+		if !m.IsPrefix {
+			entry := DUEntry{Path: m.Path}
+			entry.add(m.Size, m.StorageClass, m.Created, opts, now)
+			mu.Lock()
+			entries = append(entries, entry)
+			mu.Unlock()
+			return nil
+		}
 
-	for _, m := range matches {
 		wg.Add(1)
 		sem <- struct{}{}
 		go func(m *ObjectInfo) {
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			var size int64
-			var count int64
-			var sumErr error
-			if m.IsPrefix {
-				subPrefix := strings.TrimPrefix(m.Path, "gs://"+bucket+"/")
-				size, count, sumErr = sumPrefix(ctx, client, bucket, subPrefix)
-			} else {
-				size = m.Size
-				count = 1
+			subPrefix := strings.TrimPrefix(m.Path, "gs://"+bucket+"/")
+			tallied, err := sumPrefix(ctx, client, bucket, subPrefix, opts, now)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if sumErr == nil {
+					sumErr = err
+				}
+				return
+			}
+			for _, e := range tallied {
+				entries = append(entries, *e)
 			}
-			resultCh <- subdirResult{path: m.Path, size: size, count: count, err: sumErr}
 		}(m)
-	}
-
+		return nil
+	})
 	wg.Wait()
-	close(resultCh)
-
-	var entries []DUEntry
-	for r := range resultCh {
-		if r.err != nil {
-			return nil, r.err
-		}
-		entries = append(entries, DUEntry{Path: r.path, Size: r.size, Count: r.count})
+	if listErr != nil {
+		return nil, listErr
+	}
+	if sumErr != nil {
+		return nil, sumErr
 	}
 
-	sort.Slice(entries, func(i, j int) bool {
-		return entries[i].Path < entries[j].Path
-	})
+	sortDUEntries(entries)
 
 	return entries, nil
 }
 
-// sumPrefix returns the total byte size and file count of all objects under a
-// GCS prefix.  It uses SetAttrSelection to fetch only Name and Size,
-// significantly reducing JSON payload and improving throughput for large
-// prefixes.
-func sumPrefix(ctx context.Context, client *storage.Client, bucket, prefix string) (size int64, count int64, err error) {
+// sumPrefix tallies the size and file count of all objects under a GCS
+// prefix. The result holds an entry for the prefix itself and, with
+// opts.MaxDepth > 1, for each nested directory up to that depth (counted from
+// the du root, which is one level above prefix). It uses SetAttrSelection to
+// fetch only the attributes needed, significantly reducing JSON payload and
+// improving throughput for large prefixes.
+func sumPrefix(ctx context.Context, client *storage.Client, bucket, prefix string, opts *DUOptions, now time.Time) (map[string]*DUEntry, error) {
+	fields := []string{"Name", "Size"}
+	if opts.ByClass {
+		fields = append(fields, "StorageClass")
+	}
+	if opts.ByAge {
+		fields = append(fields, "Created")
+	}
 	q := &storage.Query{Prefix: prefix}
-	if setErr := q.SetAttrSelection([]string{"Name", "Size"}); setErr != nil {
-		return 0, 0, fmt.Errorf("SetAttrSelection: %w", setErr)
+	if setErr := q.SetAttrSelection(fields); setErr != nil {
+		return nil, fmt.Errorf("SetAttrSelection: %w", setErr)
 	}
 
 	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) [du sum]", bucket, prefix)
 	it := client.Bucket(bucket).Objects(ctx, q)
 
+	root := fmt.Sprintf("gs://%s/%s", bucket, prefix)
+	entries := map[string]*DUEntry{root: {Path: root}}
 	for {
 		attrs, iterErr := it.Next()
 		if iterErr == iterator.Done {
 			break
 		}
 		if iterErr != nil {
-			return 0, 0, fmt.Errorf("iterating objects under gs://%s/%s: %w", bucket, prefix, iterErr)
+			return nil, fmt.Errorf("iterating objects under gs://%s/%s: %w", bucket, prefix, iterErr)
 		}
 		// Skip zero-byte directory placeholder objects (name ends with /).
 		if attrs.Size == 0 && strings.HasSuffix(attrs.Name, "/") {
 			continue
 		}
-		size += attrs.Size
-		count++
+		entries[root].add(attrs.Size, attrs.StorageClass, attrs.Created, opts, now)
+
+		// Nested directories below prefix, down to MaxDepth
+		dir := root
+		rest := strings.TrimPrefix(attrs.Name, prefix)
+		for depth := 2; depth <= opts.MaxDepth; depth++ {
+			i := strings.Index(rest, "/")
+			if i < 0 {
+				break
+			}
+			dir += rest[:i+1]
+			rest = rest[i+1:]
+			e, ok := entries[dir]
+			if !ok {
+				e = &DUEntry{Path: dir}
+				entries[dir] = e
+			}
+			e.add(attrs.Size, attrs.StorageClass, attrs.Created, opts, now)
+		}
 	}
-	return size, count, nil
+	return entries, nil
 }
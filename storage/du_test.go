@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAgeBucket(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{0, "<30d"},
+		{29 * day, "<30d"},
+		{30 * day, "30-90d"},
+		{89 * day, "30-90d"},
+		{90 * day, "90d-1y"},
+		{364 * day, "90d-1y"},
+		{365 * day, ">1y"},
+		{3 * 365 * day, ">1y"},
+	}
+
+	for _, tt := range tests {
+		if got := ageBucket(tt.age); got != tt.want {
+			t.Errorf("ageBucket(%v) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
+func TestSortDUEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{
+			name:  "siblings by name",
+			paths: []string{"gs://b/c/", "gs://b/a/", "gs://b/b/"},
+			want:  []string{"gs://b/a/", "gs://b/b/", "gs://b/c/"},
+		},
+		{
+			name:  "nested before parent",
+			paths: []string{"gs://b/a/", "gs://b/a/x/", "gs://b/a/x/y/"},
+			want:  []string{"gs://b/a/x/y/", "gs://b/a/x/", "gs://b/a/"},
+		},
+		{
+			name:  "mixed",
+			paths: []string{"gs://b/logs/", "gs://b/data/2024/", "gs://b/data/", "gs://b/logs/app/", "gs://b/data/2023/"},
+			want:  []string{"gs://b/data/2023/", "gs://b/data/2024/", "gs://b/data/", "gs://b/logs/app/", "gs://b/logs/"},
+		},
+		{
+			name:  "name prefix is not a parent",
+			paths: []string{"gs://b/ab/", "gs://b/a/"},
+			want:  []string{"gs://b/a/", "gs://b/ab/"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := make([]DUEntry, len(tt.paths))
+			for i, p := range tt.paths {
+				entries[i] = DUEntry{Path: p}
+			}
+			sortDUEntries(entries)
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortDUEntries(%v) = %v, want %v", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
+)
+
+// EncryptionKeys are the keys cio uses for the objects below one location.
+type EncryptionKeys struct {
+	// Key is a customer-supplied AES-256 key (CSEK). New objects are
+	// written encrypted with it, and objects encrypted with it can be read.
+	Key []byte
+	// KMSKeyName is the Cloud KMS key (CMEK) new objects are written with,
+	// e.g. projects/p/locations/l/keyRings/r/cryptoKeys/k. Ignored when Key
+	// is set.
+	KMSKeyName string
+	// DecryptionKeys are further CSEKs accepted for reading only, such as
+	// the key being rotated away from
+	DecryptionKeys [][]byte
+}
+
+// encryptionRule applies keys to the objects of bucket below prefix.
+type encryptionRule struct {
+	bucket string
+	prefix string
+	keys   EncryptionKeys
+}
+
+// encryptionRules are set per alias from the config; encryptionOverride is
+// set from command-line flags and applies to every object.
+var (
+	encryptionRules    []encryptionRule
+	encryptionOverride *EncryptionKeys
+)
+
+// SetEncryptionKeys registers keys for the objects below location
+// ("gs://bucket/prefix/"). The longest matching location wins. It must be
+// called before the first GCS request.
+func SetEncryptionKeys(location string, keys EncryptionKeys) error {
+	bucket, prefix, err := resolver.ParseGCSPath(location)
+	if err != nil || bucket == "" {
+		return fmt.Errorf("invalid encryption location %q: must be gs://bucket/prefix", location)
+	}
+	if err := validateEncryptionKeys(keys); err != nil {
+		return fmt.Errorf("encryption keys for %s: %w", location, err)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	encryptionRules = append(encryptionRules, encryptionRule{bucket: bucket, prefix: prefix, keys: keys})
+	return nil
+}
+
+// SetCommandEncryptionKeys sets keys for every object, overriding the keys
+// registered per location (--encryption-key-file, --kms-key). Keys with
+// only DecryptionKeys add read keys and leave writes as configured.
+func SetCommandEncryptionKeys(keys EncryptionKeys) error {
+	if err := validateEncryptionKeys(keys); err != nil {
+		return err
+	}
+	encryptionOverride = &keys
+	return nil
+}
+
+// ParseEncryptionKey decodes a base64 AES-256 key as gsutil and gcloud
+// accept it. Surrounding whitespace is ignored.
+func ParseEncryptionKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: not base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid encryption key: %d bytes, want 32 (AES-256)", len(key))
+	}
+	return key, nil
+}
+
+func validateEncryptionKeys(keys EncryptionKeys) error {
+	for _, key := range append([][]byte{keys.Key}, keys.DecryptionKeys...) {
+		if key != nil && len(key) != 32 {
+			return fmt.Errorf("invalid encryption key: %d bytes, want 32 (AES-256)", len(key))
+		}
+	}
+	if keys.KMSKeyName != "" && !strings.HasPrefix(keys.KMSKeyName, "projects/") {
+		return fmt.Errorf("invalid KMS key %q: want projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>", keys.KMSKeyName)
+	}
+	return nil
+}
+
+// encryptionFor returns the keys that apply to bucket/object, or nil.
+func encryptionFor(bucket, object string) *EncryptionKeys {
+	if o := encryptionOverride; o != nil && (o.Key != nil || o.KMSKeyName != "") {
+		return o
+	}
+	var best *encryptionRule
+	for i := range encryptionRules {
+		r := &encryptionRules[i]
+		if r.bucket == bucket && strings.HasPrefix(object, r.prefix) && (best == nil || len(r.prefix) > len(best.prefix)) {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &best.keys
+}
+
+// KeySHA256 returns the base64 SHA-256 of a CSEK, the form GCS reports in
+// an object's customerEncryption.keySha256.
+func KeySHA256(key []byte) string {
+	sum := sha256.Sum256(key)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeKeyFor returns the CSEK new objects at bucket/object are written
+// with, or nil.
+func writeKeyFor(bucket, object string) []byte {
+	if keys := encryptionFor(bucket, object); keys != nil {
+		return keys.Key
+	}
+	return nil
+}
+
+// withWriteKey applies the CSEK configured for obj's location, so a write
+// through the handle is encrypted with it.
+func withWriteKey(obj *storage.ObjectHandle) *storage.ObjectHandle {
+	if key := writeKeyFor(obj.BucketName(), obj.ObjectName()); key != nil {
+		return obj.Key(key)
+	}
+	return obj
+}
+
+// kmsKeyFor returns the KMS key new objects at bucket/object are written
+// with, or "" (no CMEK, or a CSEK takes precedence).
+func kmsKeyFor(bucket, object string) string {
+	if keys := encryptionFor(bucket, object); keys != nil && keys.Key == nil {
+		return keys.KMSKeyName
+	}
+	return ""
+}
+
+// encryptWriter sets the CMEK of a new writer; CSEKs are applied to the
+// handle (withWriteKey).
+func encryptWriter(w *storage.Writer) {
+	if kms := kmsKeyFor(w.Bucket, w.Name); kms != "" {
+		w.KMSKeyName = kms
+	}
+}
+
+// WithReadKey applies the CSEK an object is encrypted with, identified by
+// keySHA256 from its attributes ("" for objects without a CSEK). Every
+// configured key is tried, so objects remain readable while keys rotate.
+func WithReadKey(obj *storage.ObjectHandle, keySHA256 string) (*storage.ObjectHandle, error) {
+	if keySHA256 == "" {
+		return obj, nil
+	}
+	if key := findKey(keySHA256); key != nil {
+		return obj.Key(key), nil
+	}
+	return nil, fmt.Errorf("gs://%s/%s is encrypted with a customer-supplied key (sha256 %s) that is not configured (see 'encryption' in the config or --encryption-key-file)",
+		obj.BucketName(), obj.ObjectName(), keySHA256)
+}
+
+// findKey returns the configured CSEK whose SHA-256 is keySHA256.
+func findKey(keySHA256 string) []byte {
+	candidates := encryptionRules
+	if encryptionOverride != nil {
+		candidates = append([]encryptionRule{{keys: *encryptionOverride}}, candidates...)
+	}
+	for _, r := range candidates {
+		for _, key := range append([][]byte{r.keys.Key}, r.keys.DecryptionKeys...) {
+			if key != nil && KeySHA256(key) == keySHA256 {
+				return key
+			}
+		}
+	}
+	return nil
+}
+
+// csekConfigured reports whether any customer-supplied key is configured.
+func csekConfigured() bool {
+	if encryptionOverride != nil && (encryptionOverride.Key != nil || len(encryptionOverride.DecryptionKeys) > 0) {
+		return true
+	}
+	for _, r := range encryptionRules {
+		if r.keys.Key != nil || len(r.keys.DecryptionKeys) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// withObjectKey applies the CSEK obj is encrypted with, for readers that
+// have not fetched its attributes. Without configured CSEKs obj is returned
+// unchanged; otherwise the attributes are read to identify the key.
+func withObjectKey(ctx context.Context, obj *storage.ObjectHandle) (*storage.ObjectHandle, error) {
+	if !csekConfigured() {
+		return obj, nil
+	}
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s) for encryption key", obj.BucketName(), obj.ObjectName())
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return WithReadKey(obj, attrs.CustomerKeySHA256)
+}
+
+// objectAttrs fetches obj's attributes for a verified read. GCS withholds
+// the checksums of objects encrypted with a customer-supplied key unless the
+// key is given, so for those they are fetched again with the key.
+func objectAttrs(ctx context.Context, obj *storage.ObjectHandle) (*storage.ObjectAttrs, error) {
+	attrs, err := obj.Attrs(ctx)
+	if err != nil || attrs.CustomerKeySHA256 == "" {
+		return attrs, err
+	}
+	keyed, err := WithReadKey(obj, attrs.CustomerKeySHA256)
+	if err != nil {
+		return nil, err
+	}
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s) with encryption key", obj.BucketName(), obj.ObjectName())
+	return keyed.Attrs(ctx)
+}
+
+// readHandle returns obj set up to read the object described by attrs: the
+// generation read up front and its CSEK, if any.
+func readHandle(obj *storage.ObjectHandle, attrs *storage.ObjectAttrs) (*storage.ObjectHandle, error) {
+	return WithReadKey(obj.Generation(attrs.Generation), attrs.CustomerKeySHA256)
+}
+
+// encryptedCopier returns a server-side copy from src, whose read key must
+// already be applied, to dst encrypted with the key configured for dst.
+func encryptedCopier(dst, src *storage.ObjectHandle) *storage.Copier {
+	c := withWriteKey(dst).CopierFrom(src)
+	c.DestinationKMSKeyName = kmsKeyFor(dst.BucketName(), dst.ObjectName())
+	return c
+}
+
+// sameKeyCopier returns a server-side copy from src, described by attrs, to
+// dst that keeps the source's encryption: the same CSEK or KMS key.
+func sameKeyCopier(dst, src *storage.ObjectHandle, attrs *storage.ObjectAttrs) (*storage.Copier, error) {
+	if attrs.CustomerKeySHA256 != "" {
+		var err error
+		if src, err = WithReadKey(src, attrs.CustomerKeySHA256); err != nil {
+			return nil, err
+		}
+		dst = dst.Key(findKey(attrs.CustomerKeySHA256))
+	}
+	c := dst.CopierFrom(src)
+	c.DestinationKMSKeyName = kmsKeyOf(attrs)
+	return c, nil
+}
+
+// kmsKeyOf returns the KMS key an object is encrypted with. Attributes
+// report the key version; writes take the key itself.
+func kmsKeyOf(attrs *storage.ObjectAttrs) string {
+	key, _, _ := strings.Cut(attrs.KMSKeyName, "/cryptoKeyVersions/")
+	return key
+}
+
+// hasTargetKey reports whether an object at bucket/object, encrypted as
+// described, already uses the key configured for its location.
+func hasTargetKey(bucket, object, kmsKeyName, customerKeySHA256 string) bool {
+	keys := encryptionFor(bucket, object)
+	switch {
+	case keys == nil:
+		return kmsKeyName == "" && customerKeySHA256 == ""
+	case keys.Key != nil:
+		return customerKeySHA256 == KeySHA256(keys.Key)
+	default:
+		key, _, _ := strings.Cut(kmsKeyName, "/cryptoKeyVersions/")
+		return customerKeySHA256 == "" && key == keys.KMSKeyName
+	}
+}
+
+// encryptionDescription describes an object's key for rewrite output.
+func encryptionDescription(kmsKeyName, customerKeySHA256 string) string {
+	if customerKeySHA256 != "" {
+		return "CSEK " + customerKeySHA256[:8]
+	}
+	if label := EncryptionLabel(kmsKeyName, ""); label != "" {
+		return label
+	}
+	return "Google-managed"
+}
+
+// targetKeyDescription describes the key configured for bucket/object.
+func targetKeyDescription(bucket, object string) string {
+	keys := encryptionFor(bucket, object)
+	switch {
+	case keys == nil:
+		return "Google-managed"
+	case keys.Key != nil:
+		return encryptionDescription("", KeySHA256(keys.Key))
+	default:
+		return encryptionDescription(keys.KMSKeyName, "")
+	}
+}
+
+// EncryptionLabel describes how an object is encrypted for listings: "CSEK",
+// "CMEK <key>" or "" for Google-managed keys.
+func EncryptionLabel(kmsKeyName, customerKeySHA256 string) string {
+	switch {
+	case customerKeySHA256 != "":
+		return "CSEK"
+	case kmsKeyName != "":
+		key, _, _ := strings.Cut(kmsKeyName, "/cryptoKeyVersions/")
+		return "CMEK " + key[strings.LastIndex(key, "/")+1:]
+	}
+	return ""
+}
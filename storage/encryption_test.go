@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+// withEncryption installs rules and override for the duration of a test.
+func withEncryption(t *testing.T, rules []encryptionRule, override *EncryptionKeys) {
+	savedRules, savedOverride := encryptionRules, encryptionOverride
+	t.Cleanup(func() { encryptionRules, encryptionOverride = savedRules, savedOverride })
+	encryptionRules, encryptionOverride = rules, override
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"valid", base64.StdEncoding.EncodeToString(key), false},
+		{"surrounding whitespace", "  " + base64.StdEncoding.EncodeToString(key) + "\n", false},
+		{"not base64", "not a key!", true},
+		{"AES-128 key", base64.StdEncoding.EncodeToString(key[:16]), true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEncryptionKey(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseEncryptionKey(%q) = %v, want error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(got, key) {
+				t.Errorf("ParseEncryptionKey(%q) = %v, %v, want the key", tt.in, got, err)
+			}
+		})
+	}
+}
+
+func TestSetEncryptionKeys(t *testing.T) {
+	withEncryption(t, nil, nil)
+	key := bytes.Repeat([]byte{1}, 32)
+	tests := []struct {
+		location   string
+		keys       EncryptionKeys
+		wantErr    bool
+		wantPrefix string
+	}{
+		{"gs://vault/secret/", EncryptionKeys{Key: key}, false, "secret/"},
+		{"gs://vault/secret", EncryptionKeys{Key: key}, false, "secret/"},
+		{"gs://vault", EncryptionKeys{KMSKeyName: "projects/p/locations/l/keyRings/r/cryptoKeys/k"}, false, ""},
+		{"gs://vault/x/", EncryptionKeys{Key: key[:16]}, true, ""},
+		{"gs://vault/x/", EncryptionKeys{KMSKeyName: "keyRings/r/cryptoKeys/k"}, true, ""},
+		{"/local/dir", EncryptionKeys{Key: key}, true, ""},
+	}
+	for _, tt := range tests {
+		encryptionRules = nil
+		err := SetEncryptionKeys(tt.location, tt.keys)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("SetEncryptionKeys(%q) = nil, want error", tt.location)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SetEncryptionKeys(%q) = %v", tt.location, err)
+			continue
+		}
+		if len(encryptionRules) != 1 || encryptionRules[0].bucket != "vault" || encryptionRules[0].prefix != tt.wantPrefix {
+			t.Errorf("SetEncryptionKeys(%q) registered %+v, want bucket vault, prefix %q", tt.location, encryptionRules, tt.wantPrefix)
+		}
+	}
+}
+
+func TestEncryptionFor(t *testing.T) {
+	bucketKey := bytes.Repeat([]byte{1}, 32)
+	secretKey := bytes.Repeat([]byte{2}, 32)
+	flagKey := bytes.Repeat([]byte{3}, 32)
+	const kms = "projects/p/locations/l/keyRings/r/cryptoKeys/reports"
+	rules := []encryptionRule{
+		{bucket: "vault", keys: EncryptionKeys{Key: bucketKey}},
+		{bucket: "vault", prefix: "secret/", keys: EncryptionKeys{Key: secretKey}},
+		{bucket: "data", prefix: "reports/", keys: EncryptionKeys{KMSKeyName: kms}},
+	}
+
+	tests := []struct {
+		name     string
+		override *EncryptionKeys
+		bucket   string
+		object   string
+		wantKey  []byte
+		wantKMS  string
+	}{
+		{name: "bucket-wide rule", bucket: "vault", object: "public/a", wantKey: bucketKey},
+		{name: "longest prefix wins", bucket: "vault", object: "secret/a", wantKey: secretKey},
+		{name: "prefix is not a path match", bucket: "vault", object: "secretive/a", wantKey: bucketKey},
+		{name: "kms rule", bucket: "data", object: "reports/q1.csv", wantKMS: kms},
+		{name: "no rule", bucket: "data", object: "raw/x"},
+		{name: "command key overrides", override: &EncryptionKeys{Key: flagKey}, bucket: "data", object: "reports/q1.csv", wantKey: flagKey},
+		{name: "read-only command keys do not override", override: &EncryptionKeys{DecryptionKeys: [][]byte{flagKey}}, bucket: "vault", object: "secret/a", wantKey: secretKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withEncryption(t, rules, tt.override)
+			if got := writeKeyFor(tt.bucket, tt.object); !bytes.Equal(got, tt.wantKey) {
+				t.Errorf("writeKeyFor(%q, %q) = %v, want %v", tt.bucket, tt.object, got, tt.wantKey)
+			}
+			if got := kmsKeyFor(tt.bucket, tt.object); got != tt.wantKMS {
+				t.Errorf("kmsKeyFor(%q, %q) = %q, want %q", tt.bucket, tt.object, got, tt.wantKMS)
+			}
+		})
+	}
+}
+
+func TestFindKey(t *testing.T) {
+	current := bytes.Repeat([]byte{1}, 32)
+	old := bytes.Repeat([]byte{2}, 32)
+	flagKey := bytes.Repeat([]byte{3}, 32)
+	unknown := bytes.Repeat([]byte{4}, 32)
+	withEncryption(t, []encryptionRule{
+		{bucket: "vault", prefix: "secret/", keys: EncryptionKeys{Key: current, DecryptionKeys: [][]byte{old}}},
+	}, &EncryptionKeys{DecryptionKeys: [][]byte{flagKey}})
+
+	for _, key := range [][]byte{current, old, flagKey} {
+		if got := findKey(KeySHA256(key)); !bytes.Equal(got, key) {
+			t.Errorf("findKey(%s) = %v, want %v", KeySHA256(key), got, key)
+		}
+	}
+	if got := findKey(KeySHA256(unknown)); got != nil {
+		t.Errorf("findKey() of an unconfigured key = %v, want nil", got)
+	}
+	if !csekConfigured() {
+		t.Error("csekConfigured() = false with customer-supplied keys configured")
+	}
+}
+
+func TestHasTargetKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	const kms = "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+	withEncryption(t, []encryptionRule{
+		{bucket: "vault", prefix: "csek/", keys: EncryptionKeys{Key: key}},
+		{bucket: "vault", prefix: "cmek/", keys: EncryptionKeys{KMSKeyName: kms}},
+	}, nil)
+
+	tests := []struct {
+		object    string
+		kmsKey    string
+		keySHA256 string
+		want      bool
+	}{
+		{"csek/a", "", KeySHA256(key), true},
+		{"csek/a", "", KeySHA256(bytes.Repeat([]byte{2}, 32)), false},
+		{"csek/a", "", "", false},
+		{"cmek/a", kms + "/cryptoKeyVersions/3", "", true},
+		{"cmek/a", "projects/p/locations/l/keyRings/r/cryptoKeys/old/cryptoKeyVersions/1", "", false},
+		{"cmek/a", "", "", false},
+		{"plain/a", "", "", true},
+		{"plain/a", kms + "/cryptoKeyVersions/1", "", false},
+	}
+	for _, tt := range tests {
+		if got := hasTargetKey("vault", tt.object, tt.kmsKey, tt.keySHA256); got != tt.want {
+			t.Errorf("hasTargetKey(%q, %q, %q) = %v, want %v", tt.object, tt.kmsKey, tt.keySHA256, got, tt.want)
+		}
+	}
+}
+
+func TestEncryptionLabel(t *testing.T) {
+	tests := []struct {
+		kmsKey    string
+		keySHA256 string
+		want      string
+	}{
+		{"", "", ""},
+		{"", "c2hhMjU2", "CSEK"},
+		{"projects/p/locations/l/keyRings/r/cryptoKeys/reports/cryptoKeyVersions/2", "", "CMEK reports"},
+		{"projects/p/locations/l/keyRings/r/cryptoKeys/reports", "", "CMEK reports"},
+	}
+	for _, tt := range tests {
+		if got := EncryptionLabel(tt.kmsKey, tt.keySHA256); got != tt.want {
+			t.Errorf("EncryptionLabel(%q, %q) = %q, want %q", tt.kmsKey, tt.keySHA256, got, tt.want)
+		}
+	}
+}
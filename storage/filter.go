@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/thieso2/cio/resolver"
+)
+
+// PathFilter selects files and objects by their path relative to the root of
+// a recursive copy or delete. Patterns follow gitignore conventions on top
+// of resolver.MatchDoubleStarPattern:
+//
+//   - a pattern without "/" matches a name at any depth ("*.tmp")
+//   - a pattern with "/" is anchored at the root ("logs/2024/*"); a leading
+//     "/" only anchors
+//   - a pattern matching a directory matches everything below it; a trailing
+//     "/" matches directories only ("build/")
+//   - in exclude rules, "!" re-includes what an earlier rule excluded; the
+//     last matching rule wins
+//
+// A nil *PathFilter matches everything.
+type PathFilter struct {
+	includes []filterRule
+	excludes []filterRule
+}
+
+type filterRule struct {
+	pattern string // with "**/" prepended if unanchored
+	dirOnly bool
+	negate  bool
+}
+
+// NewPathFilter builds a filter from --include and --exclude patterns and
+// --exclude-from files (gitignore syntax: one pattern per line, # comments).
+// File rules come before --exclude patterns, so the flags take precedence.
+// It returns nil if there are no patterns.
+func NewPathFilter(includes, excludes, excludeFiles []string) (*PathFilter, error) {
+	f := &PathFilter{}
+	for _, p := range includes {
+		rule, ok := parseFilterRule(p)
+		if !ok {
+			continue
+		}
+		if rule.negate {
+			return nil, fmt.Errorf("invalid --include %q: ! is only supported in exclude rules", p)
+		}
+		f.includes = append(f.includes, rule)
+	}
+	for _, file := range excludeFiles {
+		rules, err := readIgnoreFile(file)
+		if err != nil {
+			return nil, err
+		}
+		f.excludes = append(f.excludes, rules...)
+	}
+	for _, p := range excludes {
+		if rule, ok := parseFilterRule(p); ok {
+			f.excludes = append(f.excludes, rule)
+		}
+	}
+	if len(f.includes) == 0 && len(f.excludes) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// readIgnoreFile reads gitignore-style rules from path.
+func readIgnoreFile(path string) ([]filterRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exclude file: %w", err)
+	}
+	defer file.Close()
+
+	var rules []filterRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rule, ok := parseFilterRule(line); ok {
+			rules = append(rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read exclude file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// parseFilterRule parses one pattern. It reports false for blank patterns.
+func parseFilterRule(p string) (filterRule, bool) {
+	var rule filterRule
+	p = strings.TrimRight(p, " \t\r")
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\`) {
+		// \# and \! escape a literal leading # or !
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return rule, false
+	}
+	if strings.HasPrefix(p, "/") {
+		p = strings.TrimLeft(p, "/")
+	} else if !strings.Contains(p, "/") && !strings.HasPrefix(p, "**") {
+		p = "**/" + p
+	}
+	rule.pattern = p
+	return rule, true
+}
+
+// matches reports whether the rule matches relPath itself (files only) or
+// one of its parent directories.
+func (r filterRule) matches(relPath string) bool {
+	if !r.dirOnly && resolver.MatchDoubleStarPattern(relPath, r.pattern) {
+		return true
+	}
+	return resolver.MatchDoubleStarPattern(relPath, r.pattern+"/**")
+}
+
+// Match reports whether the file or object at relPath (relative to the
+// copy/delete root, "/"-separated) passes the filter.
+func (f *PathFilter) Match(relPath string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.includes) > 0 {
+		included := false
+		for _, r := range f.includes {
+			if r.matches(relPath) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	excluded := false
+	for _, r := range f.excludes {
+		if r.matches(relPath) {
+			excluded = !r.negate
+		}
+	}
+	return !excluded
+}
+
+// SkipDir reports whether a whole local directory can be skipped while
+// walking: it is excluded and no "!" rule could re-include anything below it.
+func (f *PathFilter) SkipDir(relDir string) bool {
+	if f == nil {
+		return false
+	}
+	excluded := false
+	for _, r := range f.excludes {
+		if r.negate {
+			return false
+		}
+		if resolver.MatchDoubleStarPattern(relDir, r.pattern) || resolver.MatchDoubleStarPattern(relDir, r.pattern+"/**") {
+			excluded = true
+		}
+	}
+	return excluded
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFilterRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want filterRule
+		ok   bool
+	}{
+		{in: "*.tmp", want: filterRule{pattern: "**/*.tmp"}, ok: true},
+		{in: "logs/2024/*", want: filterRule{pattern: "logs/2024/*"}, ok: true},
+		{in: "/build", want: filterRule{pattern: "build"}, ok: true},
+		{in: "build/", want: filterRule{pattern: "**/build", dirOnly: true}, ok: true},
+		{in: "!keep.tmp", want: filterRule{pattern: "**/keep.tmp", negate: true}, ok: true},
+		{in: `\!bang`, want: filterRule{pattern: "**/!bang"}, ok: true},
+		{in: `\#hash`, want: filterRule{pattern: "**/#hash"}, ok: true},
+		{in: "**/cache", want: filterRule{pattern: "**/cache"}, ok: true},
+		{in: "*.log  \r", want: filterRule{pattern: "**/*.log"}, ok: true},
+		{in: ""},
+		{in: "   "},
+		{in: "/"},
+		{in: "!"},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseFilterRule(tt.in)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseFilterRule(%q) = %+v, %t; want %+v, %t", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPathFilterMatch(t *testing.T) {
+	tests := []struct {
+		name               string
+		includes, excludes []string
+		match              map[string]bool
+	}{
+		{
+			name:     "unanchored exclude matches at any depth",
+			excludes: []string{"*.tmp"},
+			match:    map[string]bool{"a.tmp": false, "x/y/a.tmp": false, "a.txt": true},
+		},
+		{
+			name:     "anchored exclude",
+			excludes: []string{"/logs"},
+			match:    map[string]bool{"logs/a": false, "x/logs/a": true},
+		},
+		{
+			name:     "directory exclude matches everything below it",
+			excludes: []string{"build/"},
+			match:    map[string]bool{"build/a.o": false, "src/build/x/a.o": false, "build": true},
+		},
+		{
+			name:     "negation re-includes, last rule wins",
+			excludes: []string{"*.log", "!keep.log"},
+			match:    map[string]bool{"a.log": false, "keep.log": true, "x/keep.log": true},
+		},
+		{
+			name:     "later exclude overrides negation",
+			excludes: []string{"*.log", "!keep.log", "old/"},
+			match:    map[string]bool{"old/keep.log": false, "new/keep.log": true},
+		},
+		{
+			name:     "includes",
+			includes: []string{"*.csv", "data/**"},
+			match:    map[string]bool{"a.csv": true, "x/a.csv": true, "data/a.bin": true, "a.bin": false},
+		},
+		{
+			name:     "exclude applies after include",
+			includes: []string{"*.csv"},
+			excludes: []string{"tmp/"},
+			match:    map[string]bool{"a.csv": true, "tmp/a.csv": false, "a.json": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewPathFilter(tt.includes, tt.excludes, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for path, want := range tt.match {
+				if got := f.Match(path); got != want {
+					t.Errorf("Match(%q) = %t, want %t", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPathFilterSkipDir(t *testing.T) {
+	tests := []struct {
+		excludes []string
+		dir      string
+		want     bool
+	}{
+		{[]string{"node_modules/"}, "node_modules", true},
+		{[]string{"node_modules/"}, "a/node_modules", true},
+		{[]string{"node_modules/"}, "src", false},
+		{[]string{"/cache"}, "cache/sub", true},
+		{[]string{"cache/", "!cache/keep"}, "cache", false},
+		{[]string{"*.tmp"}, "src", false},
+	}
+	for _, tt := range tests {
+		f, err := NewPathFilter(nil, tt.excludes, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.SkipDir(tt.dir); got != tt.want {
+			t.Errorf("excludes %v: SkipDir(%q) = %t, want %t", tt.excludes, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestNewPathFilter(t *testing.T) {
+	f, err := NewPathFilter(nil, []string{"", " "}, nil)
+	if err != nil || f != nil {
+		t.Errorf("blank patterns = %v, %v; want nil filter", f, err)
+	}
+	if !f.Match("anything") || f.SkipDir("anything") {
+		t.Error("nil filter must match everything and skip nothing")
+	}
+	if _, err := NewPathFilter([]string{"!*.csv"}, nil, nil); err == nil {
+		t.Error("negated --include: want error")
+	}
+	if _, err := NewPathFilter(nil, nil, []string{filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("missing exclude file: want error")
+	}
+
+	// --exclude patterns come after the file's rules and take precedence
+	file := filepath.Join(t.TempDir(), "excludes.txt")
+	content := "# build output\n*.o\n!main.o\n\n\\#notes\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err = NewPathFilter(nil, []string{"main.o"}, []string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{"a.o": false, "main.o": false, "#notes": false, "# build output": true, "a.c": true} {
+		if got := f.Match(path); got != want {
+			t.Errorf("Match(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +26,34 @@ func FormatSize(bytes int64) string {
 	return fmt.Sprintf("%.1f %s", float64(bytes)/float64(div), units[exp])
 }
 
+// ParseSize parses a human-readable size such as "4096", "4KB", "1.5M" or
+// "2 GiB" into bytes. Units are binary (1KB = 1024 bytes), matching FormatSize.
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	num, unit := str, ""
+	if i := strings.IndexFunc(str, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }); i >= 0 {
+		num, unit = str[:i], strings.TrimSpace(str[i:])
+	}
+
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	multipliers := map[string]float64{
+		"": 1, "B": 1,
+		"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+		"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+		"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+		"T": 1 << 40, "TB": 1 << 40, "TIB": 1 << 40,
+	}
+	mult, ok := multipliers[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, unit)
+	}
+	return int64(value * mult), nil
+}
+
 // FormatUnixTime formats a time in Unix ls style
 // Recent files (< 6 months): "19 Jan. 16:54"
 // Older files (>= 6 months): " 2 Jan.  2024"
@@ -52,9 +81,21 @@ type ObjectInfo struct {
 	Path         string
 	Size         int64
 	Updated      time.Time
+	Created      time.Time
 	IsPrefix     bool
 	ContentType  string
 	StorageClass string
+	Generation   int64
+	Noncurrent   bool // a noncurrent version (only returned when listing versions)
+	// KMSKeyName and CustomerKeySHA256 identify a customer-managed (CMEK)
+	// or customer-supplied (CSEK) key; both are empty for Google-managed keys
+	KMSKeyName        string
+	CustomerKeySHA256 string
+	// Holds and the end of retention; a deleted object is refused until
+	// the holds are released and RetainedUntil has passed
+	TemporaryHold  bool
+	EventBasedHold bool
+	RetainedUntil  time.Time
 }
 
 // FormatShort formats object info in short format (just the path)
@@ -117,6 +158,15 @@ func (oi *ObjectInfo) FormatLongWithAlias(humanReadable bool, aliasPath string)
 	if aliasPath != "" {
 		displayPath = aliasPath
 	}
+	if oi.Noncurrent {
+		displayPath += "  (noncurrent)"
+	}
+	if label := EncryptionLabel(oi.KMSKeyName, oi.CustomerKeySHA256); label != "" {
+		displayPath += "  (" + label + ")"
+	}
+	if label := HoldLabel(oi.TemporaryHold, oi.EventBasedHold, oi.RetainedUntil, time.Now()); label != "" {
+		displayPath += "  (" + label + ")"
+	}
 
 	return fmt.Sprintf("%s  %s  %s", size, timestamp, displayPath)
 }
@@ -127,9 +177,19 @@ func CreateObjectInfo(attrs *storage.ObjectAttrs, bucketName string) *ObjectInfo
 		Path:         fmt.Sprintf("gs://%s/%s", bucketName, attrs.Name),
 		Size:         attrs.Size,
 		Updated:      attrs.Updated,
+		Created:      attrs.Created,
 		IsPrefix:     false,
 		ContentType:  attrs.ContentType,
 		StorageClass: attrs.StorageClass,
+		Generation:   attrs.Generation,
+		Noncurrent:   !attrs.Deleted.IsZero(),
+
+		KMSKeyName:        attrs.KMSKeyName,
+		CustomerKeySHA256: attrs.CustomerKeySHA256,
+
+		TemporaryHold:  attrs.TemporaryHold,
+		EventBasedHold: attrs.EventBasedHold,
+		RetainedUntil:  retainedUntil(attrs),
 	}
 }
 
@@ -0,0 +1,36 @@
+package storage
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "4096", want: 4096},
+		{in: "0", want: 0},
+		{in: "512B", want: 512},
+		{in: "4KB", want: 4 << 10},
+		{in: "4k", want: 4 << 10},
+		{in: "1.5M", want: 3 << 19},
+		{in: "2 GiB", want: 2 << 30},
+		{in: " 1TB ", want: 1 << 40},
+		{in: "", wantErr: true},
+		{in: "-1K", wantErr: true},
+		{in: "10XB", wantErr: true},
+		{in: "MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSize(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+)
+
+// maxGrepLine is the longest line grep can scan.
+const maxGrepLine = 16 << 20
+
+// GrepOptions controls how GrepObjects searches and reports.
+type GrepOptions struct {
+	Pattern *regexp.Regexp
+	// Invert selects non-matching lines
+	Invert bool
+	// FilesWithMatches prints only the paths of objects with a match
+	FilesWithMatches bool
+	// Count prints the number of matching lines per object
+	Count bool
+	// Before and After are the context lines printed around each match
+	Before, After int
+	// NoFilename omits the path prefix on output lines
+	NoFilename bool
+	// Raw disables transparent gunzip of compressed objects
+	Raw bool
+	// Recursive searches every object under a prefix
+	Recursive bool
+	// Formatter formats object paths in output (nil prints gs:// paths)
+	Formatter PathFormatter
+}
+
+// GrepObjects searches the objects object selects (a single object, a prefix
+// with Recursive, or a wildcard) for lines matching opts.Pattern. Objects are
+// read in parallel by up to maxWorkers workers; output is written per object,
+// in listing order, holding at most maxWorkers objects' output at a time, as "path:line:text" ("path-line-text" for context lines,
+// "--" between non-adjacent groups). It returns the number of objects with a
+// match.
+func GrepObjects(ctx context.Context, client *storage.Client, bucket, object string, w io.Writer, opts *GrepOptions, maxWorkers int) (int, error) {
+	if opts.Formatter == nil {
+		opts.Formatter = DefaultPathFormatter
+	}
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+
+	objects, err := expandObjects(ctx, client, bucket, object, opts.Recursive)
+	if err != nil {
+		return 0, err
+	}
+	if len(objects) == 0 {
+		return 0, fmt.Errorf("no objects found matching gs://%s/%s", bucket, object)
+	}
+
+	type grepped struct {
+		output  []byte
+		matched bool
+		err     error
+	}
+	// One result channel per object. window bounds the objects started but
+	// not yet printed, so a slow object holds back at most maxWorkers
+	// results instead of every later one.
+	results := make([]chan grepped, len(objects))
+	for i := range results {
+		results[i] = make(chan grepped, 1)
+	}
+	window := make(chan struct{}, maxWorkers)
+
+	go func() {
+		bkt := client.Bucket(bucket)
+		for i, obj := range objects {
+			window <- struct{}{}
+			go func(i int, obj *ObjectInfo) {
+				var out bytes.Buffer
+				matched, err := grepObject(ctx, bkt.Object(objectName(bucket, obj)), opts.Formatter(obj.Path), &out, opts)
+				results[i] <- grepped{output: out.Bytes(), matched: matched, err: err}
+			}(i, obj)
+		}
+	}()
+
+	// Print each object's output in listing order
+	var matchedCount int
+	var firstErr error
+	for i, ch := range results {
+		r := <-ch
+		<-window
+		if r.err != nil {
+			fmt.Printf("Failed: %s - %v\n", opts.Formatter(objects[i].Path), r.err)
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		if r.matched {
+			matchedCount++
+		}
+		w.Write(r.output)
+	}
+
+	if firstErr != nil {
+		return matchedCount, fmt.Errorf("grep failed: %w", firstErr)
+	}
+	return matchedCount, nil
+}
+
+// grepObject scans one object and writes its output to w. It reports
+// whether any line matched.
+func grepObject(ctx context.Context, obj *storage.ObjectHandle, path string, w io.Writer, opts *GrepOptions) (bool, error) {
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", obj.BucketName(), obj.ObjectName())
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return false, err
+	}
+	// Stop reading early once -l has its answer
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	src, err := openContent(ctx, obj.Generation(attrs.Generation), attrs, opts.Raw)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+	return grepReader(src, path, w, opts)
+}
+
+// grepReader scans src line by line and writes matches (and their context)
+// to w, labelled with path.
+func grepReader(src io.Reader, path string, w io.Writer, opts *GrepOptions) (bool, error) {
+	prefix := path + ":"
+	contextPrefix := path + "-"
+	if opts.NoFilename {
+		prefix, contextPrefix = "", ""
+	}
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), maxGrepLine)
+
+	type line struct {
+		n    int
+		text string
+	}
+	var before []line // the last opts.Before unprinted lines
+	afterLeft := 0    // context lines still to print after a match
+	lastPrinted := 0  // last line number printed, for "--" separators
+	count := 0
+	n := 0
+	for scanner.Scan() {
+		n++
+		text := scanner.Text()
+		if opts.Pattern.MatchString(text) == opts.Invert {
+			if afterLeft > 0 {
+				fmt.Fprintf(w, "%s%d-%s\n", contextPrefix, n, text)
+				lastPrinted = n
+				afterLeft--
+			} else if opts.Before > 0 {
+				before = append(before, line{n, text})
+				if len(before) > opts.Before {
+					before = before[1:]
+				}
+			}
+			continue
+		}
+
+		count++
+		if opts.FilesWithMatches {
+			fmt.Fprintln(w, path)
+			return true, nil
+		}
+		if opts.Count {
+			continue
+		}
+
+		first := n
+		if len(before) > 0 {
+			first = before[0].n
+		}
+		if lastPrinted > 0 && first > lastPrinted+1 && (opts.Before > 0 || opts.After > 0) {
+			fmt.Fprintln(w, "--")
+		}
+		for _, l := range before {
+			fmt.Fprintf(w, "%s%d-%s\n", contextPrefix, l.n, l.text)
+		}
+		before = before[:0]
+		fmt.Fprintf(w, "%s%d:%s\n", prefix, n, text)
+		lastPrinted = n
+		afterLeft = opts.After
+	}
+	if err := scanner.Err(); err != nil {
+		return count > 0, err
+	}
+
+	if opts.Count {
+		if opts.NoFilename {
+			fmt.Fprintf(w, "%d\n", count)
+		} else {
+			fmt.Fprintf(w, "%s:%d\n", path, count)
+		}
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGrepReader(t *testing.T) {
+	input := strings.Join([]string{"a", "b", "match 1", "c", "d", "e", "f", "match 2", "match 3", "g"}, "\n") + "\n"
+
+	tests := []struct {
+		name    string
+		opts    GrepOptions
+		want    string
+		matched bool
+	}{
+		{
+			name:    "matches only",
+			want:    "p:3:match 1\np:8:match 2\np:9:match 3\n",
+			matched: true,
+		},
+		{
+			name:    "context after",
+			opts:    GrepOptions{After: 1},
+			want:    "p:3:match 1\np-4-c\n--\np:8:match 2\np:9:match 3\np-10-g\n",
+			matched: true,
+		},
+		{
+			name:    "context before",
+			opts:    GrepOptions{Before: 2},
+			want:    "p-1-a\np-2-b\np:3:match 1\n--\np-6-e\np-7-f\np:8:match 2\np:9:match 3\n",
+			matched: true,
+		},
+		{
+			name:    "adjacent groups are not separated",
+			opts:    GrepOptions{Before: 2, After: 2},
+			want:    "p-1-a\np-2-b\np:3:match 1\np-4-c\np-5-d\np-6-e\np-7-f\np:8:match 2\np:9:match 3\np-10-g\n",
+			matched: true,
+		},
+		{
+			name:    "count",
+			opts:    GrepOptions{Count: true},
+			want:    "p:3\n",
+			matched: true,
+		},
+		{
+			name:    "files with matches",
+			opts:    GrepOptions{FilesWithMatches: true},
+			want:    "p\n",
+			matched: true,
+		},
+		{
+			name:    "invert",
+			opts:    GrepOptions{Invert: true, NoFilename: true},
+			want:    "1:a\n2:b\n4:c\n5:d\n6:e\n7:f\n10:g\n",
+			matched: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Pattern = regexp.MustCompile("match")
+			var out bytes.Buffer
+			matched, err := grepReader(strings.NewReader(input), "p", &out, &opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if matched != tt.matched {
+				t.Errorf("matched = %v, want %v", matched, tt.matched)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGrepReaderNoMatch(t *testing.T) {
+	var out bytes.Buffer
+	matched, err := grepReader(strings.NewReader("a\nb\n"), "p", &out, &GrepOptions{Pattern: regexp.MustCompile("x"), Count: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matched || out.String() != "p:0\n" {
+		t.Errorf("got %v %q, want false \"p:0\\n\"", matched, out.String())
+	}
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"google.golang.org/api/googleapi"
+)
+
+// HoldUpdate sets or clears the holds of objects; nil leaves a hold as is.
+type HoldUpdate struct {
+	Temporary  *bool
+	EventBased *bool
+}
+
+// IsEmpty reports whether the update changes nothing.
+func (u *HoldUpdate) IsEmpty() bool {
+	return u.Temporary == nil && u.EventBased == nil
+}
+
+// String describes the update for progress output, e.g. "temporary hold set".
+func (u *HoldUpdate) String() string {
+	var parts []string
+	add := func(name string, v *bool) {
+		switch {
+		case v == nil:
+		case *v:
+			parts = append(parts, name+" hold set")
+		default:
+			parts = append(parts, name+" hold cleared")
+		}
+	}
+	add("temporary", u.Temporary)
+	add("event-based", u.EventBased)
+	return strings.Join(parts, ", ")
+}
+
+// SetHolds sets or clears the temporary and event-based holds of a single
+// object, every object under a prefix (object ending in "/", requires
+// opts.Recursive), or every object matching a wildcard pattern, in parallel.
+// Each update is conditional on the object's metageneration.
+func SetHolds(ctx context.Context, client *storage.Client, bucket, object string, update *HoldUpdate, formatter PathFormatter, opts *SetMetaOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+	if opts == nil {
+		opts = &SetMetaOptions{}
+	}
+	if update.IsEmpty() {
+		return fmt.Errorf("no hold given (use --temporary or --event-based)")
+	}
+
+	toUpdate := storage.ObjectAttrsToUpdate{}
+	if update.Temporary != nil {
+		toUpdate.TemporaryHold = *update.Temporary
+	}
+	if update.EventBased != nil {
+		toUpdate.EventBasedHold = *update.EventBased
+	}
+	return updateObjects(ctx, client, bucket, object, update.String(), formatter, opts, "hold",
+		func(obj *storage.ObjectHandle) error {
+			apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", obj.BucketName(), obj.ObjectName())
+			attrs, err := obj.Attrs(ctx)
+			if err != nil {
+				return err
+			}
+			apilog.Logf("[GCS] Object.Update(gs://%s/%s, %s)", obj.BucketName(), obj.ObjectName(), update)
+			_, err = obj.If(storage.Conditions{MetagenerationMatch: attrs.Metageneration}).Update(ctx, toUpdate)
+			return err
+		})
+}
+
+// retainedUntil returns when the retention of an object ends: the later of
+// the bucket retention policy's expiry and the object's own retention, or
+// the zero time.
+func retainedUntil(attrs *storage.ObjectAttrs) time.Time {
+	until := attrs.RetentionExpirationTime
+	if r := attrs.Retention; r != nil && r.RetainUntil.After(until) {
+		until = r.RetainUntil
+	}
+	return until
+}
+
+// HoldLabel describes why an object cannot be deleted for listings:
+// "temporary hold", "event-based hold", "retained until <date>", or "" if
+// it can be deleted at now.
+func HoldLabel(temporary, eventBased bool, retainedUntil, now time.Time) string {
+	var parts []string
+	if temporary {
+		parts = append(parts, "temporary hold")
+	}
+	if eventBased {
+		parts = append(parts, "event-based hold")
+	}
+	if retainedUntil.After(now) {
+		parts = append(parts, "retained until "+retainedUntil.Local().Format("2006-01-02 15:04"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// HoldError reports an object that was not deleted or replaced because it
+// is under a hold or a retention policy.
+type HoldError struct {
+	Path   string // gs:// path of the object
+	Reason string // e.g. "temporary hold", see HoldLabel
+	err    error
+}
+
+func (e *HoldError) Error() string {
+	return fmt.Sprintf("%s is protected by %s", e.Path, e.Reason)
+}
+
+func (e *HoldError) Unwrap() error { return e.err }
+
+// holdError returns err as a *HoldError if GCS refused a delete or
+// overwrite of bucket/object because of a hold or retention, and err
+// unchanged otherwise. GCS only says so in the 403 message.
+func holdError(err error, bucket, object string) error {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		return err
+	}
+	msg := strings.ToLower(apiErr.Message)
+	var reason string
+	switch {
+	case strings.Contains(msg, "temporary hold"):
+		reason = "temporary hold"
+	case strings.Contains(msg, "event-based hold"):
+		reason = "event-based hold"
+	case strings.Contains(msg, "retention"):
+		reason = "retention policy"
+	default:
+		return err
+	}
+	return &HoldError{Path: fmt.Sprintf("gs://%s/%s", bucket, object), Reason: reason, err: err}
+}
+
+// checkHolds returns a *HoldError if the object described by attrs cannot
+// be deleted now, so it is not copied anywhere first (e.g. into the trash).
+func checkHolds(attrs *storage.ObjectAttrs) error {
+	if label := HoldLabel(attrs.TemporaryHold, attrs.EventBasedHold, retainedUntil(attrs), time.Now()); label != "" {
+		return &HoldError{Path: fmt.Sprintf("gs://%s/%s", attrs.Bucket, attrs.Name), Reason: label}
+	}
+	return nil
+}
+
+// holdHint tells how to get past a hold or retention.
+const holdHint = "release holds with 'cio hold clear'; 'cio info' shows when retention ends"
+
+// removeError wraps an error of a single-object remove, explaining holds.
+func removeError(action string, err error) error {
+	var held *HoldError
+	if errors.As(err, &held) {
+		return fmt.Errorf("%s: %w (%s)", action, held, holdHint)
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+)
+
+func TestHoldError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantReason string // "" when err must come back unchanged
+	}{
+		{
+			name:       "temporary hold",
+			err:        &googleapi.Error{Code: http.StatusForbidden, Message: "Object 'b/x' is under active Temporary hold and cannot be deleted, overwritten or archived until hold is removed."},
+			wantReason: "temporary hold",
+		},
+		{
+			name:       "event-based hold",
+			err:        &googleapi.Error{Code: http.StatusForbidden, Message: "Object 'b/x' is under active Event-Based hold and cannot be deleted, overwritten or archived until hold is removed."},
+			wantReason: "event-based hold",
+		},
+		{
+			name:       "retention policy",
+			err:        &googleapi.Error{Code: http.StatusForbidden, Message: "Object 'b/x' is subject to bucket's retention policy or object retention and cannot be deleted or overwritten until 2030-01-01T00:00:00Z"},
+			wantReason: "retention policy",
+		},
+		{
+			name:       "wrapped",
+			err:        errors.Join(errors.New("delete failed"), &googleapi.Error{Code: http.StatusForbidden, Message: "Object is under active temporary hold"}),
+			wantReason: "temporary hold",
+		},
+		{
+			name: "permission denied",
+			err:  &googleapi.Error{Code: http.StatusForbidden, Message: "agent@example.com does not have storage.objects.delete access to the Google Cloud Storage object."},
+		},
+		{
+			name: "hold wording on another status",
+			err:  &googleapi.Error{Code: http.StatusPreconditionFailed, Message: "temporary hold"},
+		},
+		{
+			name: "not an API error",
+			err:  errors.New("object under temporary hold"),
+		},
+		{
+			name: "nil",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := holdError(tt.err, "b", "x")
+			if tt.wantReason == "" {
+				if got != tt.err {
+					t.Errorf("holdError() = %v, want the error unchanged", got)
+				}
+				return
+			}
+			var held *HoldError
+			if !errors.As(got, &held) {
+				t.Fatalf("holdError() = %v, want a *HoldError", got)
+			}
+			if held.Path != "gs://b/x" || held.Reason != tt.wantReason {
+				t.Errorf("holdError() = %q, %q, want %q, %q", held.Path, held.Reason, "gs://b/x", tt.wantReason)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("holdError() does not wrap the API error")
+			}
+		})
+	}
+}
+
+func TestHoldLabel(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.Local)
+	later := now.Add(48 * time.Hour)
+	tests := []struct {
+		name                  string
+		temporary, eventBased bool
+		retainedUntil         time.Time
+		want                  string
+	}{
+		{name: "deletable"},
+		{name: "temporary", temporary: true, want: "temporary hold"},
+		{name: "event-based", eventBased: true, want: "event-based hold"},
+		{name: "retained", retainedUntil: later, want: "retained until 2026-06-03 12:00"},
+		{name: "retention expired", retainedUntil: now.Add(-time.Hour)},
+		{name: "everything", temporary: true, eventBased: true, retainedUntil: later, want: "temporary hold, event-based hold, retained until 2026-06-03 12:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HoldLabel(tt.temporary, tt.eventBased, tt.retainedUntil, now); got != tt.want {
+				t.Errorf("HoldLabel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetainedUntil(t *testing.T) {
+	policy := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	object := time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		attrs *storage.ObjectAttrs
+		want  time.Time
+	}{
+		{"none", &storage.ObjectAttrs{}, time.Time{}},
+		{"bucket policy", &storage.ObjectAttrs{RetentionExpirationTime: policy}, policy},
+		{"object retention", &storage.ObjectAttrs{Retention: &storage.ObjectRetention{RetainUntil: object}}, object},
+		{"later of both", &storage.ObjectAttrs{RetentionExpirationTime: object, Retention: &storage.ObjectRetention{RetainUntil: policy}}, object},
+	}
+	for _, tt := range tests {
+		if got := retainedUntil(tt.attrs); !got.Equal(tt.want) {
+			t.Errorf("%s: retainedUntil() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHolds(t *testing.T) {
+	if err := checkHolds(&storage.ObjectAttrs{Bucket: "b", Name: "x"}); err != nil {
+		t.Errorf("checkHolds() of a deletable object = %v, want nil", err)
+	}
+
+	err := checkHolds(&storage.ObjectAttrs{Bucket: "b", Name: "x", EventBasedHold: true})
+	var held *HoldError
+	if !errors.As(err, &held) || held.Path != "gs://b/x" || held.Reason != "event-based hold" {
+		t.Fatalf("checkHolds() = %v, want an event-based hold on gs://b/x", err)
+	}
+	if msg := removeError("failed to delete", err).Error(); !strings.Contains(msg, "cio hold clear") {
+		t.Errorf("removeError() = %q, want a hint on releasing holds", msg)
+	}
+	if msg := removeError("failed to delete", errors.New("boom")).Error(); msg != "failed to delete: boom" {
+		t.Errorf("removeError() = %q, want no hold hint", msg)
+	}
+}
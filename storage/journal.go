@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadJournalSuffix is appended to a destination path to name the sidecar
+// journal that records which chunks of a parallel download are complete.
+const DownloadJournalSuffix = ".cio-journal"
+
+// Written chunks are synced to disk and recorded in the journal in batches:
+// once journalSyncChunks are waiting or journalSyncInterval has passed since
+// the last sync. An interrupted download refetches at most one batch.
+const (
+	journalSyncChunks   = 16
+	journalSyncInterval = 5 * time.Second
+)
+
+// downloadJournal persists chunk completion state for a parallel chunked
+// download so an interrupted transfer can resume. It is keyed by object
+// generation: a journal written for one generation is never used to resume a
+// download of another, so data from two versions of an object is never spliced.
+type downloadJournal struct {
+	Object     string `json:"object"`
+	Generation int64  `json:"generation"`
+	Size       int64  `json:"size"`
+	ChunkSize  int64  `json:"chunk_size"`
+	Completed  []bool `json:"completed"`
+
+	path     string
+	mu       sync.Mutex
+	written  []int // chunks written to the file but not synced yet
+	lastSync time.Time
+}
+
+// journalPathFor returns the sidecar journal path for a destination file.
+func journalPathFor(localPath string) string {
+	return localPath + DownloadJournalSuffix
+}
+
+// isDownloadJournal reports whether path names a download journal sidecar.
+func isDownloadJournal(path string) bool {
+	return strings.HasSuffix(path, DownloadJournalSuffix)
+}
+
+// hasDownloadJournal reports whether an unfinished chunked download left a
+// journal next to localPath (the file itself is then pre-allocated but partial).
+func hasDownloadJournal(localPath string) bool {
+	_, err := os.Stat(journalPathFor(localPath))
+	return err == nil
+}
+
+// newDownloadJournal creates an empty journal for a fresh download.
+func newDownloadJournal(localPath, object string, generation, size, chunkSize int64, numChunks int) *downloadJournal {
+	return &downloadJournal{
+		Object:     object,
+		Generation: generation,
+		Size:       size,
+		ChunkSize:  chunkSize,
+		Completed:  make([]bool, numChunks),
+		path:       journalPathFor(localPath),
+	}
+}
+
+// loadDownloadJournal reads the journal next to localPath. It returns nil
+// without error when no journal exists.
+func loadDownloadJournal(localPath string) (*downloadJournal, error) {
+	path := journalPathFor(localPath)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read download journal: %w", err)
+	}
+
+	j := &downloadJournal{path: path}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("failed to parse download journal %s: %w", path, err)
+	}
+	return j, nil
+}
+
+// matches reports whether the journal describes exactly this download: same
+// object, same generation, same size and the same chunk layout.
+func (j *downloadJournal) matches(object string, generation, size, chunkSize int64, numChunks int) bool {
+	return j.Object == object &&
+		j.Generation == generation &&
+		j.Size == size &&
+		j.ChunkSize == chunkSize &&
+		len(j.Completed) == numChunks
+}
+
+// done reports whether chunk index was already completed.
+func (j *downloadJournal) done(index int) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.Completed[index]
+}
+
+// markWritten records that chunk index has been written to file. The chunk
+// is recorded as complete once file has been synced, which happens when a
+// batch is full (see journalSyncChunks) or by flush.
+func (j *downloadJournal) markWritten(file *os.File, index int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.lastSync.IsZero() {
+		j.lastSync = time.Now()
+	}
+	j.written = append(j.written, index)
+	if len(j.written) < journalSyncChunks && time.Since(j.lastSync) < journalSyncInterval {
+		return nil
+	}
+	return j.flushLocked(file)
+}
+
+// flush syncs file and records every chunk written so far as complete.
+func (j *downloadJournal) flush(file *os.File) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.flushLocked(file)
+}
+
+func (j *downloadJournal) flushLocked(file *os.File) error {
+	if len(j.written) == 0 {
+		return nil
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	for _, index := range j.written {
+		j.Completed[index] = true
+	}
+	j.written = j.written[:0]
+	j.lastSync = time.Now()
+	return j.saveLocked()
+}
+
+// save persists the journal.
+func (j *downloadJournal) save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.saveLocked()
+}
+
+// saveLocked writes the journal atomically (temp file + rename) so a crash
+// mid-write never leaves a truncated journal behind.
+func (j *downloadJournal) saveLocked() error {
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to encode download journal: %w", err)
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write download journal: %w", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to write download journal: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the journal once the download has completed.
+func (j *downloadJournal) remove() {
+	os.Remove(j.path)
+}
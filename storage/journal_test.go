@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadJournalMatches(t *testing.T) {
+	j := newDownloadJournal("/tmp/f", "gs://b/o", 7, 100, 30, 4)
+
+	tests := []struct {
+		name       string
+		object     string
+		generation int64
+		size       int64
+		chunkSize  int64
+		numChunks  int
+		want       bool
+	}{
+		{"same download", "gs://b/o", 7, 100, 30, 4, true},
+		{"other object", "gs://b/p", 7, 100, 30, 4, false},
+		{"new generation", "gs://b/o", 8, 100, 30, 4, false},
+		{"other size", "gs://b/o", 7, 101, 30, 4, false},
+		{"other chunk size", "gs://b/o", 7, 100, 50, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := j.matches(tt.object, tt.generation, tt.size, tt.chunkSize, tt.numChunks); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadJournalResume(t *testing.T) {
+	dir := t.TempDir()
+	localPath := filepath.Join(dir, "f")
+	file, err := os.Create(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	numChunks := journalSyncChunks + 3
+	j := newDownloadJournal(localPath, "gs://b/o", 7, int64(numChunks), 1, numChunks)
+	if err := j.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A full batch is synced and journaled; the rest waits for flush
+	for i := 0; i < journalSyncChunks+1; i++ {
+		if err := j.markWritten(file, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loaded, err := loadDownloadJournal(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := countDone(loaded, numChunks); got != journalSyncChunks {
+		t.Errorf("after one batch: %d chunks done, want %d", got, journalSyncChunks)
+	}
+
+	if err := j.flush(file); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = loadDownloadJournal(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.matches("gs://b/o", 7, int64(numChunks), 1, numChunks) {
+		t.Fatalf("reloaded journal does not match: %+v", loaded)
+	}
+	if got := countDone(loaded, numChunks); got != journalSyncChunks+1 {
+		t.Errorf("after flush: %d chunks done, want %d", got, journalSyncChunks+1)
+	}
+	if loaded.done(numChunks - 1) {
+		t.Error("unwritten chunk recorded as done")
+	}
+
+	j.remove()
+	if loaded, err := loadDownloadJournal(localPath); err != nil || loaded != nil {
+		t.Errorf("after remove: %v, %v; want no journal", loaded, err)
+	}
+}
+
+func countDone(j *downloadJournal, numChunks int) int {
+	n := 0
+	for i := 0; i < numChunks; i++ {
+		if j.done(i) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"gopkg.in/yaml.v3"
+)
+
+// LifecycleConfig is the YAML/JSON file format for bucket lifecycle rules:
+//
+//	rules:
+//	  - action: SetStorageClass
+//	    storage_class: NEARLINE
+//	    condition:
+//	      age: 30
+//	      prefix: [logs/]
+//	  - action: Delete
+//	    condition:
+//	      age: 365
+//
+// JSON files use the same keys.
+type LifecycleConfig struct {
+	Rules []LifecycleRule `yaml:"rules" json:"rules"`
+}
+
+// LifecycleRule is a single lifecycle rule: an action and the conditions an
+// object must meet for it to apply.
+type LifecycleRule struct {
+	// Action is Delete, SetStorageClass or AbortIncompleteMultipartUpload
+	Action string `yaml:"action" json:"action"`
+	// StorageClass is the target class of a SetStorageClass action
+	StorageClass string             `yaml:"storage_class,omitempty" json:"storage_class,omitempty"`
+	Condition    LifecycleCondition `yaml:"condition" json:"condition"`
+}
+
+// LifecycleCondition selects the objects a rule applies to. All set
+// conditions must match.
+type LifecycleCondition struct {
+	Age                 *int64   `yaml:"age,omitempty" json:"age,omitempty"`                                     // days since creation
+	CreatedBefore       string   `yaml:"created_before,omitempty" json:"created_before,omitempty"`               // YYYY-MM-DD
+	Prefix              []string `yaml:"prefix,omitempty" json:"prefix,omitempty"`                               // any of these name prefixes
+	Suffix              []string `yaml:"suffix,omitempty" json:"suffix,omitempty"`                               // any of these name suffixes
+	StorageClass        []string `yaml:"storage_class,omitempty" json:"storage_class,omitempty"`                 // any of these current classes
+	Live                *bool    `yaml:"live,omitempty" json:"live,omitempty"`                                   // live (true) or noncurrent (false) versions
+	NumNewerVersions    int64    `yaml:"num_newer_versions,omitempty" json:"num_newer_versions,omitempty"`       // versioned buckets
+	DaysSinceNoncurrent int64    `yaml:"days_since_noncurrent,omitempty" json:"days_since_noncurrent,omitempty"` // versioned buckets
+	DaysSinceCustomTime int64    `yaml:"days_since_custom_time,omitempty" json:"days_since_custom_time,omitempty"`
+}
+
+// ParseLifecycleConfig parses lifecycle rules from YAML or JSON and validates
+// them.
+func ParseLifecycleConfig(data []byte) ([]LifecycleRule, error) {
+	var cfg LifecycleConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse lifecycle rules: %w", err)
+	}
+	if len(cfg.Rules) == 0 {
+		return nil, fmt.Errorf("no lifecycle rules found (expected a top-level 'rules' list)")
+	}
+	for i := range cfg.Rules {
+		if _, err := cfg.Rules[i].toGCS(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return cfg.Rules, nil
+}
+
+// toGCS converts the rule to the client library representation, validating
+// and normalizing action and storage class names.
+func (r *LifecycleRule) toGCS() (storage.LifecycleRule, error) {
+	var rule storage.LifecycleRule
+
+	switch strings.ToLower(r.Action) {
+	case "delete":
+		rule.Action.Type = storage.DeleteAction
+	case "setstorageclass":
+		rule.Action.Type = storage.SetStorageClassAction
+		class, err := ValidateStorageClass(r.StorageClass)
+		if err != nil {
+			return rule, err
+		}
+		rule.Action.StorageClass = class
+	case "abortincompletemultipartupload":
+		rule.Action.Type = storage.AbortIncompleteMPUAction
+	default:
+		return rule, fmt.Errorf("unknown action %q (use Delete, SetStorageClass or AbortIncompleteMultipartUpload)", r.Action)
+	}
+	if rule.Action.Type != storage.SetStorageClassAction && r.StorageClass != "" {
+		return rule, fmt.Errorf("storage_class is only valid for SetStorageClass")
+	}
+
+	c := r.Condition
+	if c.Age != nil {
+		rule.Condition.AgeInDays = *c.Age
+		// age 0 would otherwise be indistinguishable from "no age condition"
+		rule.Condition.AllObjects = *c.Age == 0
+	}
+	if c.CreatedBefore != "" {
+		t, err := time.Parse("2006-01-02", c.CreatedBefore)
+		if err != nil {
+			return rule, fmt.Errorf("invalid created_before %q (expected YYYY-MM-DD)", c.CreatedBefore)
+		}
+		rule.Condition.CreatedBefore = t
+	}
+	rule.Condition.MatchesPrefix = c.Prefix
+	rule.Condition.MatchesSuffix = c.Suffix
+	for _, class := range c.StorageClass {
+		// Lifecycle conditions also accept the legacy regional class names
+		name := strings.ToUpper(class)
+		if name != "MULTI_REGIONAL" && name != "REGIONAL" && name != "DURABLE_REDUCED_AVAILABILITY" {
+			var err error
+			if name, err = ValidateStorageClass(class); err != nil {
+				return rule, err
+			}
+		}
+		rule.Condition.MatchesStorageClasses = append(rule.Condition.MatchesStorageClasses, name)
+	}
+	if c.Live != nil {
+		rule.Condition.Liveness = storage.Archived
+		if *c.Live {
+			rule.Condition.Liveness = storage.Live
+		}
+	}
+	rule.Condition.NumNewerVersions = c.NumNewerVersions
+	rule.Condition.DaysSinceNoncurrentTime = c.DaysSinceNoncurrent
+	rule.Condition.DaysSinceCustomTime = c.DaysSinceCustomTime
+	return rule, nil
+}
+
+// lifecycleRuleFrom converts a client library rule to the file format.
+func lifecycleRuleFrom(rule storage.LifecycleRule) LifecycleRule {
+	r := LifecycleRule{Action: rule.Action.Type, StorageClass: rule.Action.StorageClass}
+	c := rule.Condition
+	if c.AgeInDays > 0 || c.AllObjects {
+		age := c.AgeInDays
+		r.Condition.Age = &age
+	}
+	if !c.CreatedBefore.IsZero() {
+		r.Condition.CreatedBefore = c.CreatedBefore.Format("2006-01-02")
+	}
+	r.Condition.Prefix = c.MatchesPrefix
+	r.Condition.Suffix = c.MatchesSuffix
+	r.Condition.StorageClass = c.MatchesStorageClasses
+	switch c.Liveness {
+	case storage.Live:
+		live := true
+		r.Condition.Live = &live
+	case storage.Archived:
+		live := false
+		r.Condition.Live = &live
+	}
+	r.Condition.NumNewerVersions = c.NumNewerVersions
+	r.Condition.DaysSinceNoncurrent = c.DaysSinceNoncurrentTime
+	r.Condition.DaysSinceCustomTime = c.DaysSinceCustomTime
+	return r
+}
+
+// Describe returns the rule's action and a one-line summary of its
+// conditions, e.g. ("SetStorageClass NEARLINE", "age >= 30d, prefix logs/").
+func (r *LifecycleRule) Describe() (action, conditions string) {
+	action = r.Action
+	if r.StorageClass != "" {
+		action += " " + r.StorageClass
+	}
+
+	c := r.Condition
+	var parts []string
+	if c.Age != nil {
+		parts = append(parts, fmt.Sprintf("age >= %dd", *c.Age))
+	}
+	if c.CreatedBefore != "" {
+		parts = append(parts, "created before "+c.CreatedBefore)
+	}
+	if len(c.Prefix) > 0 {
+		parts = append(parts, "prefix "+strings.Join(c.Prefix, "|"))
+	}
+	if len(c.Suffix) > 0 {
+		parts = append(parts, "suffix "+strings.Join(c.Suffix, "|"))
+	}
+	if len(c.StorageClass) > 0 {
+		parts = append(parts, "class "+strings.Join(c.StorageClass, "|"))
+	}
+	if c.Live != nil {
+		if *c.Live {
+			parts = append(parts, "live")
+		} else {
+			parts = append(parts, "noncurrent")
+		}
+	}
+	if c.NumNewerVersions > 0 {
+		parts = append(parts, fmt.Sprintf("%d+ newer versions", c.NumNewerVersions))
+	}
+	if c.DaysSinceNoncurrent > 0 {
+		parts = append(parts, fmt.Sprintf("noncurrent >= %dd", c.DaysSinceNoncurrent))
+	}
+	if c.DaysSinceCustomTime > 0 {
+		parts = append(parts, fmt.Sprintf("custom time >= %dd", c.DaysSinceCustomTime))
+	}
+	if len(parts) == 0 {
+		return action, "(all objects)"
+	}
+	return action, strings.Join(parts, ", ")
+}
+
+// GetLifecycleRules returns the bucket's lifecycle rules.
+func GetLifecycleRules(ctx context.Context, client *storage.Client, bucket string) ([]LifecycleRule, error) {
+	apilog.Logf("[GCS] Bucket.Attrs(%s)", bucket)
+	attrs, err := client.Bucket(bucket).Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket gs://%s: %w", bucket, err)
+	}
+	rules := make([]LifecycleRule, len(attrs.Lifecycle.Rules))
+	for i, rule := range attrs.Lifecycle.Rules {
+		rules[i] = lifecycleRuleFrom(rule)
+	}
+	return rules, nil
+}
+
+// SetLifecycleRules replaces the bucket's lifecycle rules; no rules clears
+// them.
+func SetLifecycleRules(ctx context.Context, client *storage.Client, bucket string, rules []LifecycleRule) error {
+	return updateLifecycle(ctx, client, bucket, func([]storage.LifecycleRule) ([]storage.LifecycleRule, error) {
+		return lifecycleRulesToGCS(rules)
+	})
+}
+
+// AddLifecycleRules appends rules to the bucket's lifecycle configuration.
+func AddLifecycleRules(ctx context.Context, client *storage.Client, bucket string, rules []LifecycleRule) error {
+	return updateLifecycle(ctx, client, bucket, func(current []storage.LifecycleRule) ([]storage.LifecycleRule, error) {
+		added, err := lifecycleRulesToGCS(rules)
+		if err != nil {
+			return nil, err
+		}
+		return append(current, added...), nil
+	})
+}
+
+// RemoveLifecycleRules removes the rules at the given indexes (as listed by
+// GetLifecycleRules) from the bucket's lifecycle configuration.
+func RemoveLifecycleRules(ctx context.Context, client *storage.Client, bucket string, indexes []int) error {
+	return updateLifecycle(ctx, client, bucket, func(current []storage.LifecycleRule) ([]storage.LifecycleRule, error) {
+		remove := make(map[int]bool, len(indexes))
+		for _, i := range indexes {
+			if i < 0 || i >= len(current) {
+				return nil, fmt.Errorf("no lifecycle rule %d (bucket has %d rule(s))", i, len(current))
+			}
+			remove[i] = true
+		}
+		var kept []storage.LifecycleRule
+		for i, rule := range current {
+			if !remove[i] {
+				kept = append(kept, rule)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// updateLifecycle applies change to the bucket's current rules and writes the
+// result, conditional on the bucket's metageneration so that concurrent edits
+// are not lost.
+func updateLifecycle(ctx context.Context, client *storage.Client, bucket string, change func([]storage.LifecycleRule) ([]storage.LifecycleRule, error)) error {
+	bkt := client.Bucket(bucket)
+	apilog.Logf("[GCS] Bucket.Attrs(%s)", bucket)
+	attrs, err := bkt.Attrs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get bucket gs://%s: %w", bucket, err)
+	}
+
+	rules, err := change(attrs.Lifecycle.Rules)
+	if err != nil {
+		return err
+	}
+
+	apilog.Logf("[GCS] Bucket.Update(%s, lifecycle rules=%d)", bucket, len(rules))
+	_, err = bkt.If(storage.BucketConditions{MetagenerationMatch: attrs.MetaGeneration}).
+		Update(ctx, storage.BucketAttrsToUpdate{Lifecycle: &storage.Lifecycle{Rules: rules}})
+	if err != nil {
+		return fmt.Errorf("failed to update lifecycle of gs://%s: %w", bucket, err)
+	}
+	return nil
+}
+
+func lifecycleRulesToGCS(rules []LifecycleRule) ([]storage.LifecycleRule, error) {
+	out := make([]storage.LifecycleRule, 0, len(rules))
+	for i := range rules {
+		rule, err := rules[i].toGCS()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+)
+
+func TestParseLifecycleConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		actions []string
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			data: `rules:
+  - action: SetStorageClass
+    storage_class: nearline
+    condition:
+      age: 30
+      prefix: [logs/]
+  - action: Delete
+    condition:
+      age: 365
+`,
+			actions: []string{storage.SetStorageClassAction, storage.DeleteAction},
+		},
+		{
+			name:    "json",
+			data:    `{"rules": [{"action": "delete", "condition": {"created_before": "2024-01-01"}}]}`,
+			actions: []string{storage.DeleteAction},
+		},
+		{
+			name:    "legacy storage class condition",
+			data:    `{"rules": [{"action": "Delete", "condition": {"storage_class": ["REGIONAL"]}}]}`,
+			actions: []string{storage.DeleteAction},
+		},
+		{
+			name:    "no rules",
+			data:    `rules: []`,
+			wantErr: "no lifecycle rules found",
+		},
+		{
+			name:    "invalid syntax",
+			data:    `rules: [`,
+			wantErr: "failed to parse lifecycle rules",
+		},
+		{
+			name:    "unknown action",
+			data:    `{"rules": [{"action": "Archive"}]}`,
+			wantErr: `rule 0: unknown action "Archive"`,
+		},
+		{
+			name:    "invalid target class",
+			data:    `{"rules": [{"action": "SetStorageClass", "storage_class": "COLD"}]}`,
+			wantErr: `invalid storage class "COLD"`,
+		},
+		{
+			name:    "storage class on delete",
+			data:    `{"rules": [{"action": "Delete", "storage_class": "NEARLINE"}]}`,
+			wantErr: "storage_class is only valid for SetStorageClass",
+		},
+		{
+			name:    "invalid created_before",
+			data:    `{"rules": [{"action": "Delete"}, {"action": "Delete", "condition": {"created_before": "01/02/2024"}}]}`,
+			wantErr: `rule 1: invalid created_before "01/02/2024"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseLifecycleConfig([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rules) != len(tt.actions) {
+				t.Fatalf("got %d rules, want %d", len(rules), len(tt.actions))
+			}
+			for i := range rules {
+				rule, err := rules[i].toGCS()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rule.Action.Type != tt.actions[i] {
+					t.Errorf("rule %d action = %q, want %q", i, rule.Action.Type, tt.actions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLifecycleRuleToGCS(t *testing.T) {
+	age := int64(0)
+	live := false
+	rule := LifecycleRule{
+		Action:       "setstorageclass",
+		StorageClass: "coldline",
+		Condition: LifecycleCondition{
+			Age:          &age,
+			StorageClass: []string{"standard", "multi_regional"},
+			Live:         &live,
+		},
+	}
+	got, err := rule.toGCS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Action.StorageClass != "COLDLINE" {
+		t.Errorf("StorageClass = %q, want COLDLINE", got.Action.StorageClass)
+	}
+	if !got.Condition.AllObjects {
+		t.Error("age 0: AllObjects = false, want true")
+	}
+	if classes := strings.Join(got.Condition.MatchesStorageClasses, ","); classes != "STANDARD,MULTI_REGIONAL" {
+		t.Errorf("MatchesStorageClasses = %s, want STANDARD,MULTI_REGIONAL", classes)
+	}
+	if got.Condition.Liveness != storage.Archived {
+		t.Errorf("Liveness = %v, want Archived", got.Condition.Liveness)
+	}
+}
@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"cloud.google.com/go/storage"
 	"github.com/thieso2/cio/apilog"
@@ -16,7 +18,8 @@ type ListOptions struct {
 	LongFormat    bool // Show detailed information
 	HumanReadable bool // Show sizes in human-readable format
 	Delimiter     string
-	MaxResults    int // Maximum number of results (0 = no limit)
+	MaxResults    int  // Maximum number of results (0 = no limit)
+	Versions      bool // Include noncurrent object versions
 }
 
 // DefaultListOptions returns the default listing options
@@ -30,20 +33,61 @@ func DefaultListOptions() *ListOptions {
 	}
 }
 
-// List retrieves objects from a GCS bucket with optional prefix
-func List(ctx context.Context, bucket, prefix string, opts *ListOptions) ([]*ObjectInfo, error) {
-	client, err := GetClient(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create storage client: %w", err)
+// ListFunc receives the objects and prefixes of a streaming listing as the
+// pages arrive. Returning an error stops the listing; the error is passed
+// back to the caller.
+type ListFunc func(obj *ObjectInfo) error
+
+// errListLimit stops a listing once MaxResults entries were delivered.
+var errListLimit = errors.New("list limit reached")
+
+// limitListFunc wraps fn to deliver at most max entries (0 = no limit).
+// The wrapper is not safe for concurrent use.
+func limitListFunc(max int, fn ListFunc) ListFunc {
+	if max <= 0 {
+		return fn
 	}
+	count := 0
+	return func(obj *ObjectInfo) error {
+		if count >= max {
+			return errListLimit
+		}
+		count++
+		if err := fn(obj); err != nil {
+			return err
+		}
+		if count >= max {
+			return errListLimit
+		}
+		return nil
+	}
+}
 
+// ListEach lists objects from a GCS bucket with optional prefix, calling fn
+// for each object and prefix in listing order. Unlike List it holds only the
+// current page in memory, so output can start before the listing ends.
+func ListEach(ctx context.Context, bucket, prefix string, opts *ListOptions, fn ListFunc) error {
 	if opts == nil {
 		opts = DefaultListOptions()
 	}
+	err := listEach(ctx, bucket, prefix, opts, limitListFunc(opts.MaxResults, fn))
+	if err == errListLimit {
+		return nil
+	}
+	return err
+}
+
+// listEach is ListEach without MaxResults handling.
+func listEach(ctx context.Context, bucket, prefix string, opts *ListOptions, fn ListFunc) error {
+	client, err := GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create storage client: %w", err)
+	}
 
 	// Configure query
 	query := &storage.Query{
-		Prefix: prefix,
+		Prefix:   prefix,
+		Versions: opts.Versions,
 	}
 
 	// If not recursive, use delimiter to group by "directories"
@@ -53,38 +97,41 @@ func List(ctx context.Context, bucket, prefix string, opts *ListOptions) ([]*Obj
 
 	// Execute query
 	bucketHandle := client.Bucket(bucket)
-	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q, recursive=%v)", bucket, query.Prefix, opts.Recursive)
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q, recursive=%v, versions=%v)", bucket, query.Prefix, opts.Recursive, opts.Versions)
 	it := bucketHandle.Objects(ctx, query)
 
-	var results []*ObjectInfo
-	count := 0
-
 	for {
-		// Check if we've reached the max results limit
-		if opts.MaxResults > 0 && count >= opts.MaxResults {
-			break
-		}
-
 		attrs, err := it.Next()
 		if err == iterator.Done {
-			break
+			return nil
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to iterate objects: %w", err)
+			return fmt.Errorf("failed to iterate objects: %w", err)
 		}
 
-		// Handle prefixes (directories)
+		// Handle prefixes (directories) and objects
+		var info *ObjectInfo
 		if attrs.Prefix != "" {
-			results = append(results, CreatePrefixInfo(attrs.Prefix, bucket))
-			count++
-			continue
+			info = CreatePrefixInfo(attrs.Prefix, bucket)
+		} else {
+			info = CreateObjectInfo(attrs, bucket)
+		}
+		if err := fn(info); err != nil {
+			return err
 		}
-
-		// Handle objects
-		results = append(results, CreateObjectInfo(attrs, bucket))
-		count++
 	}
+}
 
+// List retrieves objects from a GCS bucket with optional prefix
+func List(ctx context.Context, bucket, prefix string, opts *ListOptions) ([]*ObjectInfo, error) {
+	var results []*ObjectInfo
+	err := ListEach(ctx, bucket, prefix, opts, func(obj *ObjectInfo) error {
+		results = append(results, obj)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return results, nil
 }
 
@@ -119,10 +166,33 @@ const maxParallelRecursiveLists = 8
 //	"**.csv.zst"            – parallel recursive from root, matches any depth
 //	"*/exports/**.csv.zst"  – expands top-level dirs, then parallel recursive per dir
 func ListWithPattern(ctx context.Context, bucket, pattern string, opts *ListOptions) ([]*ObjectInfo, error) {
+	var results []*ObjectInfo
+	err := ListWithPatternEach(ctx, bucket, pattern, opts, func(obj *ObjectInfo) error {
+		results = append(results, obj)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ListWithPatternEach is the streaming form of ListWithPattern: fn is called
+// for each match as it is found. Matches of a ** pattern arrive from parallel
+// listings in no particular order; fn is never called concurrently.
+func ListWithPatternEach(ctx context.Context, bucket, pattern string, opts *ListOptions, fn ListFunc) error {
 	if opts == nil {
 		opts = DefaultListOptions()
 	}
+	err := listWithPattern(ctx, bucket, pattern, opts, limitListFunc(opts.MaxResults, fn))
+	if err == errListLimit {
+		return nil
+	}
+	return err
+}
 
+// listWithPattern is ListWithPatternEach without MaxResults handling.
+func listWithPattern(ctx context.Context, bucket, pattern string, opts *ListOptions, fn ListFunc) error {
 	segments := strings.Split(pattern, "/")
 
 	// Find the first segment that contains **.
@@ -158,46 +228,41 @@ func ListWithPattern(ctx context.Context, bucket, pattern string, opts *ListOpti
 		for _, prefix := range prefixes {
 			dirs, err := listDirsMatchingSegment(ctx, bucket, prefix, seg, opts)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			next = append(next, dirs...)
 		}
 		prefixes = next
 		if len(prefixes) == 0 {
-			return nil, nil
+			return nil
 		}
 	}
 
 	// ** path: fan out parallel recursive listings from each anchor prefix.
 	if doubleStarIdx != -1 {
 		suffixPattern := strings.Join(segments[doubleStarIdx:], "/")
-		return listParallelDoubleStarPattern(ctx, bucket, prefixes, suffixPattern, opts)
+		return listParallelDoubleStarPattern(ctx, bucket, prefixes, suffixPattern, opts, fn)
 	}
 
 	// Single-* path: expand the last segment across all active prefixes.
 	lastSeg := segments[len(segments)-1]
-	var results []*ObjectInfo
 
 	// If the pattern ended with "/" the last segment is empty — the directories
 	// found during intermediate expansion ARE the results.
 	if lastSeg == "" {
 		for _, prefix := range prefixes {
-			results = append(results, CreatePrefixInfo(prefix, bucket))
-		}
-	} else {
-		for _, prefix := range prefixes {
-			objs, err := listMatchingLastSegment(ctx, bucket, prefix, lastSeg, opts)
-			if err != nil {
-				return nil, err
+			if err := fn(CreatePrefixInfo(prefix, bucket)); err != nil {
+				return err
 			}
-			results = append(results, objs...)
 		}
+		return nil
 	}
-
-	if opts.MaxResults > 0 && len(results) > opts.MaxResults {
-		results = results[:opts.MaxResults]
+	for _, prefix := range prefixes {
+		if err := listMatchingLastSegment(ctx, bucket, prefix, lastSeg, opts, fn); err != nil {
+			return err
+		}
 	}
-	return results, nil
+	return nil
 }
 
 // listParallelDoubleStarPattern issues one recursive GCS listing per anchor
@@ -207,129 +272,123 @@ func ListWithPattern(ctx context.Context, bucket, pattern string, opts *ListOpti
 // Anchors are listed in parallel, capped at maxParallelRecursiveLists goroutines.
 // For a single anchor (e.g. **.csv.zst → anchor "") this becomes one API call.
 // For multiple anchors (e.g. */exports/**.csv.zst → one anchor per matched dir)
-// the parallel goroutines provide real concurrency.
-func listParallelDoubleStarPattern(ctx context.Context, bucket string, anchors []string, pattern string, opts *ListOptions) ([]*ObjectInfo, error) {
-	type chanResult struct {
-		objects []*ObjectInfo
-		err     error
-	}
-
-	results := make(chan chanResult, len(anchors))
+// the parallel goroutines provide real concurrency. Calls to fn are
+// serialized; once fn returns an error, the other listings stop too.
+func listParallelDoubleStarPattern(ctx context.Context, bucket string, anchors []string, pattern string, opts *ListOptions, fn ListFunc) error {
+	errs := make(chan error, len(anchors))
 	sem := make(chan struct{}, maxParallelRecursiveLists)
 
+	var mu sync.Mutex
+	var stopErr error
+	emit := func(obj *ObjectInfo) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopErr != nil {
+			return stopErr
+		}
+		stopErr = fn(obj)
+		return stopErr
+	}
+	stopped := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return stopErr
+	}
+
 	for _, anchor := range anchors {
 		go func(anchor string) {
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			all, err := List(ctx, bucket, anchor, &ListOptions{
+			if err := stopped(); err != nil {
+				errs <- err
+				return
+			}
+			errs <- listEach(ctx, bucket, anchor, &ListOptions{
 				Recursive:     true,
 				LongFormat:    opts.LongFormat,
 				HumanReadable: opts.HumanReadable,
-			})
-			if err != nil {
-				results <- chanResult{err: err}
-				return
-			}
-
-			var matched []*ObjectInfo
-			for _, obj := range all {
+				Versions:      opts.Versions,
+			}, func(obj *ObjectInfo) error {
 				if obj.IsPrefix {
-					continue
+					return nil
 				}
 				relPath := strings.TrimPrefix(obj.Path, "gs://"+bucket+"/"+anchor)
-				if doubleStarMatchPath(relPath, pattern) {
-					matched = append(matched, obj)
+				if !doubleStarMatchPath(relPath, pattern) {
+					return nil
 				}
-			}
-			results <- chanResult{objects: matched}
+				return emit(obj)
+			})
 		}(anchor)
 	}
 
-	var combined []*ObjectInfo
 	var firstErr error
 	for range len(anchors) {
-		r := <-results
-		if r.err != nil && firstErr == nil {
-			firstErr = r.err
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
 		}
-		combined = append(combined, r.objects...)
-	}
-	if firstErr != nil {
-		return nil, firstErr
-	}
-
-	if opts.MaxResults > 0 && len(combined) > opts.MaxResults {
-		combined = combined[:opts.MaxResults]
 	}
-	return combined, nil
+	return firstErr
 }
 
 // listDirsMatchingSegment lists one level below prefix (non-recursive) and
 // returns the GCS prefixes of directories whose name matches seg.
 func listDirsMatchingSegment(ctx context.Context, bucket, prefix, seg string, opts *ListOptions) ([]string, error) {
-	objects, err := List(ctx, bucket, prefix, &ListOptions{
+	var dirs []string
+	err := listEach(ctx, bucket, prefix, &ListOptions{
 		Recursive: false, Delimiter: "/",
 		LongFormat: opts.LongFormat, HumanReadable: opts.HumanReadable,
-	})
-	if err != nil {
-		return nil, err
-	}
-	var dirs []string
-	for _, obj := range objects {
+	}, func(obj *ObjectInfo) error {
 		if !obj.IsPrefix {
-			continue
+			return nil
 		}
 		name := relSegmentName(bucket, prefix, obj)
 		if complexWildcardMatch(name, seg) {
 			dirs = append(dirs, strings.TrimPrefix(obj.Path, "gs://"+bucket+"/"))
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return dirs, nil
 }
 
 // listMatchingLastSegment lists objects at prefix (non-recursive by default,
-// recursive when opts.Recursive is set) and returns those whose name matches seg.
-func listMatchingLastSegment(ctx context.Context, bucket, prefix, seg string, opts *ListOptions) ([]*ObjectInfo, error) {
+// recursive when opts.Recursive is set) and passes those whose name matches
+// seg to fn.
+func listMatchingLastSegment(ctx context.Context, bucket, prefix, seg string, opts *ListOptions, fn ListFunc) error {
 	if opts.Recursive {
 		// Recursive: flat list under prefix, match the filename portion only.
-		all, err := List(ctx, bucket, prefix, &ListOptions{
-			Recursive: true,
+		return listEach(ctx, bucket, prefix, &ListOptions{
+			Recursive:  true,
 			LongFormat: opts.LongFormat, HumanReadable: opts.HumanReadable,
-		})
-		if err != nil {
-			return nil, err
-		}
-		var results []*ObjectInfo
-		for _, obj := range all {
+			Versions: opts.Versions,
+		}, func(obj *ObjectInfo) error {
 			name := relSegmentName(bucket, prefix, obj)
 			// For recursive results spanning multiple levels take only the leaf name.
 			if idx := strings.LastIndex(name, "/"); idx >= 0 {
 				name = name[idx+1:]
 			}
-			if complexWildcardMatch(name, seg) {
-				results = append(results, obj)
+			if !complexWildcardMatch(name, seg) {
+				return nil
 			}
-		}
-		return results, nil
+			return fn(obj)
+		})
 	}
 
 	// Non-recursive: list one level, filter by seg.
-	all, err := List(ctx, bucket, prefix, &ListOptions{
+	return listEach(ctx, bucket, prefix, &ListOptions{
 		Recursive: false, Delimiter: "/",
 		LongFormat: opts.LongFormat, HumanReadable: opts.HumanReadable,
-	})
-	if err != nil {
-		return nil, err
-	}
-	var results []*ObjectInfo
-	for _, obj := range all {
+		Versions: opts.Versions,
+	}, func(obj *ObjectInfo) error {
 		name := relSegmentName(bucket, prefix, obj)
-		if complexWildcardMatch(name, seg) {
-			results = append(results, obj)
+		if !complexWildcardMatch(name, seg) {
+			return nil
 		}
-	}
-	return results, nil
+		return fn(obj)
+	})
 }
 
 // relSegmentName returns the single path segment for obj relative to prefix.
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestLimitListFunc(t *testing.T) {
+	errStop := errors.New("stop")
+	tests := []struct {
+		name      string
+		max       int
+		entries   int
+		failAt    int // fn fails on this entry (1-based), 0 = never
+		wantCalls int
+		wantErr   error
+	}{
+		{"no limit", 0, 5, 0, 5, nil},
+		{"under the limit", 10, 3, 0, 3, nil},
+		{"stops at the limit", 3, 5, 0, 3, errListLimit},
+		{"limit of one", 1, 5, 0, 1, errListLimit},
+		{"callback error", 3, 5, 2, 2, errStop},
+		{"callback error without limit", 0, 5, 4, 4, errStop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			fn := limitListFunc(tt.max, func(obj *ObjectInfo) error {
+				calls++
+				if calls == tt.failAt {
+					return errStop
+				}
+				return nil
+			})
+
+			// Feed entries the way listEach does: stop at the first error
+			var err error
+			for i := 0; i < tt.entries && err == nil; i++ {
+				err = fn(&ObjectInfo{Path: fmt.Sprintf("gs://b/%d", i)})
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("callback ran %d times, want %d", calls, tt.wantCalls)
+			}
+			if err != tt.wantErr {
+				t.Errorf("listing stopped with %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
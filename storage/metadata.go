@@ -0,0 +1,558 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
+	"google.golang.org/api/iterator"
+)
+
+// customMetaHeaderPrefix is the gsutil-style header prefix for custom metadata
+// (e.g. "x-goog-meta-owner: data-team").
+const customMetaHeaderPrefix = "x-goog-meta-"
+
+// MetadataUpdate describes changes to an object's editable metadata. Nil
+// fields are left unchanged; a field set to "" clears it.
+type MetadataUpdate struct {
+	ContentType        *string
+	ContentEncoding    *string
+	ContentLanguage    *string
+	ContentDisposition *string
+	CacheControl       *string
+	// Metadata holds custom key/value pairs to set; an empty value removes the key
+	Metadata map[string]string
+}
+
+// SetHeader applies a "Name: value" header such as "Content-Type: text/csv"
+// or "x-goog-meta-owner: data-team". An empty value clears the field.
+func (u *MetadataUpdate) SetHeader(header string) error {
+	name, value, ok := strings.Cut(header, ":")
+	if !ok {
+		return fmt.Errorf("invalid header %q (expected \"Name: value\")", header)
+	}
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+	lower := strings.ToLower(name)
+
+	if strings.HasPrefix(lower, customMetaHeaderPrefix) && len(name) > len(customMetaHeaderPrefix) {
+		u.setMeta(name[len(customMetaHeaderPrefix):], value)
+		return nil
+	}
+
+	switch lower {
+	case "content-type":
+		u.ContentType = &value
+	case "content-encoding":
+		u.ContentEncoding = &value
+	case "content-language":
+		u.ContentLanguage = &value
+	case "content-disposition":
+		u.ContentDisposition = &value
+	case "cache-control":
+		u.CacheControl = &value
+	default:
+		return fmt.Errorf("unsupported header %q (use Content-Type, Content-Encoding, Content-Language, Content-Disposition, Cache-Control or x-goog-meta-*)", name)
+	}
+	return nil
+}
+
+// SetMeta applies a custom "key=value" pair. "key=" removes the key.
+func (u *MetadataUpdate) SetMeta(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return fmt.Errorf("invalid metadata %q (expected key=value)", pair)
+	}
+	u.setMeta(key, value)
+	return nil
+}
+
+func (u *MetadataUpdate) setMeta(key, value string) {
+	if u.Metadata == nil {
+		u.Metadata = make(map[string]string)
+	}
+	u.Metadata[key] = value
+}
+
+// IsEmpty reports whether the update changes nothing.
+func (u *MetadataUpdate) IsEmpty() bool {
+	return u == nil || (u.ContentType == nil && u.ContentEncoding == nil && u.ContentLanguage == nil &&
+		u.ContentDisposition == nil && u.CacheControl == nil && len(u.Metadata) == 0)
+}
+
+// String describes the update for previews, e.g.
+// "Content-Type=text/csv, owner=data-team, -stale".
+func (u *MetadataUpdate) String() string {
+	var parts []string
+	add := func(name string, v *string) {
+		if v != nil {
+			parts = append(parts, name+"="+*v)
+		}
+	}
+	add("Content-Type", u.ContentType)
+	add("Content-Encoding", u.ContentEncoding)
+	add("Content-Language", u.ContentLanguage)
+	add("Content-Disposition", u.ContentDisposition)
+	add("Cache-Control", u.CacheControl)
+	for _, k := range sortedKeys(u.Metadata) {
+		if u.Metadata[k] == "" {
+			parts = append(parts, "-"+k)
+		} else {
+			parts = append(parts, k+"="+u.Metadata[k])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// applyTo sets the update's fields on attrs for a new object (upload or
+// compose). Custom keys are merged into any metadata already on attrs.
+func (u *MetadataUpdate) applyTo(attrs *storage.ObjectAttrs) {
+	if u == nil {
+		return
+	}
+	set := func(dst *string, v *string) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	set(&attrs.ContentType, u.ContentType)
+	set(&attrs.ContentEncoding, u.ContentEncoding)
+	set(&attrs.ContentLanguage, u.ContentLanguage)
+	set(&attrs.ContentDisposition, u.ContentDisposition)
+	set(&attrs.CacheControl, u.CacheControl)
+	for k, v := range u.Metadata {
+		if v == "" {
+			continue
+		}
+		if attrs.Metadata == nil {
+			attrs.Metadata = make(map[string]string)
+		}
+		attrs.Metadata[k] = v
+	}
+}
+
+// attrsToUpdate converts the update to an ObjectAttrsToUpdate, given the
+// object's current custom metadata. Custom metadata is patched with merge
+// semantics, so removing keys requires the full resulting map; the second
+// return value reports whether keys are removed.
+func (u *MetadataUpdate) attrsToUpdate(current map[string]string) (storage.ObjectAttrsToUpdate, bool) {
+	var attrs storage.ObjectAttrsToUpdate
+	if u.ContentType != nil {
+		attrs.ContentType = *u.ContentType
+	}
+	if u.ContentEncoding != nil {
+		attrs.ContentEncoding = *u.ContentEncoding
+	}
+	if u.ContentLanguage != nil {
+		attrs.ContentLanguage = *u.ContentLanguage
+	}
+	if u.ContentDisposition != nil {
+		attrs.ContentDisposition = *u.ContentDisposition
+	}
+	if u.CacheControl != nil {
+		attrs.CacheControl = *u.CacheControl
+	}
+	if len(u.Metadata) == 0 {
+		return attrs, false
+	}
+
+	merged := make(map[string]string, len(current)+len(u.Metadata))
+	for k, v := range current {
+		merged[k] = v
+	}
+	removes := false
+	for k, v := range u.Metadata {
+		if v == "" {
+			if _, ok := merged[k]; ok {
+				removes = true
+			}
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	attrs.Metadata = merged
+	return attrs, removes
+}
+
+// SetMetaOptions controls SetMetadata.
+type SetMetaOptions struct {
+	// Recursive allows a prefix (path ending in "/") and makes wildcards
+	// match at any depth
+	Recursive bool
+	// DryRun prints what would change without updating anything
+	DryRun bool
+	// MaxWorkers is the number of objects updated in parallel
+	MaxWorkers int
+}
+
+// SetMetadata applies update to a single object, every object under a prefix
+// (object ending in "/", requires opts.Recursive), or every object matching a
+// wildcard pattern. Objects are updated in place, in parallel; each update is
+// conditional on the object's metageneration so a concurrent metadata change
+// is not overwritten.
+func SetMetadata(ctx context.Context, client *storage.Client, bucket, object string, update *MetadataUpdate, verbose bool, formatter PathFormatter, opts *SetMetaOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+	if opts == nil {
+		opts = &SetMetaOptions{}
+	}
+	if update.IsEmpty() {
+		return fmt.Errorf("no metadata changes given (use --header or --meta)")
+	}
+
+	return updateObjects(ctx, client, bucket, object, update.String(), formatter, opts, "setmeta",
+		func(obj *storage.ObjectHandle) error { return updateObjectMetadata(ctx, obj, update) })
+}
+
+// updateObjects runs apply on every object that object expands to (see
+// expandObjects), in parallel, printing progress. change describes the
+// update for dry runs and single-object output; command prefixes the error.
+func updateObjects(ctx context.Context, client *storage.Client, bucket, object, change string, formatter PathFormatter, opts *SetMetaOptions, command string, apply func(obj *storage.ObjectHandle) error) error {
+	objects, err := expandObjects(ctx, client, bucket, object, opts.Recursive)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(objects))
+	for i, obj := range objects {
+		names[i] = objectName(bucket, obj)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no objects found matching gs://%s/%s", bucket, object)
+	}
+
+	if opts.DryRun {
+		for _, name := range names {
+			fmt.Printf("Would update: %s (%s)\n", formatter(fmt.Sprintf("gs://%s/%s", bucket, name)), change)
+		}
+		fmt.Printf("\n%d object(s) would be updated (dry run)\n", len(names))
+		return nil
+	}
+
+	maxWorkers := opts.MaxWorkers
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+	totalCount := len(names)
+
+	sem := make(chan struct{}, maxWorkers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	var completedCount int32
+
+	// Channel for completed updates (for progress tracking)
+	type updated struct {
+		gcsPath string
+		err     error
+	}
+	results := make(chan updated, totalCount)
+
+	done := make(chan struct{})
+	go func() {
+		for u := range results {
+			count := atomic.AddInt32(&completedCount, 1)
+			if u.err != nil {
+				fmt.Printf("Failed %d/%d: %s - %v\n", count, totalCount, formatter(u.gcsPath), u.err)
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = u.err
+				}
+				mu.Unlock()
+			} else if totalCount > 1 {
+				fmt.Printf("Updated %d/%d: %s\n", count, totalCount, formatter(u.gcsPath))
+			} else {
+				fmt.Printf("Updated: %s (%s)\n", formatter(u.gcsPath), change)
+			}
+		}
+		close(done)
+	}()
+
+	bkt := client.Bucket(bucket)
+	for _, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			gcsPath := fmt.Sprintf("gs://%s/%s", bucket, name)
+			results <- updated{gcsPath: gcsPath, err: apply(bkt.Object(name))}
+		}(name)
+	}
+
+	wg.Wait()
+	close(results)
+	<-done
+
+	if firstErr != nil {
+		return fmt.Errorf("%s failed: %w", command, firstErr)
+	}
+	if totalCount > 1 {
+		fmt.Printf("\nTotal objects updated: %d\n", totalCount)
+	}
+	return nil
+}
+
+// updateObjectMetadata patches one object's metadata, guarded by its current
+// metageneration. Removing custom keys takes two patches, because a patch
+// merges custom metadata: the first clears it, the second writes the result.
+// If the second patch fails, the original custom metadata is written back.
+func updateObjectMetadata(ctx context.Context, obj *storage.ObjectHandle, update *MetadataUpdate) error {
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", obj.BucketName(), obj.ObjectName())
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+
+	toUpdate, removes := update.attrsToUpdate(attrs.Metadata)
+	metageneration := attrs.Metageneration
+	if removes {
+		apilog.Logf("[GCS] Object.Update(gs://%s/%s, clear metadata)", obj.BucketName(), obj.ObjectName())
+		cleared, err := obj.If(storage.Conditions{MetagenerationMatch: metageneration}).Update(ctx, storage.ObjectAttrsToUpdate{Metadata: map[string]string{}})
+		if err != nil {
+			return err
+		}
+		metageneration = cleared.Metageneration
+		if len(toUpdate.Metadata) == 0 {
+			toUpdate.Metadata = nil
+		}
+	}
+
+	apilog.Logf("[GCS] Object.Update(gs://%s/%s)", obj.BucketName(), obj.ObjectName())
+	_, err = obj.If(storage.Conditions{MetagenerationMatch: metageneration}).Update(ctx, toUpdate)
+	if err != nil && removes {
+		return restoreMetadata(ctx, obj, attrs.Metadata, metageneration, err)
+	}
+	return err
+}
+
+// restoreMetadata writes back the custom metadata that the first patch of
+// updateObjectMetadata cleared, after the second patch failed with
+// updateErr. It runs even if ctx was canceled (Ctrl-C), and if it fails too
+// the error lists the lost keys and values.
+func restoreMetadata(ctx context.Context, obj *storage.ObjectHandle, metadata map[string]string, metageneration int64, updateErr error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+
+	apilog.Logf("[GCS] Object.Update(gs://%s/%s, restore metadata)", obj.BucketName(), obj.ObjectName())
+	_, err := obj.If(storage.Conditions{MetagenerationMatch: metageneration}).Update(ctx, storage.ObjectAttrsToUpdate{Metadata: metadata})
+	if err == nil {
+		return updateErr
+	}
+	lost := make([]string, 0, len(metadata))
+	for k, v := range metadata {
+		lost = append(lost, k+"="+v)
+	}
+	sort.Strings(lost)
+	return fmt.Errorf("%w; custom metadata was cleared and could not be restored (%v), lost: %s", updateErr, err, strings.Join(lost, ", "))
+}
+
+// expandObjects resolves object to the objects it refers to: the object
+// itself, every object under a prefix (recursive only), or every object
+// matching a wildcard. Directory markers are skipped.
+func expandObjects(ctx context.Context, client *storage.Client, bucket, object string, recursive bool) ([]*ObjectInfo, error) {
+	if resolver.HasWildcard(object) {
+		matched, err := ListWithPattern(ctx, bucket, object, &ListOptions{Recursive: recursive})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		var objects []*ObjectInfo
+		for _, obj := range matched {
+			if obj.IsPrefix || strings.HasSuffix(obj.Path, "/") {
+				continue
+			}
+			objects = append(objects, obj)
+		}
+		return objects, nil
+	}
+
+	if object != "" && !strings.HasSuffix(object, "/") {
+		apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", bucket, object)
+		attrs, err := client.Bucket(bucket).Object(object).Attrs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gs://%s/%s: %w", bucket, object, err)
+		}
+		return []*ObjectInfo{CreateObjectInfo(attrs, bucket)}, nil
+	}
+	if !recursive {
+		return nil, fmt.Errorf("gs://%s/%s is a prefix (use -r to include every object under it)", bucket, object)
+	}
+
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q)", bucket, object)
+	it := client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: object})
+	var objects []*ObjectInfo
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		if strings.HasSuffix(attrs.Name, "/") {
+			continue
+		}
+		objects = append(objects, CreateObjectInfo(attrs, bucket))
+	}
+	return objects, nil
+}
+
+// objectName returns the object name of info within bucket.
+func objectName(bucket string, info *ObjectInfo) string {
+	return strings.TrimPrefix(info.Path, "gs://"+bucket+"/")
+}
+
+// ObjectDetails holds the full metadata of a single GCS object, as shown by
+// 'cio info'.
+type ObjectDetails struct {
+	Path               string            `json:"path"`
+	Size               int64             `json:"size"`
+	ContentType        string            `json:"content_type,omitempty"`
+	ContentEncoding    string            `json:"content_encoding,omitempty"`
+	ContentLanguage    string            `json:"content_language,omitempty"`
+	ContentDisposition string            `json:"content_disposition,omitempty"`
+	CacheControl       string            `json:"cache_control,omitempty"`
+	StorageClass       string            `json:"storage_class,omitempty"`
+	Created            time.Time         `json:"created"`
+	Updated            time.Time         `json:"updated"`
+	Generation         int64             `json:"generation"`
+	Metageneration     int64             `json:"metageneration"`
+	CRC32C             string            `json:"crc32c"`
+	MD5                string            `json:"md5,omitempty"`
+	ETag               string            `json:"etag,omitempty"`
+	KMSKeyName         string            `json:"kms_key_name,omitempty"`
+	CustomerKeySHA256  string            `json:"customer_key_sha256,omitempty"`
+	TemporaryHold      bool              `json:"temporary_hold,omitempty"`
+	EventBasedHold     bool              `json:"event_based_hold,omitempty"`
+	RetainedUntil      *time.Time        `json:"retained_until,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+// GetObjectDetails fetches the metadata of a single object.
+// An object of the form "name#generation" describes that object version.
+func GetObjectDetails(ctx context.Context, client *storage.Client, bucket, object string) (*ObjectDetails, error) {
+	name, generation := resolver.SplitGeneration(object)
+	obj := client.Bucket(bucket).Object(name)
+	if generation != 0 {
+		obj = obj.Generation(generation)
+	}
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", bucket, object)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gs://%s/%s: %w", bucket, object, err)
+	}
+	return objectDetailsFrom(attrs), nil
+}
+
+func objectDetailsFrom(attrs *storage.ObjectAttrs) *ObjectDetails {
+	d := &ObjectDetails{
+		Path:               fmt.Sprintf("gs://%s/%s", attrs.Bucket, attrs.Name),
+		Size:               attrs.Size,
+		ContentType:        attrs.ContentType,
+		ContentEncoding:    attrs.ContentEncoding,
+		ContentLanguage:    attrs.ContentLanguage,
+		ContentDisposition: attrs.ContentDisposition,
+		CacheControl:       attrs.CacheControl,
+		StorageClass:       attrs.StorageClass,
+		Created:            attrs.Created,
+		Updated:            attrs.Updated,
+		Generation:         attrs.Generation,
+		Metageneration:     attrs.Metageneration,
+		CRC32C:             base64.StdEncoding.EncodeToString(crc32cBytes(attrs.CRC32C)),
+		ETag:               attrs.Etag,
+		KMSKeyName:         attrs.KMSKeyName,
+		CustomerKeySHA256:  attrs.CustomerKeySHA256,
+		Metadata:           attrs.Metadata,
+		TemporaryHold:      attrs.TemporaryHold,
+		EventBasedHold:     attrs.EventBasedHold,
+	}
+	if until := retainedUntil(attrs); !until.IsZero() {
+		d.RetainedUntil = &until
+	}
+	if len(attrs.MD5) > 0 {
+		d.MD5 = base64.StdEncoding.EncodeToString(attrs.MD5)
+	}
+	return d
+}
+
+// crc32cBytes returns the big-endian bytes of a CRC32C, the form GCS and
+// gsutil display in base64.
+func crc32cBytes(crc uint32) []byte {
+	return []byte{byte(crc >> 24), byte(crc >> 16), byte(crc >> 8), byte(crc)}
+}
+
+// FormatDetailed formats the object's metadata for 'cio info'.
+func (d *ObjectDetails) FormatDetailed(aliasPath string) string {
+	displayPath := d.Path
+	if aliasPath != "" {
+		displayPath = aliasPath
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Path:                %s\n", displayPath)
+	fmt.Fprintf(&b, "Size:                %s (%d bytes)\n", FormatSize(d.Size), d.Size)
+	optional := func(label, v string) {
+		if v != "" {
+			fmt.Fprintf(&b, "%-21s%s\n", label+":", v)
+		}
+	}
+	optional("Content-Type", d.ContentType)
+	optional("Content-Encoding", d.ContentEncoding)
+	optional("Content-Language", d.ContentLanguage)
+	optional("Content-Disposition", d.ContentDisposition)
+	optional("Cache-Control", d.CacheControl)
+	optional("Storage Class", d.StorageClass)
+	fmt.Fprintf(&b, "Created:             %s\n", d.Created.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "Updated:             %s\n", d.Updated.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "Generation:          %d\n", d.Generation)
+	fmt.Fprintf(&b, "Metageneration:      %d\n", d.Metageneration)
+	fmt.Fprintf(&b, "CRC32C:              %s\n", d.CRC32C)
+	optional("MD5", d.MD5)
+	optional("ETag", d.ETag)
+	switch {
+	case d.CustomerKeySHA256 != "":
+		fmt.Fprintf(&b, "Encryption:          customer-supplied key (sha256 %s)\n", d.CustomerKeySHA256)
+	case d.KMSKeyName != "":
+		fmt.Fprintf(&b, "Encryption:          Cloud KMS key %s\n", d.KMSKeyName)
+	default:
+		fmt.Fprintf(&b, "Encryption:          Google-managed key\n")
+	}
+	if d.TemporaryHold || d.EventBasedHold {
+		fmt.Fprintf(&b, "Hold:                %s\n", HoldLabel(d.TemporaryHold, d.EventBasedHold, time.Time{}, time.Time{}))
+	}
+	if d.RetainedUntil != nil {
+		state := "expired"
+		if d.RetainedUntil.After(time.Now()) {
+			state = "cannot be deleted or replaced until then"
+		}
+		fmt.Fprintf(&b, "Retention Expiry:    %s (%s)\n", d.RetainedUntil.Local().Format("2006-01-02 15:04:05"), state)
+	}
+	if len(d.Metadata) > 0 {
+		fmt.Fprintf(&b, "Metadata:\n")
+		for _, k := range sortedKeys(d.Metadata) {
+			fmt.Fprintf(&b, "  %s: %s\n", k, d.Metadata[k])
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttrsToUpdate(t *testing.T) {
+	csv := "text/csv"
+	tests := []struct {
+		name        string
+		current     map[string]string
+		update      MetadataUpdate
+		metadata    map[string]string
+		contentType string
+		removes     bool
+	}{
+		{
+			name:        "headers only leave metadata alone",
+			current:     map[string]string{"owner": "a"},
+			update:      MetadataUpdate{ContentType: &csv},
+			contentType: "text/csv",
+		},
+		{
+			name:     "set merges with current keys",
+			current:  map[string]string{"owner": "a"},
+			update:   MetadataUpdate{Metadata: map[string]string{"team": "b"}},
+			metadata: map[string]string{"owner": "a", "team": "b"},
+		},
+		{
+			name:     "set overwrites a key",
+			current:  map[string]string{"owner": "a"},
+			update:   MetadataUpdate{Metadata: map[string]string{"owner": "b"}},
+			metadata: map[string]string{"owner": "b"},
+		},
+		{
+			name:     "remove drops the key",
+			current:  map[string]string{"owner": "a", "team": "b"},
+			update:   MetadataUpdate{Metadata: map[string]string{"owner": ""}},
+			metadata: map[string]string{"team": "b"},
+			removes:  true,
+		},
+		{
+			name:     "remove the last key",
+			current:  map[string]string{"owner": "a"},
+			update:   MetadataUpdate{Metadata: map[string]string{"owner": ""}},
+			metadata: map[string]string{},
+			removes:  true,
+		},
+		{
+			name:     "remove a missing key is a plain merge",
+			current:  map[string]string{"owner": "a"},
+			update:   MetadataUpdate{Metadata: map[string]string{"team": ""}},
+			metadata: map[string]string{"owner": "a"},
+		},
+		{
+			name:        "remove and set together",
+			current:     map[string]string{"owner": "a", "tmp": "1"},
+			update:      MetadataUpdate{ContentType: &csv, Metadata: map[string]string{"tmp": "", "team": "b"}},
+			metadata:    map[string]string{"owner": "a", "team": "b"},
+			contentType: "text/csv",
+			removes:     true,
+		},
+		{
+			name:     "no current metadata",
+			update:   MetadataUpdate{Metadata: map[string]string{"team": "b"}},
+			metadata: map[string]string{"team": "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := make(map[string]string)
+			for k, v := range tt.current {
+				current[k] = v
+			}
+			attrs, removes := tt.update.attrsToUpdate(current)
+			if removes != tt.removes {
+				t.Errorf("removes = %t, want %t", removes, tt.removes)
+			}
+			if !reflect.DeepEqual(attrs.Metadata, tt.metadata) {
+				t.Errorf("Metadata = %v, want %v", attrs.Metadata, tt.metadata)
+			}
+			if got, _ := attrs.ContentType.(string); got != tt.contentType {
+				t.Errorf("ContentType = %q, want %q", got, tt.contentType)
+			}
+			if !reflect.DeepEqual(current, tt.current) && !(len(current) == 0 && tt.current == nil) {
+				t.Errorf("current metadata was modified: %v", current)
+			}
+		})
+	}
+}
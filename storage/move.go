@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+)
+
+// MoveFailure records one object that could not be moved. Stage is "copy"
+// when the rewrite failed (the source is untouched) or "delete" when the copy
+// succeeded but the source could not be removed (the object now exists twice).
+type MoveFailure struct {
+	Source      string
+	Destination string
+	Stage       string
+	Err         error
+}
+
+// MoveObject moves a single object by rewriting it server-side and deleting
+// the source only after the copy succeeded.
+// If dstObject is empty or ends with "/", the source object's base name is appended.
+func MoveObject(ctx context.Context, client *storage.Client, srcBucket, srcObject, dstBucket, dstObject string, verbose bool, formatter PathFormatter) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	dstObject = copyDestination(srcObject, dstObject)
+	srcGCSPath := fmt.Sprintf("gs://%s/%s", srcBucket, srcObject)
+	dstGCSPath := fmt.Sprintf("gs://%s/%s", dstBucket, dstObject)
+	fm := fileCopy{
+		srcObject:  srcObject,
+		dstObject:  dstObject,
+		srcGCSPath: srcGCSPath,
+		dstGCSPath: dstGCSPath,
+	}
+	if err := checkMoveTargets(srcBucket, dstBucket, []fileCopy{fm}, formatter); err != nil {
+		return err
+	}
+
+	if verbose {
+		fmt.Printf("Moving %s to %s\n", formatter(srcGCSPath), formatter(dstGCSPath))
+	}
+
+	written, failure := moveOne(ctx, client, fm, srcBucket, dstBucket)
+	if failure != nil {
+		return fmt.Errorf("failed to move %s: %s: %w", formatter(srcGCSPath), failure.Stage, failure.Err)
+	}
+
+	fmt.Printf("Moved: %s → %s (%s)\n", formatter(srcGCSPath), formatter(dstGCSPath), FormatSize(written))
+	return nil
+}
+
+// MovePlan is the list of objects a recursive or wildcard move rewrites,
+// built up front so it can be previewed and confirmed before anything moves.
+type MovePlan struct {
+	srcBucket string
+	dstBucket string
+	files     []fileCopy
+}
+
+// PlanMoveDirectory plans moving all objects under srcPrefix to dstPrefix,
+// keeping the layout relative to srcPrefix (the same semantics as
+// CopyDirectory).
+func PlanMoveDirectory(ctx context.Context, client *storage.Client, srcBucket, srcPrefix, dstBucket, dstPrefix string) (*MovePlan, error) {
+	files, err := collectPrefixCopies(ctx, client, srcBucket, srcPrefix, dstBucket, dstPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &MovePlan{srcBucket: srcBucket, dstBucket: dstBucket, files: files}, nil
+}
+
+// PlanMoveWithPattern plans moving all objects matching a wildcard pattern
+// to dstPrefix. With preserveStructure the full object path is kept under
+// dstPrefix, otherwise matches are flattened to their base names.
+func PlanMoveWithPattern(ctx context.Context, srcBucket, pattern, dstBucket, dstPrefix string, preserveStructure bool) (*MovePlan, error) {
+	files, err := collectPatternCopies(ctx, srcBucket, pattern, dstBucket, dstPrefix, preserveStructure)
+	if err != nil {
+		return nil, err
+	}
+	return &MovePlan{srcBucket: srcBucket, dstBucket: dstBucket, files: files}, nil
+}
+
+// Len returns the number of objects to move.
+func (p *MovePlan) Len() int {
+	return len(p.files)
+}
+
+// Sources returns the gs:// paths of the objects to move, for a preview.
+func (p *MovePlan) Sources() []string {
+	paths := make([]string, len(p.files))
+	for i, f := range p.files {
+		paths[i] = f.srcGCSPath
+	}
+	return paths
+}
+
+// Run moves the planned objects in parallel (see moveObjectsParallel).
+func (p *MovePlan) Run(ctx context.Context, client *storage.Client, verbose bool, formatter PathFormatter, maxWorkers int) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+	return moveObjectsParallel(ctx, client, p.srcBucket, p.dstBucket, p.files, verbose, formatter, maxWorkers)
+}
+
+// checkMoveTargets refuses a move that maps any object onto itself. Flattening
+// wildcards or overlapping prefixes can do that, and copying then deleting
+// such an object would lose the data.
+func checkMoveTargets(srcBucket, dstBucket string, filesToMove []fileCopy, formatter PathFormatter) error {
+	if srcBucket != dstBucket {
+		return nil
+	}
+	for _, fm := range filesToMove {
+		if fm.srcObject == fm.dstObject {
+			return fmt.Errorf("source and destination are the same object: %s", formatter(fm.srcGCSPath))
+		}
+	}
+	return nil
+}
+
+// moveObjectsParallel moves objects with controlled concurrency. Every object
+// is copied first and its source deleted only once the copy returned; failures
+// are collected and printed as a report at the end. Because successfully moved
+// objects no longer exist at the source, re-running the same command resumes a
+// partially finished move.
+func moveObjectsParallel(ctx context.Context, client *storage.Client, srcBucket, dstBucket string, filesToMove []fileCopy, verbose bool, formatter PathFormatter, maxWorkers int) error {
+	totalCount := len(filesToMove)
+
+	if err := checkMoveTargets(srcBucket, dstBucket, filesToMove, formatter); err != nil {
+		return err
+	}
+
+	sem := make(chan struct{}, maxWorkers)
+	var wg sync.WaitGroup
+	var failures []MoveFailure
+	var completedCount int32
+	var movedBytes int64
+
+	type moved struct {
+		fileCopy
+		bytesWritten int64
+		failure      *MoveFailure
+	}
+	results := make(chan moved, totalCount)
+
+	// Start progress reporter goroutine
+	done := make(chan struct{})
+	go func() {
+		for m := range results {
+			count := atomic.AddInt32(&completedCount, 1)
+
+			if m.failure != nil {
+				fmt.Printf("Failed %d/%d: %s - %s: %v\n", count, totalCount, formatter(m.srcGCSPath), m.failure.Stage, m.failure.Err)
+				failures = append(failures, *m.failure)
+				continue
+			}
+
+			atomic.AddInt64(&movedBytes, m.bytesWritten)
+			if verbose {
+				fmt.Printf("Moved %d/%d: %s to %s (%s)\n", count, totalCount, formatter(m.srcGCSPath), formatter(m.dstGCSPath), FormatSize(m.bytesWritten))
+			} else {
+				fmt.Printf("Moved %d/%d: %s → %s (%s)\n", count, totalCount, formatter(m.srcGCSPath), formatter(m.dstGCSPath), FormatSize(m.bytesWritten))
+			}
+		}
+		close(done)
+	}()
+
+	for _, fm := range filesToMove {
+		wg.Add(1)
+
+		// Acquire semaphore
+		sem <- struct{}{}
+
+		go func(fm fileCopy) {
+			defer wg.Done()
+			defer func() { <-sem }() // Release semaphore
+
+			written, failure := moveOne(ctx, client, fm, srcBucket, dstBucket)
+			results <- moved{fileCopy: fm, bytesWritten: written, failure: failure}
+		}(fm)
+	}
+
+	wg.Wait()
+	close(results)
+	<-done
+
+	if len(failures) > 0 {
+		printMoveFailures(failures, formatter)
+		return fmt.Errorf("move failed: %d of %d objects not moved", len(failures), totalCount)
+	}
+
+	if totalCount > 1 {
+		fmt.Printf("\nTotal files moved: %d (%s)\n", totalCount, FormatSize(atomic.LoadInt64(&movedBytes)))
+	}
+	return nil
+}
+
+// moveOne copies one object and deletes its source. Both the copy and the
+// delete are pinned to the source generation read up front, so an object that
+// is overwritten at the source mid-move is never deleted without being copied.
+func moveOne(ctx context.Context, client *storage.Client, fm fileCopy, srcBucket, dstBucket string) (int64, *MoveFailure) {
+	src := client.Bucket(srcBucket).Object(fm.srcObject)
+	dst := client.Bucket(dstBucket).Object(fm.dstObject)
+
+	apilog.Logf("[GCS] Object.Attrs(%s) for move", fm.srcGCSPath)
+	srcAttrs, err := src.Attrs(ctx)
+	if err != nil {
+		return 0, &MoveFailure{Source: fm.srcGCSPath, Destination: fm.dstGCSPath, Stage: "copy", Err: err}
+	}
+
+	readSrc, err := readHandle(src, srcAttrs)
+	if err != nil {
+		return 0, &MoveFailure{Source: fm.srcGCSPath, Destination: fm.dstGCSPath, Stage: "copy", Err: err}
+	}
+
+	apilog.Logf("[GCS] Object.Rewrite(%s → %s) for move", fm.srcGCSPath, fm.dstGCSPath)
+	attrs, err := encryptedCopier(dst, readSrc).Run(ctx)
+	if err != nil {
+		return 0, &MoveFailure{Source: fm.srcGCSPath, Destination: fm.dstGCSPath, Stage: "copy", Err: err}
+	}
+
+	apilog.Logf("[GCS] Object.Delete(%s) for move", fm.srcGCSPath)
+	if err := src.If(storage.Conditions{GenerationMatch: srcAttrs.Generation}).Delete(ctx); err != nil {
+		return attrs.Size, &MoveFailure{Source: fm.srcGCSPath, Destination: fm.dstGCSPath, Stage: "delete", Err: err}
+	}
+	return attrs.Size, nil
+}
+
+// printMoveFailures prints a per-object report of everything that was not
+// moved, grouped by the stage that failed.
+func printMoveFailures(failures []MoveFailure, formatter PathFormatter) {
+	var copyFailed, deleteFailed []MoveFailure
+	for _, f := range failures {
+		if f.Stage == "copy" {
+			copyFailed = append(copyFailed, f)
+		} else {
+			deleteFailed = append(deleteFailed, f)
+		}
+	}
+
+	fmt.Printf("\n%d object(s) not moved:\n", len(failures))
+	if len(copyFailed) > 0 {
+		fmt.Printf("  Copy failed (source untouched):\n")
+		for _, f := range copyFailed {
+			fmt.Printf("    %s - %v\n", formatter(f.Source), f.Err)
+		}
+	}
+	if len(deleteFailed) > 0 {
+		fmt.Printf("  Copied but source not deleted (exists at both paths):\n")
+		for _, f := range deleteFailed {
+			fmt.Printf("    %s → %s - %v\n", formatter(f.Source), formatter(f.Destination), f.Err)
+		}
+	}
+	fmt.Printf("Re-run the same command to retry; objects already moved are no longer at the source.\n")
+}
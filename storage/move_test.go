@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckMoveTargets(t *testing.T) {
+	move := func(src, dst string) fileCopy {
+		return fileCopy{srcObject: src, dstObject: dst, srcGCSPath: "gs://b/" + src}
+	}
+	tests := []struct {
+		name      string
+		dstBucket string
+		files     []fileCopy
+		wantErr   string
+	}{
+		{"distinct objects", "b", []fileCopy{move("a/x", "b/x"), move("a/y", "b/y")}, ""},
+		{"same name in another bucket", "other", []fileCopy{move("a/x", "a/x")}, ""},
+		{"object onto itself", "b", []fileCopy{move("a/x", "b/x"), move("b/y", "b/y")}, "gs://b/b/y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMoveTargets("b", tt.dstBucket, tt.files, DefaultPathFormatter)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkMoveTargets() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkMoveTargets() = %v, want error naming %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestMoveMappingOntoItself covers the mappings that land an object on its
+// own name: a flattened wildcard whose matches already sit in the
+// destination, and a prefix moved onto itself.
+func TestMoveMappingOntoItself(t *testing.T) {
+	flat, ok := patternCopy("b", "b", dirPrefix("out"), &ObjectInfo{Path: "gs://b/out/a.log"}, false)
+	if !ok {
+		t.Fatal("patternCopy() skipped a plain object")
+	}
+	if err := checkMoveTargets("b", "b", []fileCopy{flat}, DefaultPathFormatter); err == nil {
+		t.Errorf("checkMoveTargets(%+v) = nil, want same-object error", flat)
+	}
+
+	prefix, ok := prefixCopy("b", "logs/", "b", dirPrefix("logs"), "logs/a.log")
+	if !ok {
+		t.Fatal("prefixCopy() skipped a plain object")
+	}
+	if err := checkMoveTargets("b", "b", []fileCopy{prefix}, DefaultPathFormatter); err == nil {
+		t.Errorf("checkMoveTargets(%+v) = nil, want same-object error", prefix)
+	}
+}
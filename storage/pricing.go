@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StorageClassPrices holds storage prices in USD per GiB-month, keyed by
+// storage class. The defaults are GCS list prices for the US multi-region;
+// estimates built from them ignore operation, retrieval and early-deletion
+// charges.
+var StorageClassPrices = map[string]float64{
+	"STANDARD": 0.026,
+	"NEARLINE": 0.010,
+	"COLDLINE": 0.007,
+	"ARCHIVE":  0.0025,
+}
+
+// SetStorageClassPrices overrides entries of StorageClassPrices, e.g. with
+// regional or negotiated prices from the config. Class names are normalized.
+func SetStorageClassPrices(prices map[string]float64) {
+	for class, price := range prices {
+		StorageClassPrices[NormalizeStorageClass(class)] = price
+	}
+}
+
+// MinimumStorageDays is the minimum storage duration per class; objects
+// deleted or rewritten to another class sooner are billed for the remainder.
+var MinimumStorageDays = map[string]int{
+	"NEARLINE": 30,
+	"COLDLINE": 90,
+	"ARCHIVE":  365,
+}
+
+// MonthlyStorageCost estimates the monthly storage cost of bytes stored in
+// class. Returns false if no price is known for the class.
+func MonthlyStorageCost(class string, bytes int64) (float64, bool) {
+	price, ok := StorageClassPrices[NormalizeStorageClass(class)]
+	if !ok {
+		return 0, false
+	}
+	return float64(bytes) / (1 << 30) * price, true
+}
+
+// NormalizeStorageClass maps legacy and lower-case class names to the
+// canonical upper-case names used by the API.
+func NormalizeStorageClass(class string) string {
+	class = strings.ToUpper(strings.TrimSpace(class))
+	switch class {
+	case "MULTI_REGIONAL", "REGIONAL", "DURABLE_REDUCED_AVAILABILITY":
+		return "STANDARD"
+	}
+	return class
+}
+
+// ValidateStorageClass returns the canonical name of a storage class given on
+// the command line, or an error for an unknown class.
+func ValidateStorageClass(class string) (string, error) {
+	c := strings.ToUpper(strings.TrimSpace(class))
+	switch c {
+	case "STANDARD", "NEARLINE", "COLDLINE", "ARCHIVE":
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid storage class %q (use STANDARD, NEARLINE, COLDLINE or ARCHIVE)", class)
+}
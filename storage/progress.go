@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressMode selects how parallel transfers and deletions report their
+// aggregate progress.
+type ProgressMode int
+
+const (
+	// ProgressAuto draws a single updating line when stderr is a terminal
+	ProgressAuto ProgressMode = iota
+	// ProgressOff disables progress reporting
+	ProgressOff
+	// ProgressJSON writes a JSON progress event to stderr every second
+	ProgressJSON
+)
+
+// Progress is the mode used by cp -r, rm -r, sync and friends. The CLI sets
+// it to ProgressJSON when --json is given.
+var Progress = ProgressAuto
+
+const (
+	progressLineInterval = 200 * time.Millisecond
+	progressJSONInterval = time.Second
+)
+
+// ProgressEvent is one machine-readable progress report (--json).
+type ProgressEvent struct {
+	// Type is "progress" while running and "done" for the final event
+	Type      string `json:"type"`
+	Operation string `json:"operation"`
+
+	ObjectsDone  int64 `json:"objects_done"`
+	ObjectsTotal int64 `json:"objects_total"`
+	BytesDone    int64 `json:"bytes_done"`
+	BytesTotal   int64 `json:"bytes_total"`
+	Failed       int64 `json:"failed"`
+	Skipped      int64 `json:"skipped"`
+	// Held counts objects a delete left alone because of a hold or
+	// retention policy; they are not failures
+	Held int64 `json:"held,omitempty"`
+	// Retries counts requests retried after transient errors
+	Retries int64 `json:"retries"`
+	// Enumerating is true while the totals are still growing (rm -r lists
+	// and deletes at the same time)
+	Enumerating bool `json:"enumerating,omitempty"`
+
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	// ETASeconds is omitted while it cannot be estimated
+	ETASeconds *float64 `json:"eta_seconds,omitempty"`
+}
+
+// progressTracker aggregates object and byte counts across the workers of
+// one parallel operation and renders them periodically. All counters are
+// updated atomically; output is serialized by mu.
+type progressTracker struct {
+	operation string // "download", "upload", "delete" or "trash"
+	mode      ProgressMode
+	out       io.Writer
+	start     time.Time
+
+	objectsDone  int64
+	objectsTotal int64
+	bytesDone    int64
+	bytesTotal   int64
+	failed       int64
+	skipped      int64
+	held         int64
+	enumerating  int32
+	retriesStart int64 // RetryCount() when the operation started
+
+	mu        sync.Mutex
+	lineShown bool
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// newProgressTracker starts reporting progress for an operation over
+// objects objects totalling bytes bytes (either may grow via addTotal).
+func newProgressTracker(operation string, objects, bytes int64) *progressTracker {
+	mode := Progress
+	if mode == ProgressAuto {
+		mode = ProgressOff
+		if fi, err := os.Stderr.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			mode = ProgressAuto
+		}
+	}
+	p := &progressTracker{
+		operation:    operation,
+		mode:         mode,
+		out:          os.Stderr,
+		start:        time.Now(),
+		objectsTotal: objects,
+		bytesTotal:   bytes,
+		retriesStart: RetryCount(),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	if mode == ProgressOff {
+		close(p.done)
+		return p
+	}
+
+	interval := progressLineInterval
+	if mode == ProgressJSON {
+		interval = progressJSONInterval
+	}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.render(false)
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// setEnumerating marks the totals as still growing (true) or final (false).
+func (p *progressTracker) setEnumerating(enumerating bool) {
+	v := int32(0)
+	if enumerating {
+		v = 1
+	}
+	atomic.StoreInt32(&p.enumerating, v)
+}
+
+// addTotal grows the totals as objects are discovered.
+func (p *progressTracker) addTotal(objects, bytes int64) {
+	atomic.AddInt64(&p.objectsTotal, objects)
+	atomic.AddInt64(&p.bytesTotal, bytes)
+}
+
+// addBytes records bytes transferred; transfers call it as data streams.
+func (p *progressTracker) addBytes(n int64) {
+	atomic.AddInt64(&p.bytesDone, n)
+}
+
+// objectDone records a finished object.
+func (p *progressTracker) objectDone(err error) {
+	atomic.AddInt64(&p.objectsDone, 1)
+	if err != nil {
+		atomic.AddInt64(&p.failed, 1)
+	}
+}
+
+// objectSkipped records an object that needed no transfer.
+func (p *progressTracker) objectSkipped() {
+	atomic.AddInt64(&p.objectsDone, 1)
+	atomic.AddInt64(&p.skipped, 1)
+}
+
+// objectHeld records an object a delete refused because of a hold or
+// retention.
+func (p *progressTracker) objectHeld() {
+	atomic.AddInt64(&p.objectsDone, 1)
+	atomic.AddInt64(&p.held, 1)
+}
+
+// reader counts bytes read from r as transferred.
+func (p *progressTracker) reader(r io.Reader) io.Reader {
+	if p.mode == ProgressOff {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+type progressReader struct {
+	r io.Reader
+	p *progressTracker
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.p.addBytes(int64(n))
+	return n, err
+}
+
+// Printf prints a per-object message to stdout, clearing the progress line
+// first so the two do not run into each other.
+func (p *progressTracker) Printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLine()
+	fmt.Printf(format, args...)
+}
+
+// finish stops periodic reporting. The progress line is cleared; in JSON
+// mode a final "done" event is written. If requests had to be retried, a
+// summary of retries and permanent failures goes to stderr.
+func (p *progressTracker) finish() {
+	if p.mode != ProgressOff {
+		close(p.stop)
+		<-p.done
+		p.render(true)
+	}
+	if p.mode == ProgressJSON {
+		return
+	}
+	if retries := RetryCount() - p.retriesStart; retries > 0 {
+		fmt.Fprintf(os.Stderr, "Retried %d request(s) after transient errors; %d item(s) failed permanently\n",
+			retries, atomic.LoadInt64(&p.failed))
+	}
+}
+
+// clearLine erases the progress line. Callers hold p.mu.
+func (p *progressTracker) clearLine() {
+	if p.lineShown {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.lineShown = false
+	}
+}
+
+// event snapshots the counters.
+func (p *progressTracker) event(final bool) ProgressEvent {
+	e := ProgressEvent{
+		Type:           "progress",
+		Operation:      p.operation,
+		ObjectsDone:    atomic.LoadInt64(&p.objectsDone),
+		ObjectsTotal:   atomic.LoadInt64(&p.objectsTotal),
+		BytesDone:      atomic.LoadInt64(&p.bytesDone),
+		BytesTotal:     atomic.LoadInt64(&p.bytesTotal),
+		Failed:         atomic.LoadInt64(&p.failed),
+		Skipped:        atomic.LoadInt64(&p.skipped),
+		Held:           atomic.LoadInt64(&p.held),
+		Retries:        RetryCount() - p.retriesStart,
+		Enumerating:    atomic.LoadInt32(&p.enumerating) == 1,
+		ElapsedSeconds: time.Since(p.start).Seconds(),
+	}
+	if final {
+		e.Type = "done"
+		e.Enumerating = false
+	}
+	if e.ElapsedSeconds > 0 {
+		e.BytesPerSecond = float64(e.BytesDone) / e.ElapsedSeconds
+	}
+
+	// Estimate from bytes where sizes are known, else from object counts
+	if !final && !e.Enumerating {
+		var remaining float64
+		switch {
+		case e.BytesTotal > 0 && e.BytesDone > 0:
+			remaining = float64(e.BytesTotal-e.BytesDone) / e.BytesPerSecond
+		case e.ObjectsDone > 0:
+			remaining = float64(e.ObjectsTotal-e.ObjectsDone) / (float64(e.ObjectsDone) / e.ElapsedSeconds)
+		default:
+			return e
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+		e.ETASeconds = &remaining
+	}
+	return e
+}
+
+// render writes one progress report.
+func (p *progressTracker) render(final bool) {
+	e := p.event(final)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.mode == ProgressJSON {
+		data, err := json.Marshal(e)
+		if err == nil {
+			fmt.Fprintf(p.out, "%s\n", data)
+		}
+		return
+	}
+	if final {
+		p.clearLine()
+		return
+	}
+
+	verb := map[string]string{"download": "Downloading", "upload": "Uploading", "delete": "Deleting", "trash": "Trashing"}[p.operation]
+	total := formatProgressCount(e.ObjectsTotal)
+	if e.Enumerating {
+		total += "+"
+	}
+	parts := []string{fmt.Sprintf("%s %s/%s objects", verb, formatProgressCount(e.ObjectsDone), total)}
+	if e.BytesTotal > 0 {
+		parts = append(parts, fmt.Sprintf("%s/%s", FormatSize(e.BytesDone), FormatSize(e.BytesTotal)))
+		if p.operation != "delete" && p.operation != "trash" {
+			parts = append(parts, FormatSize(int64(e.BytesPerSecond))+"/s")
+		}
+	}
+	if e.ETASeconds != nil {
+		parts = append(parts, "ETA "+formatETA(*e.ETASeconds))
+	}
+	if e.Failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", e.Failed))
+	}
+	if e.Held > 0 {
+		parts = append(parts, fmt.Sprintf("%d held", e.Held))
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s", strings.Join(parts, "  "))
+	p.lineShown = true
+}
+
+// formatProgressCount formats n with thousands separators.
+func formatProgressCount(n int64) string {
+	s := fmt.Sprintf("%d", n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatETA formats a remaining time in seconds as e.g. "45s", "3m12s" or
+// "2h05m".
+func formatETA(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
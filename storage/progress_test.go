@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errTransfer = errors.New("transfer failed")
+
+// testTracker returns a tracker that has been running for ten seconds and
+// renders into a buffer, without the periodic reporting goroutine.
+func testTracker(operation string, mode ProgressMode, objects, size int64) (*progressTracker, *bytes.Buffer) {
+	var out bytes.Buffer
+	return &progressTracker{
+		operation:    operation,
+		mode:         mode,
+		out:          &out,
+		start:        time.Now().Add(-10 * time.Second),
+		objectsTotal: objects,
+		bytesTotal:   size,
+		retriesStart: RetryCount(),
+	}, &out
+}
+
+func TestFormatProgressCount(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{123456, "123,456"},
+		{1234567, "1,234,567"},
+	}
+	for _, tt := range tests {
+		if got := formatProgressCount(tt.n); got != tt.want {
+			t.Errorf("formatProgressCount(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0, "0s"},
+		{45.7, "45s"},
+		{60, "1m00s"},
+		{192, "3m12s"},
+		{3600, "1h00m"},
+		{7500, "2h05m"},
+	}
+	for _, tt := range tests {
+		if got := formatETA(tt.seconds); got != tt.want {
+			t.Errorf("formatETA(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestProgressEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		objects     int64
+		bytes       int64
+		record      func(p *progressTracker)
+		final       bool
+		enumerating bool
+		wantETA     float64 // -1 when no estimate is expected
+	}{
+		{
+			name: "estimated from bytes", objects: 4, bytes: 400,
+			record:  func(p *progressTracker) { p.addBytes(100); p.objectDone(nil) },
+			wantETA: 30,
+		},
+		{
+			name: "estimated from objects without sizes", objects: 4,
+			record:  func(p *progressTracker) { p.objectDone(nil); p.objectSkipped() },
+			wantETA: 10,
+		},
+		{
+			name: "nothing done yet", objects: 4, bytes: 400,
+			record:  func(p *progressTracker) {},
+			wantETA: -1,
+		},
+		{
+			name: "still enumerating", objects: 4, bytes: 400,
+			record:      func(p *progressTracker) { p.addBytes(100) },
+			enumerating: true,
+			wantETA:     -1,
+		},
+		{
+			name: "final", objects: 4, bytes: 400,
+			record:      func(p *progressTracker) { p.addBytes(100) },
+			final:       true,
+			enumerating: true,
+			wantETA:     -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := testTracker("download", ProgressJSON, tt.objects, tt.bytes)
+			p.setEnumerating(tt.enumerating)
+			tt.record(p)
+			e := p.event(tt.final)
+
+			wantType := "progress"
+			if tt.final {
+				wantType = "done"
+			}
+			if e.Type != wantType {
+				t.Errorf("Type = %q, want %q", e.Type, wantType)
+			}
+			if e.Enumerating != (tt.enumerating && !tt.final) {
+				t.Errorf("Enumerating = %v, want %v", e.Enumerating, tt.enumerating && !tt.final)
+			}
+			switch {
+			case tt.wantETA < 0 && e.ETASeconds != nil:
+				t.Errorf("ETASeconds = %v, want none", *e.ETASeconds)
+			case tt.wantETA >= 0 && e.ETASeconds == nil:
+				t.Errorf("ETASeconds = nil, want about %v", tt.wantETA)
+			case tt.wantETA >= 0 && math.Abs(*e.ETASeconds-tt.wantETA) > 1:
+				t.Errorf("ETASeconds = %v, want about %v", *e.ETASeconds, tt.wantETA)
+			}
+		})
+	}
+}
+
+func TestProgressJSONEvents(t *testing.T) {
+	p, out := testTracker("upload", ProgressJSON, 3, 300)
+	p.addBytes(200)
+	p.objectDone(nil)
+	p.objectDone(errTransfer)
+	p.objectSkipped()
+	p.render(false)
+	p.render(true)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d JSON lines, want 2:\n%s", len(lines), out.String())
+	}
+	for i, wantType := range []string{"progress", "done"} {
+		var e ProgressEvent
+		if err := json.Unmarshal([]byte(lines[i]), &e); err != nil {
+			t.Fatalf("line %d is not a progress event: %v", i, err)
+		}
+		want := ProgressEvent{Type: wantType, Operation: "upload", ObjectsDone: 3, ObjectsTotal: 3, BytesDone: 200, BytesTotal: 300, Failed: 1, Skipped: 1}
+		e.ElapsedSeconds, e.BytesPerSecond, e.ETASeconds = 0, 0, nil
+		if e != want {
+			t.Errorf("line %d = %+v, want %+v", i, e, want)
+		}
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		operation   string
+		objects     int64
+		bytes       int64
+		record      func(p *progressTracker)
+		enumerating bool
+		want        []string
+		notWant     []string
+	}{
+		{
+			name: "download", operation: "download", objects: 2000, bytes: 4096,
+			record:  func(p *progressTracker) { p.addBytes(1024); p.objectDone(nil) },
+			want:    []string{"Downloading 1/2,000 objects", "1.0 KB/4.0 KB", "/s", "ETA "},
+			notWant: []string{"failed"},
+		},
+		{
+			name: "failures", operation: "upload", objects: 3,
+			record: func(p *progressTracker) { p.objectDone(nil); p.objectDone(errTransfer) },
+			want:   []string{"Uploading 2/3 objects", "1 failed"},
+		},
+		{
+			name: "delete shows no rate", operation: "delete", objects: 5, bytes: 5000,
+			record:      func(p *progressTracker) { p.addBytes(1000); p.objectDone(nil) },
+			enumerating: true,
+			want:        []string{"Deleting 1/5+ objects"},
+			notWant:     []string{"/s", "ETA"},
+		},
+		{
+			name: "trash", operation: "trash", objects: 2, bytes: 2048,
+			record:  func(p *progressTracker) { p.addBytes(1024); p.objectDone(nil) },
+			want:    []string{"Trashing 1/2 objects", "1.0 KB/2.0 KB"},
+			notWant: []string{"/s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, out := testTracker(tt.operation, ProgressAuto, tt.objects, tt.bytes)
+			p.setEnumerating(tt.enumerating)
+			tt.record(p)
+			p.render(false)
+
+			line := out.String()
+			if !strings.HasPrefix(line, "\r\033[K") {
+				t.Errorf("line %q does not clear the previous one", line)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(line, w) {
+					t.Errorf("line %q does not contain %q", line, w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(line, w) {
+					t.Errorf("line %q contains %q", line, w)
+				}
+			}
+
+			out.Reset()
+			p.render(true)
+			if got := out.String(); got != "\r\033[K" {
+				t.Errorf("final render wrote %q, want the line cleared", got)
+			}
+		})
+	}
+}
+
+func TestProgressHeld(t *testing.T) {
+	p, out := testTracker("delete", ProgressJSON, 4, 0)
+	p.objectDone(nil)
+	p.objectDone(errTransfer)
+	p.objectHeld()
+	p.objectHeld()
+	p.render(true)
+
+	var e ProgressEvent
+	if err := json.Unmarshal(out.Bytes(), &e); err != nil {
+		t.Fatalf("not a progress event: %v\n%s", err, out.String())
+	}
+	if e.ObjectsDone != 4 || e.Failed != 1 || e.Held != 2 {
+		t.Errorf("done %d, failed %d, held %d; want 4, 1, 2", e.ObjectsDone, e.Failed, e.Held)
+	}
+
+	// Runs without held objects keep their JSON unchanged
+	p, out = testTracker("delete", ProgressJSON, 1, 0)
+	p.objectDone(nil)
+	p.render(true)
+	if strings.Contains(out.String(), `"held"`) {
+		t.Errorf("event %s reports held objects", out.String())
+	}
+
+	p, out = testTracker("delete", ProgressAuto, 4, 0)
+	p.objectDone(errTransfer)
+	p.objectHeld()
+	p.render(false)
+	if line := out.String(); !strings.Contains(line, "1 failed  1 held") {
+		t.Errorf("line %q does not report the held object apart from the failure", line)
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by all transfers of one command, so
+// their combined throughput stays at the configured rate however many
+// workers (-j) or chunks run in parallel. A nil *RateLimiter does not limit.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64 // bucket capacity in bytes
+	tokens float64 // may go negative: later callers wait for the debt
+	last   time.Time
+}
+
+// NewRateLimiter returns a limiter for bytesPerSecond, or nil (no limit) if
+// bytesPerSecond is not positive.
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	rate := float64(bytesPerSecond)
+	return &RateLimiter{rate: rate, burst: rate, tokens: rate, last: time.Now()}
+}
+
+// WaitN blocks until n bytes may be transferred or ctx is done. Callers
+// take tokens immediately and sleep off any deficit, so concurrent callers
+// are served in the order they asked.
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reader returns r throttled by the limiter (r itself if l is nil).
+func (l *RateLimiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, r: r, l: l}
+}
+
+type limitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *RateLimiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	// Keep single reads well below the bucket size so throughput is smooth
+	if max := int(lr.l.burst / 4); max > 0 && len(p) > max {
+		p = p[:max]
+	}
+	n, err := lr.r.Read(p)
+	if werr := lr.l.WaitN(lr.ctx, n); werr != nil && err == nil {
+		err = werr
+	}
+	return n, err
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	for _, rate := range []int64{0, -1} {
+		if l := NewRateLimiter(rate); l != nil {
+			t.Errorf("NewRateLimiter(%d) = %v, want nil", rate, l)
+		}
+	}
+	var l *RateLimiter
+	if err := l.WaitN(context.Background(), 1<<30); err != nil {
+		t.Errorf("nil limiter: WaitN = %v, want nil", err)
+	}
+	r := bytes.NewReader(nil)
+	if got := l.Reader(context.Background(), r); got != r {
+		t.Error("nil limiter: Reader did not return its input")
+	}
+}
+
+func TestRateLimiterWaitN(t *testing.T) {
+	tests := []struct {
+		name     string
+		takes    []int
+		min, max time.Duration
+	}{
+		{"within burst", []int{500, 500}, 0, 50 * time.Millisecond},
+		{"deficit", []int{1000, 200}, 150 * time.Millisecond, 500 * time.Millisecond},
+		{"single oversized take", []int{1300}, 250 * time.Millisecond, 600 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewRateLimiter(1000)
+			start := time.Now()
+			for _, n := range tt.takes {
+				if err := l.WaitN(context.Background(), n); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("WaitN(%v) took %v, want %v to %v", tt.takes, elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitNCanceled(t *testing.T) {
+	l := NewRateLimiter(1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.WaitN(ctx, 10000); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitN = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRateLimiterReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 6000)
+	l := NewRateLimiter(4000)
+	start := time.Now()
+	got, err := io.ReadAll(l.Reader(context.Background(), bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("read %d bytes, want %d", len(got), len(data))
+	}
+	// 4000 bytes of burst, then 2000 bytes at 4000 B/s
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond || elapsed > time.Second {
+		t.Errorf("reading took %v, want about 500ms", elapsed)
+	}
+}
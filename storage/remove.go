@@ -2,14 +2,16 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
 	"google.golang.org/api/iterator"
 )
 
@@ -33,57 +35,98 @@ func formatSize(bytes int64) string {
 	}
 }
 
-// RemoveObject removes a single object from GCS
-func RemoveObject(ctx context.Context, client *storage.Client, bucket, object string, verbose bool, formatter PathFormatter) error {
+// VersionSelection selects which generations of each matched object rm
+// deletes. On buckets without versioning every object has exactly one
+// (live) generation, so the selections only differ on versioned buckets.
+type VersionSelection int
+
+const (
+	// LiveVersions deletes the live object; a versioned bucket keeps it as a
+	// noncurrent version.
+	LiveVersions VersionSelection = iota
+	// AllVersions permanently deletes every generation, live and noncurrent.
+	AllVersions
+	// NoncurrentVersions permanently deletes noncurrent generations and keeps
+	// the live object.
+	NoncurrentVersions
+)
+
+// RemoveObject removes a single object from GCS.
+// An object of the form "name#generation" deletes that generation only.
+// With a trash (nil deletes permanently), the live object is moved into a
+// new trash batch instead.
+func RemoveObject(ctx context.Context, client *storage.Client, bucket, object string, verbose bool, formatter PathFormatter, versions VersionSelection, trash *Trash) error {
 	if formatter == nil {
 		formatter = DefaultPathFormatter
 	}
 
+	if trash != nil && versions == LiveVersions && !trash.Contains(bucket, object) {
+		if _, generation := resolver.SplitGeneration(object); generation == 0 {
+			batch := newTrashBatch(trash)
+			if err := batch.move(ctx, client, bucket, object); err != nil {
+				return removeError("failed to move object to trash", err)
+			}
+			fmt.Printf("Trashed: %s\n", formatter(fmt.Sprintf("gs://%s/%s", bucket, object)))
+			batch.printRestoreHint()
+			return nil
+		}
+	}
+
+	if versions != LiveVersions {
+		name, _ := resolver.SplitGeneration(object)
+		enumerate := func(ctx context.Context, send func(item workItem)) error {
+			return enumerateForDelete(ctx, client, bucket, name, versions, func(n string) bool { return n == name }, send)
+		}
+		return deleteObjectsStream(ctx, client, bucket, enumerate,
+			fmt.Sprintf("no matching versions of gs://%s/%s", bucket, name),
+			formatter, 1)
+	}
+
 	fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, object)
 
-	obj := client.Bucket(bucket).Object(object)
+	name, generation := resolver.SplitGeneration(object)
+	obj := client.Bucket(bucket).Object(name)
+	if generation != 0 {
+		obj = obj.Generation(generation)
+	}
 	apilog.Logf("[GCS] Object.Delete(gs://%s/%s)", bucket, object)
 	if err := obj.Delete(ctx); err != nil {
-		return fmt.Errorf("failed to delete object: %w", err)
+		return removeError("failed to delete object", holdError(err, bucket, name))
 	}
 
 	fmt.Printf("Deleted: %s\n", formatter(fullGCSPath))
 	return nil
 }
 
-// RemoveDirectory removes all objects with a given prefix.
-// Enumeration and deletion run concurrently via a worker pool.
-func RemoveDirectory(ctx context.Context, client *storage.Client, bucket, prefix string, verbose bool, formatter PathFormatter, maxWorkers int) error {
+// RemoveDirectory removes all objects with a given prefix, or only those
+// whose path below the prefix passes filter (nil removes all).
+// Enumeration and deletion run concurrently via a worker pool. With a trash,
+// live objects are moved into a new trash batch instead of being deleted.
+func RemoveDirectory(ctx context.Context, client *storage.Client, bucket, prefix string, verbose bool, formatter PathFormatter, maxWorkers int, versions VersionSelection, filter *PathFilter, trash *Trash) error {
 	if formatter == nil {
 		formatter = DefaultPathFormatter
 	}
 
-	enumerate := func(ctx context.Context, send func(name string, size int64)) error {
-		bkt := client.Bucket(bucket)
-		query := &storage.Query{Prefix: prefix}
-		apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for delete", bucket, prefix)
-		it := bkt.Objects(ctx, query)
-		for {
-			attrs, err := it.Next()
-			if err == iterator.Done {
-				break
-			}
-			if err != nil {
-				return fmt.Errorf("failed to list objects: %w", err)
-			}
-			send(attrs.Name, attrs.Size)
+	var match func(name string) bool
+	notFound := fmt.Sprintf("no objects found with prefix gs://%s/%s", bucket, prefix)
+	if filter != nil {
+		match = func(name string) bool {
+			return filter.Match(strings.TrimPrefix(name, prefix))
 		}
-		return nil
+		notFound = fmt.Sprintf("no objects under gs://%s/%s match the include/exclude filters", bucket, prefix)
+	}
+
+	enumerate := func(ctx context.Context, send func(item workItem)) error {
+		return enumerateForDelete(ctx, client, bucket, prefix, versions, match, send)
 	}
 
-	return deleteObjectsStream(ctx, client, bucket, enumerate,
-		fmt.Sprintf("no objects found with prefix gs://%s/%s", bucket, prefix),
-		formatter, maxWorkers)
+	return removeObjectsStream(ctx, client, bucket, enumerate, notFound, formatter, maxWorkers, trashBatchFor(trash, versions))
 }
 
 // RemoveWithPattern removes objects matching a wildcard pattern.
-// Enumeration and deletion run concurrently via a worker pool.
-func RemoveWithPattern(ctx context.Context, client *storage.Client, bucket, pattern string, verbose bool, formatter PathFormatter, maxWorkers int) error {
+// Enumeration and deletion run concurrently via a worker pool. With a trash,
+// live objects are moved into a new trash batch instead of being deleted.
+func RemoveWithPattern(ctx context.Context, client *storage.Client, bucket, pattern string, verbose bool, formatter PathFormatter, maxWorkers int, versions VersionSelection, trash *Trash) error {
 	if formatter == nil {
 		formatter = DefaultPathFormatter
 	}
@@ -93,71 +136,114 @@ func RemoveWithPattern(ctx context.Context, client *storage.Client, bucket, patt
 	// Instead we use ListWithPattern to find matching directory prefixes first.
 	isDirPattern := strings.HasSuffix(pattern, "/") && strings.ContainsAny(pattern, "*?")
 
-	enumerate := func(ctx context.Context, send func(name string, size int64)) error {
+	enumerate := func(ctx context.Context, send func(item workItem)) error {
 		if isDirPattern {
-			matchingDirs, err := ListWithPattern(ctx, bucket, pattern, DefaultListOptions())
-			if err != nil {
-				return err
-			}
-			if len(matchingDirs) == 0 {
-				return fmt.Errorf("no directories found matching pattern: %s", pattern)
-			}
-			bkt := client.Bucket(bucket)
-			for _, dir := range matchingDirs {
+			found := false
+			err := ListWithPatternEach(ctx, bucket, pattern, DefaultListOptions(), func(dir *ObjectInfo) error {
+				found = true
 				if !dir.IsPrefix {
-					continue
+					return nil
 				}
 				dirPrefix := strings.TrimPrefix(dir.Path, "gs://"+bucket+"/")
-				query := &storage.Query{Prefix: dirPrefix}
-				apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for delete", bucket, dirPrefix)
-				it := bkt.Objects(ctx, query)
-				for {
-					attrs, err := it.Next()
-					if err == iterator.Done {
-						break
-					}
-					if err != nil {
-						return fmt.Errorf("failed to list objects in %s: %w", dirPrefix, err)
-					}
-					send(attrs.Name, attrs.Size)
+				if err := enumerateForDelete(ctx, client, bucket, dirPrefix, versions, nil, send); err != nil {
+					return fmt.Errorf("in %s: %w", dirPrefix, err)
 				}
+				return nil
+			})
+			if err != nil {
+				return err
 			}
-		} else {
-			prefix, wildcardPattern := splitPattern(pattern)
-			bkt := client.Bucket(bucket)
-			query := &storage.Query{Prefix: prefix}
-			apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for delete", bucket, prefix)
-			it := bkt.Objects(ctx, query)
-			for {
-				attrs, err := it.Next()
-				if err == iterator.Done {
-					break
-				}
-				if err != nil {
-					return fmt.Errorf("failed to list objects: %w", err)
-				}
-				if !matchesPattern(attrs.Name, wildcardPattern) {
-					continue
-				}
-				send(attrs.Name, attrs.Size)
+			if !found {
+				return fmt.Errorf("no directories found matching pattern: %s", pattern)
 			}
+			return nil
 		}
-		return nil
+
+		prefix, wildcardPattern := splitPattern(pattern)
+		match := func(name string) bool { return matchesPattern(name, wildcardPattern) }
+		return enumerateForDelete(ctx, client, bucket, prefix, versions, match, send)
 	}
 
-	return deleteObjectsStream(ctx, client, bucket, enumerate,
+	return removeObjectsStream(ctx, client, bucket, enumerate,
 		fmt.Sprintf("no objects found matching pattern: %s", pattern),
-		formatter, maxWorkers)
+		formatter, maxWorkers, trashBatchFor(trash, versions))
+}
+
+// trashBatchFor starts a trash batch for a delete of live objects; deleting
+// specific versions is always permanent.
+func trashBatchFor(trash *Trash, versions VersionSelection) *trashBatch {
+	if trash == nil || versions != LiveVersions {
+		return nil
+	}
+	return newTrashBatch(trash)
+}
+
+// RemoveObjects deletes the given objects (their live versions) of one bucket
+// in parallel, e.g. the results of 'cio find'. With a trash, they are moved
+// into a new trash batch instead of being deleted.
+func RemoveObjects(ctx context.Context, client *storage.Client, bucket string, objects []*ObjectInfo, formatter PathFormatter, maxWorkers int, trash *Trash) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	enumerate := func(ctx context.Context, send func(item workItem)) error {
+		for _, obj := range objects {
+			send(workItem{name: objectName(bucket, obj), ifGeneration: obj.Generation, size: obj.Size})
+		}
+		return nil
+	}
+
+	return removeObjectsStream(ctx, client, bucket, enumerate,
+		fmt.Sprintf("no objects to delete in gs://%s", bucket),
+		formatter, maxWorkers, trashBatchFor(trash, LiveVersions))
+}
+
+// enumerateForDelete lists objects under prefix and sends those accepted by
+// match (nil accepts all) to a deleteObjectsStream. With a version selection
+// other than LiveVersions, noncurrent versions are listed too and each one is
+// sent with its generation so exactly that version is deleted.
+func enumerateForDelete(ctx context.Context, client *storage.Client, bucket, prefix string, versions VersionSelection, match func(name string) bool, send func(item workItem)) error {
+	query := &storage.Query{Prefix: prefix, Versions: versions != LiveVersions}
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q, versions=%v) for delete", bucket, prefix, query.Versions)
+	it := client.Bucket(bucket).Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list objects: %w", err)
+		}
+		if match != nil && !match(attrs.Name) {
+			continue
+		}
+		switch versions {
+		case LiveVersions:
+			send(workItem{name: attrs.Name, ifGeneration: attrs.Generation, size: attrs.Size})
+		case AllVersions:
+			send(workItem{name: attrs.Name, generation: attrs.Generation, size: attrs.Size})
+		case NoncurrentVersions:
+			if !attrs.Deleted.IsZero() {
+				send(workItem{name: attrs.Name, generation: attrs.Generation, size: attrs.Size})
+			}
+		}
+	}
 }
 
-// workItem carries the object name and its byte size through the delete pipeline.
+// workItem carries an object to delete through the delete pipeline: its
+// name, the generation to delete (0 = the live object) and its byte size.
+// ifGeneration, if set, makes deleting the live object conditional on the
+// generation that was listed, so the delete is idempotent and can be retried.
 type workItem struct {
-	name string
-	size int64
+	name         string
+	generation   int64
+	ifGeneration int64
+	size         int64
 }
 
 // deleteObjectsStream runs enumeration concurrently with a fixed worker pool.
-// enumerate calls send() for each object (name + size) to delete;
+// enumerate calls send() for each object to delete; a non-zero generation
+// deletes exactly that version;
 // deleteObjectsStream manages the worker goroutines, progress reporting, and
 // error collection.
 // notFoundMsg is returned when enumerate completes with zero objects sent.
@@ -165,11 +251,32 @@ func deleteObjectsStream(
 	ctx context.Context,
 	client *storage.Client,
 	bucket string,
-	enumerate func(ctx context.Context, send func(name string, size int64)) error,
+	enumerate func(ctx context.Context, send func(item workItem)) error,
 	notFoundMsg string,
 	formatter PathFormatter,
 	maxWorkers int,
 ) error {
+	return removeObjectsStream(ctx, client, bucket, enumerate, notFoundMsg, formatter, maxWorkers, nil)
+}
+
+// removeObjectsStream is deleteObjectsStream that, given a trash batch,
+// moves the objects into the batch instead of deleting them. Objects that
+// are already in the trash are skipped.
+func removeObjectsStream(
+	ctx context.Context,
+	client *storage.Client,
+	bucket string,
+	enumerate func(ctx context.Context, send func(item workItem)) error,
+	notFoundMsg string,
+	formatter PathFormatter,
+	maxWorkers int,
+	trash *trashBatch,
+) error {
+	verb := "Deleted"
+	if trash != nil {
+		verb = "Trashed"
+	}
+
 	workCh := make(chan workItem, maxWorkers*4)
 
 	var wg sync.WaitGroup
@@ -178,12 +285,22 @@ func deleteObjectsStream(
 	var enumErr error
 	var completedCount int32
 	var failedCount int32
+	var goneCount int32   // already deleted by someone else
+	var held []*HoldError // the first maxHeldListed objects refused because of holds or retention
+	var heldCount int
 	var enumeratedCount int32
-	var enumDone int32
 	var lastPath string
 	var completedBytes int64
 	var enumeratedBytes int64
 
+	// The totals grow while enumeration runs alongside the deletes
+	operation := "delete"
+	if trash != nil {
+		operation = "trash"
+	}
+	progress := newProgressTracker(operation, 0, 0)
+	progress.setEnumerating(true)
+
 	// Fixed worker pool: workers drain workCh until it is closed.
 	bkt := client.Bucket(bucket)
 	for i := 0; i < maxWorkers; i++ {
@@ -191,12 +308,48 @@ func deleteObjectsStream(
 		go func() {
 			defer wg.Done()
 			for item := range workCh {
-				err := bkt.Object(item.name).Delete(ctx)
+				obj := bkt.Object(item.name)
+				path := item.name
+				if item.generation != 0 {
+					obj = obj.Generation(item.generation)
+					path = fmt.Sprintf("%s#%d", item.name, item.generation)
+				} else if item.ifGeneration != 0 {
+					obj = obj.If(storage.Conditions{GenerationMatch: item.ifGeneration})
+				}
+				var err error
+				gone := false
+				if trash != nil {
+					err = trash.move(ctx, client, bucket, item.name)
+				} else {
+					err = holdError(obj.Delete(ctx), bucket, path)
+					// Already gone, e.g. deleted concurrently: nothing was
+					// removed by this run
+					gone = errors.Is(err, storage.ErrObjectNotExist)
+				}
+				var heldErr *HoldError
+				isHeld := errors.As(err, &heldErr)
 				atomic.AddInt32(&completedCount, 1)
-				atomic.AddInt64(&completedBytes, item.size)
+				switch {
+				case gone:
+					atomic.AddInt32(&goneCount, 1)
+					progress.objectSkipped()
+				case isHeld:
+					progress.objectHeld()
+				default:
+					progress.objectDone(err)
+					if err == nil {
+						atomic.AddInt64(&completedBytes, item.size)
+						progress.addBytes(item.size)
+					}
+				}
 				mu.Lock()
-				lastPath = item.name
-				if err != nil {
+				lastPath = path
+				if isHeld {
+					heldCount++
+					if len(held) < maxHeldListed {
+						held = append(held, heldErr)
+					}
+				} else if err != nil && !gone {
 					atomic.AddInt32(&failedCount, 1)
 					if firstErr == nil {
 						firstErr = err
@@ -207,78 +360,52 @@ func deleteObjectsStream(
 		}()
 	}
 
-	// Progress reporter: one \r line updated every 200ms.
-	stop := make(chan struct{})
-	reporterDone := make(chan struct{})
-	printProgress := func(final bool) {
-		deleted := atomic.LoadInt32(&completedCount)
-		enumed := atomic.LoadInt32(&enumeratedCount)
-		failed := atomic.LoadInt32(&failedCount)
-		delBytes := atomic.LoadInt64(&completedBytes)
-		enumBytes := atomic.LoadInt64(&enumeratedBytes)
-		done := atomic.LoadInt32(&enumDone) == 1
-		mu.Lock()
-		path := lastPath
-		mu.Unlock()
-		if path == "" {
-			return
-		}
-		fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, path)
-		suffix := "\r"
-		if final {
-			suffix = "\n"
-		}
-		if done {
-			if failed > 0 {
-				fmt.Printf("\rDeleted %d/%d (%s/%s, %d failed): %s%s",
-					deleted, enumed, formatSize(delBytes), formatSize(enumBytes), failed, formatter(fullGCSPath), suffix)
-			} else {
-				fmt.Printf("\rDeleted %d/%d (%s/%s): %s%s",
-					deleted, enumed, formatSize(delBytes), formatSize(enumBytes), formatter(fullGCSPath), suffix)
-			}
-		} else {
-			if failed > 0 {
-				fmt.Printf("\rDeleted %d (%s) found %d (%s), %d failed: %s%s",
-					deleted, formatSize(delBytes), enumed, formatSize(enumBytes), failed, formatter(fullGCSPath), suffix)
-			} else {
-				fmt.Printf("\rDeleted %d (%s) found %d (%s): %s%s",
-					deleted, formatSize(delBytes), enumed, formatSize(enumBytes), formatter(fullGCSPath), suffix)
-			}
-		}
-	}
-	go func() {
-		defer close(reporterDone)
-		ticker := time.NewTicker(200 * time.Millisecond)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ticker.C:
-				printProgress(false)
-			case <-stop:
-				return
-			}
-		}
-	}()
-
 	// Enumeration goroutine: feeds workCh; closing it signals workers to exit.
 	go func() {
 		defer close(workCh)
-		err := enumerate(ctx, func(name string, size int64) {
-			workCh <- workItem{name: name, size: size}
+		err := enumerate(ctx, func(item workItem) {
+			if trash != nil && trash.trash.Contains(bucket, item.name) {
+				return
+			}
+			workCh <- item
 			atomic.AddInt32(&enumeratedCount, 1)
-			atomic.AddInt64(&enumeratedBytes, size)
+			atomic.AddInt64(&enumeratedBytes, item.size)
+			progress.addTotal(1, item.size)
 		})
 		mu.Lock()
 		enumErr = err
 		mu.Unlock()
-		atomic.StoreInt32(&enumDone, 1)
+		progress.setEnumerating(false)
 	}()
 
 	wg.Wait()
-	close(stop)
-	<-reporterDone
+	progress.finish()
 
-	printProgress(true)
+	// Final status line: counts and the last object deleted
+	if lastPath != "" {
+		deleted := atomic.LoadInt32(&completedCount)
+		enumed := atomic.LoadInt32(&enumeratedCount)
+		failed := atomic.LoadInt32(&failedCount)
+		gone := atomic.LoadInt32(&goneCount)
+		fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, lastPath)
+		if failed > 0 || heldCount > 0 || gone > 0 {
+			var problems []string
+			if failed > 0 {
+				problems = append(problems, fmt.Sprintf("%d failed", failed))
+			}
+			if heldCount > 0 {
+				problems = append(problems, fmt.Sprintf("%d held", heldCount))
+			}
+			if gone > 0 {
+				problems = append(problems, fmt.Sprintf("%d already gone", gone))
+			}
+			fmt.Printf("%s %d/%d (%s/%s, %s): %s\n", verb,
+				deleted, enumed, formatSize(atomic.LoadInt64(&completedBytes)), formatSize(atomic.LoadInt64(&enumeratedBytes)), strings.Join(problems, ", "), formatter(fullGCSPath))
+		} else {
+			fmt.Printf("%s %d/%d (%s/%s): %s\n", verb,
+				deleted, enumed, formatSize(atomic.LoadInt64(&completedBytes)), formatSize(atomic.LoadInt64(&enumeratedBytes)), formatter(fullGCSPath))
+		}
+	}
 
 	mu.Lock()
 	eerr := enumErr
@@ -291,18 +418,39 @@ func deleteObjectsStream(
 	if atomic.LoadInt32(&enumeratedCount) == 0 {
 		return fmt.Errorf("%s", notFoundMsg)
 	}
+	removed := atomic.LoadInt32(&completedCount) - atomic.LoadInt32(&failedCount) - int32(heldCount) - atomic.LoadInt32(&goneCount)
+	if heldCount > 0 {
+		// Held objects are expected, not failures: list them instead of
+		// failing on the first 403
+		sort.Slice(held, func(i, j int) bool { return held[i].Path < held[j].Path })
+		fmt.Printf("Skipped %d object(s) under a hold or retention policy:\n", heldCount)
+		for _, h := range held {
+			fmt.Printf("  %s (%s)\n", formatter(h.Path), h.Reason)
+		}
+		if heldCount > len(held) {
+			fmt.Printf("  ... and %d more\n", heldCount-len(held))
+		}
+	}
+	if trash != nil && removed > 0 {
+		trash.printRestoreHint()
+	}
 	if derr != nil {
 		return fmt.Errorf("deletion failed: %w", derr)
 	}
 
-	deleted := atomic.LoadInt32(&completedCount)
 	delBytes := atomic.LoadInt64(&completedBytes)
-	if deleted > 1 {
-		fmt.Printf("Total: %d objects deleted (%s)\n", deleted, formatSize(delBytes))
+	if removed > 1 {
+		fmt.Printf("Total: %d objects %s (%s)\n", removed, strings.ToLower(verb), formatSize(delBytes))
+	}
+	if heldCount > 0 {
+		return fmt.Errorf("%d object(s) not removed because of holds or retention (%s)", heldCount, holdHint)
 	}
 	return nil
 }
 
+// maxHeldListed caps how many held objects a bulk remove lists.
+const maxHeldListed = 20
+
 // splitPattern splits a path with wildcards into prefix and pattern
 // Example: "logs/2024/*.log" -> ("logs/2024/", "*.log")
 func splitPattern(path string) (prefix, pattern string) {
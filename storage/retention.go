@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+)
+
+// RetentionInfo describes a bucket's retention settings, as shown by
+// 'cio retention show'.
+type RetentionInfo struct {
+	Bucket string `json:"bucket"`
+	// Period is the minimum age before an object may be deleted or
+	// replaced; 0 when the bucket has no retention policy
+	Period        time.Duration `json:"period,omitempty"`
+	EffectiveTime *time.Time    `json:"effective_time,omitempty"`
+	Locked        bool          `json:"locked"`
+	// DefaultEventBasedHold puts new objects under an event-based hold
+	DefaultEventBasedHold bool `json:"default_event_based_hold"`
+	// ObjectRetentionMode is "Enabled" when objects can carry their own
+	// retention
+	ObjectRetentionMode string `json:"object_retention_mode,omitempty"`
+	Metageneration      int64  `json:"metageneration"`
+}
+
+// GetRetention fetches the retention settings of bucket.
+func GetRetention(ctx context.Context, client *storage.Client, bucket string) (*RetentionInfo, error) {
+	apilog.Logf("[GCS] Bucket.Attrs(%s)", bucket)
+	attrs, err := client.Bucket(bucket).Attrs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket gs://%s: %w", bucket, err)
+	}
+	info := &RetentionInfo{
+		Bucket:                bucket,
+		DefaultEventBasedHold: attrs.DefaultEventBasedHold,
+		ObjectRetentionMode:   attrs.ObjectRetentionMode,
+		Metageneration:        attrs.MetaGeneration,
+	}
+	if p := attrs.RetentionPolicy; p != nil {
+		info.Period = p.RetentionPeriod
+		info.Locked = p.IsLocked
+		if !p.EffectiveTime.IsZero() {
+			effective := p.EffectiveTime
+			info.EffectiveTime = &effective
+		}
+	}
+	return info, nil
+}
+
+// FormatDetailed formats the retention settings for 'cio retention show'.
+func (r *RetentionInfo) FormatDetailed() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Bucket:              gs://%s\n", r.Bucket)
+	if r.Period == 0 {
+		fmt.Fprintf(&b, "Retention Policy:    none\n")
+	} else {
+		state := "unlocked (can be changed or removed)"
+		if r.Locked {
+			state = "locked (can only be extended)"
+		}
+		fmt.Fprintf(&b, "Retention Period:    %s\n", formatRetentionPeriod(r.Period))
+		if r.EffectiveTime != nil {
+			fmt.Fprintf(&b, "Effective Since:     %s\n", r.EffectiveTime.Local().Format("2006-01-02 15:04:05"))
+		}
+		fmt.Fprintf(&b, "Policy State:        %s\n", state)
+	}
+	fmt.Fprintf(&b, "Default Event Hold:  %t\n", r.DefaultEventBasedHold)
+	if r.ObjectRetentionMode != "" {
+		fmt.Fprintf(&b, "Object Retention:    %s\n", r.ObjectRetentionMode)
+	}
+	return b.String()
+}
+
+// formatRetentionPeriod formats a period in the largest whole unit of days,
+// hours, minutes or seconds, e.g. "7 days" or "36 hours".
+func formatRetentionPeriod(d time.Duration) string {
+	for _, u := range []struct {
+		name string
+		d    time.Duration
+	}{{"day", 24 * time.Hour}, {"hour", time.Hour}, {"minute", time.Minute}, {"second", time.Second}} {
+		if d%u.d == 0 {
+			n := int64(d / u.d)
+			if n == 1 {
+				return "1 " + u.name
+			}
+			return fmt.Sprintf("%d %ss", n, u.name)
+		}
+	}
+	return d.String()
+}
+
+// LockRetention permanently locks the retention policy of bucket, as
+// described by info (from GetRetention). The lock is conditional on the
+// bucket's metageneration, so a policy changed since info was read is not
+// locked. A locked policy cannot be removed or shortened, and the bucket
+// cannot be deleted while it holds objects under retention.
+func LockRetention(ctx context.Context, client *storage.Client, info *RetentionInfo) error {
+	if info.Period == 0 {
+		return fmt.Errorf("gs://%s has no retention policy to lock", info.Bucket)
+	}
+	if info.Locked {
+		return fmt.Errorf("the retention policy of gs://%s is already locked", info.Bucket)
+	}
+	apilog.Logf("[GCS] Bucket.LockRetentionPolicy(%s, metageneration=%d)", info.Bucket, info.Metageneration)
+	bkt := client.Bucket(info.Bucket).If(storage.BucketConditions{MetagenerationMatch: info.Metageneration})
+	if err := bkt.LockRetentionPolicy(ctx); err != nil {
+		return fmt.Errorf("failed to lock retention policy of gs://%s: %w", info.Bucket, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/thieso2/cio/apilog"
+	"google.golang.org/api/googleapi"
+)
+
+// RetryPolicy controls how GCS requests (uploads, downloads, deletes and
+// listings alike) are retried after transient errors.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per request; 1 disables
+	// retries
+	MaxAttempts int
+	// InitialBackoff is the upper bound of the first pause; each pause is
+	// drawn at random below the bound (full jitter), which grows by
+	// Multiplier up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// RetryCodes are the HTTP status codes treated as transient. Network
+	// errors (connection resets, unexpected EOF, timeouts) are always retried.
+	RetryCodes []int
+}
+
+// DefaultRetryCodes are the HTTP status codes GCS recommends retrying.
+var DefaultRetryCodes = []int{408, 429, 500, 502, 503, 504}
+
+// retryPolicy is applied to the client when it is created; nil keeps the
+// client library's defaults.
+var retryPolicy *RetryPolicy
+
+// retryCount counts requests retried after a transient error.
+var retryCount int64
+
+// SetRetryPolicy sets the retry policy of the shared client. It must be
+// called before the first GetClient. Empty RetryCodes means
+// DefaultRetryCodes.
+func SetRetryPolicy(p RetryPolicy) {
+	if len(p.RetryCodes) == 0 {
+		p.RetryCodes = DefaultRetryCodes
+	}
+	retryPolicy = &p
+}
+
+// RetryCount returns the number of requests retried so far.
+func RetryCount() int64 {
+	return atomic.LoadInt64(&retryCount)
+}
+
+// applyRetryPolicy configures client with the policy set by SetRetryPolicy.
+// Only idempotent requests are retried: reads, listings, and writes guarded
+// by a generation or metageneration precondition. Retrying anything else
+// after a response was lost could repeat a compose or rewrite, or fail on
+// its own first attempt's precondition. Unconditional uploads opt in with
+// retryUpload.
+func applyRetryPolicy(client *storage.Client) {
+	p := retryPolicy
+	if p == nil {
+		return
+	}
+	if p.MaxAttempts <= 1 {
+		client.SetRetry(storage.WithPolicy(storage.RetryNever))
+		return
+	}
+	client.SetRetry(
+		storage.WithPolicy(storage.RetryIdempotent),
+		storage.WithMaxAttempts(p.MaxAttempts),
+		storage.WithBackoff(gax.Backoff{Initial: p.InitialBackoff, Max: p.MaxBackoff, Multiplier: p.Multiplier}),
+		storage.WithErrorFunc(func(err error) bool {
+			if !isRetryable(err, p.RetryCodes) {
+				return false
+			}
+			atomic.AddInt64(&retryCount, 1)
+			apilog.Logf("[GCS] retrying after transient error: %v", err)
+			return true
+		}),
+	)
+}
+
+// retryUpload lets an unconditional upload to obj be retried. Sending the
+// same bytes again has no precondition to trip; at worst a versioned bucket
+// keeps an identical noncurrent version. Uploads with preconditions are
+// retried as idempotent writes anyway.
+func retryUpload(obj *storage.ObjectHandle) *storage.ObjectHandle {
+	if p := retryPolicy; p != nil && p.MaxAttempts <= 1 {
+		return obj
+	}
+	return obj.Retryer(storage.WithPolicy(storage.RetryAlways))
+}
+
+// isRetryable classifies err as transient: an HTTP status in codes, or a
+// network-level error.
+func isRetryable(err error, codes []int) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		for _, code := range codes {
+			if apiErr.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return storage.ShouldRetry(err)
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		codes []int
+		want  bool
+	}{
+		{"503 in default codes", &googleapi.Error{Code: 503}, DefaultRetryCodes, true},
+		{"429 in default codes", &googleapi.Error{Code: 429}, DefaultRetryCodes, true},
+		{"wrapped 500", fmt.Errorf("upload: %w", &googleapi.Error{Code: 500}), DefaultRetryCodes, true},
+		{"404 not retried", &googleapi.Error{Code: 404}, DefaultRetryCodes, false},
+		{"412 not retried", &googleapi.Error{Code: 412}, DefaultRetryCodes, false},
+		{"503 not in custom codes", &googleapi.Error{Code: 503}, []int{429}, false},
+		{"403 in custom codes", &googleapi.Error{Code: 403}, []int{403}, true},
+		{"network timeout", timeoutError{}, nil, true},
+		{"wrapped network timeout", &net.OpError{Op: "read", Err: timeoutError{}}, nil, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, nil, true},
+		{"connection reset", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, nil, true},
+		{"other error", errors.New("permission denied"), DefaultRetryCodes, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err, tt.codes); got != tt.want {
+				t.Errorf("isRetryable(%v, %v) = %t, want %t", tt.err, tt.codes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetRetryPolicyDefaultCodes(t *testing.T) {
+	saved := retryPolicy
+	defer func() { retryPolicy = saved }()
+
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 3})
+	if len(retryPolicy.RetryCodes) != len(DefaultRetryCodes) {
+		t.Errorf("RetryCodes = %v, want %v", retryPolicy.RetryCodes, DefaultRetryCodes)
+	}
+	SetRetryPolicy(RetryPolicy{MaxAttempts: 3, RetryCodes: []int{503}})
+	if len(retryPolicy.RetryCodes) != 1 || retryPolicy.RetryCodes[0] != 503 {
+		t.Errorf("RetryCodes = %v, want [503]", retryPolicy.RetryCodes)
+	}
+}
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
+)
+
+// RewritePlan is the set of objects a storage class rewrite or key
+// rotation will touch. Objects already in the target class (and on the
+// target key) are left out, so re-running an interrupted rewrite only picks
+// up what is left.
+type RewritePlan struct {
+	Bucket string
+	// StorageClass is the target class; "" keeps each object's class
+	StorageClass string
+	// RotateKey re-encrypts objects with the key configured for their
+	// location (CSEK or CMEK)
+	RotateKey bool
+	// Objects still to rewrite
+	Objects []*ObjectInfo
+	// Bytes is the total size of Objects
+	Bytes int64
+	// BytesByClass breaks Bytes down by current storage class
+	BytesByClass map[string]int64
+	// Skipped is the number of selected objects that need no rewrite
+	Skipped int
+	// Selection is the du-style total of everything the path selects
+	Selection DUEntry
+}
+
+// PlanStorageClassRewrite selects the objects under object (a single object,
+// a prefix with recursive, or a wildcard) that are not yet in class.
+func PlanStorageClassRewrite(ctx context.Context, client *storage.Client, bucket, object, class string, recursive bool, workers int) (*RewritePlan, error) {
+	return planRewrite(ctx, client, bucket, object, class, false, recursive, workers)
+}
+
+// PlanKeyRotation selects the objects under object that are not yet
+// encrypted with the key configured for their location, or (with a class)
+// not yet in class.
+func PlanKeyRotation(ctx context.Context, client *storage.Client, bucket, object, class string, recursive bool, workers int) (*RewritePlan, error) {
+	return planRewrite(ctx, client, bucket, object, class, true, recursive, workers)
+}
+
+func planRewrite(ctx context.Context, client *storage.Client, bucket, object, class string, rotateKey, recursive bool, workers int) (*RewritePlan, error) {
+	objects, err := expandObjects(ctx, client, bucket, object, recursive)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no objects found matching gs://%s/%s", bucket, object)
+	}
+
+	plan := &RewritePlan{Bucket: bucket, StorageClass: class, RotateKey: rotateKey, BytesByClass: make(map[string]int64)}
+	for _, obj := range objects {
+		current := NormalizeStorageClass(obj.StorageClass)
+		onKey := true
+		if rotateKey {
+			name := objectName(bucket, obj)
+			if encryptionFor(bucket, name) == nil {
+				return nil, fmt.Errorf("no encryption key configured for gs://%s/%s (set one in the config's encryption section, --encryption-key-file or --kms-key)", bucket, name)
+			}
+			onKey = hasTargetKey(bucket, name, obj.KMSKeyName, obj.CustomerKeySHA256)
+		}
+		if (class == "" || current == class) && onKey {
+			plan.Skipped++
+			continue
+		}
+		plan.Objects = append(plan.Objects, obj)
+		plan.Bytes += obj.Size
+		plan.BytesByClass[current] += obj.Size
+	}
+
+	plan.Selection, err = selectionUsage(ctx, bucket, object, workers)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// selectionUsage sums the size and object count of everything object selects,
+// using the same parallel listing as 'cio du'.
+func selectionUsage(ctx context.Context, bucket, object string, workers int) (DUEntry, error) {
+	entry := DUEntry{Path: fmt.Sprintf("gs://%s/%s", bucket, object)}
+	if resolver.HasWildcard(object) {
+		matches, err := DiskUsagePattern(ctx, bucket, object, &DUOptions{Workers: workers})
+		if err != nil {
+			return entry, fmt.Errorf("failed to calculate disk usage: %w", err)
+		}
+		for _, m := range matches {
+			entry.Size += m.Size
+			entry.Count += m.Count
+		}
+		return entry, nil
+	}
+	result, err := DiskUsage(ctx, bucket, object, &DUOptions{Workers: workers})
+	if err != nil {
+		return entry, fmt.Errorf("failed to calculate disk usage: %w", err)
+	}
+	entry.Size, entry.Count = result.Total, result.Count
+	return entry, nil
+}
+
+// FormatPreview describes the plan: sizes, object counts and the estimated
+// monthly storage cost before and after the rewrite.
+func (p *RewritePlan) FormatPreview(formatter PathFormatter) string {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Selected:   %s in %d object(s) under %s\n", FormatSize(p.Selection.Size), p.Selection.Count, formatter(p.Selection.Path))
+	fmt.Fprintf(&b, "To rewrite: %s in %d object(s)", FormatSize(p.Bytes), len(p.Objects))
+	if p.Skipped > 0 {
+		switch {
+		case p.RotateKey && p.StorageClass != "":
+			fmt.Fprintf(&b, " (%d already %s on the target key)", p.Skipped, p.StorageClass)
+		case p.RotateKey:
+			fmt.Fprintf(&b, " (%d already on the target key)", p.Skipped)
+		default:
+			fmt.Fprintf(&b, " (%d already %s)", p.Skipped, p.StorageClass)
+		}
+	}
+	b.WriteString("\n")
+
+	classes := make([]string, 0, len(p.BytesByClass))
+	for class := range p.BytesByClass {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	var before float64
+	known := true
+	for _, class := range classes {
+		cost, ok := MonthlyStorageCost(class, p.BytesByClass[class])
+		if !ok {
+			known = false
+		}
+		before += cost
+		fmt.Fprintf(&b, "  %-10s %s\n", class, FormatSize(p.BytesByClass[class]))
+	}
+
+	if p.StorageClass == "" {
+		return b.String()
+	}
+	after, ok := MonthlyStorageCost(p.StorageClass, p.Bytes)
+	if known && ok && len(p.Objects) > 0 {
+		fmt.Fprintf(&b, "Estimated storage cost: $%.2f/month → $%.2f/month (%s)\n", before, after, p.StorageClass)
+	}
+	if days, ok := MinimumStorageDays[p.StorageClass]; ok && len(p.Objects) > 0 {
+		fmt.Fprintf(&b, "Note: %s objects are billed for at least %d days; retrieval is charged per GB.\n", p.StorageClass, days)
+	}
+	return b.String()
+}
+
+// ExecuteRewrite rewrites the plan's objects to the target storage class
+// and/or encryption key in parallel. Each object is rewritten server-side
+// onto itself, keeping its content headers and custom metadata (and its key,
+// unless rotating), and conditional on the generation just read, so a
+// concurrent upload is never replaced by old data. On a versioned bucket the
+// previous generation becomes noncurrent.
+func ExecuteRewrite(ctx context.Context, client *storage.Client, plan *RewritePlan, verbose bool, formatter PathFormatter, maxWorkers int) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+	if maxWorkers < 1 {
+		maxWorkers = 1
+	}
+
+	startTime := time.Now()
+	totalCount := len(plan.Objects)
+	var totalBytes int64
+
+	sem := make(chan struct{}, maxWorkers)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	var completedCount int32
+
+	// Channel for completed rewrites (for progress tracking)
+	type rewritten struct {
+		gcsPath string
+		from    string
+		to      string
+		size    int64
+		err     error
+	}
+	results := make(chan rewritten, totalCount)
+
+	done := make(chan struct{})
+	go func() {
+		for r := range results {
+			count := atomic.AddInt32(&completedCount, 1)
+			if r.err != nil {
+				fmt.Printf("Failed %d/%d: %s - %v\n", count, totalCount, formatter(r.gcsPath), r.err)
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = r.err
+				}
+				mu.Unlock()
+				continue
+			}
+			atomic.AddInt64(&totalBytes, r.size)
+			fmt.Printf("Rewrote %d/%d: %s (%s → %s, %s)\n", count, totalCount, formatter(r.gcsPath), r.from, r.to, FormatSize(r.size))
+		}
+		close(done)
+	}()
+
+	bkt := client.Bucket(plan.Bucket)
+	for _, obj := range plan.Objects {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(obj *ObjectInfo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			from, to, err := rewriteInPlace(ctx, bkt.Object(objectName(plan.Bucket, obj)), plan.StorageClass, plan.RotateKey)
+			results <- rewritten{gcsPath: obj.Path, from: from, to: to, size: obj.Size, err: err}
+		}(obj)
+	}
+
+	wg.Wait()
+	close(results)
+	<-done
+
+	if firstErr != nil {
+		return fmt.Errorf("rewrite failed (re-run to continue with the remaining objects): %w", firstErr)
+	}
+
+	if totalCount > 1 {
+		elapsed := time.Since(startTime)
+		fmt.Printf("\nTotal objects rewritten: %d (%s in %.2fs)\n", totalCount, FormatSize(atomic.LoadInt64(&totalBytes)), elapsed.Seconds())
+	}
+	return nil
+}
+
+// rewriteInPlace rewrites one object onto itself in class ("" keeps its
+// class), re-encrypted with the configured key if rotateKey is set. It
+// returns descriptions of the object before and after, e.g. the storage
+// classes.
+func rewriteInPlace(ctx context.Context, obj *storage.ObjectHandle, class string, rotateKey bool) (string, string, error) {
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", obj.BucketName(), obj.ObjectName())
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	from := NormalizeStorageClass(attrs.StorageClass)
+	if class == "" {
+		class = from
+	}
+	to := class
+
+	dst := obj.If(storage.Conditions{GenerationMatch: attrs.Generation})
+	var copier *storage.Copier
+	if rotateKey {
+		src, err := WithReadKey(obj.Generation(attrs.Generation), attrs.CustomerKeySHA256)
+		if err != nil {
+			return "", "", err
+		}
+		copier = encryptedCopier(dst, src)
+		from, to = encryptionDescription(attrs.KMSKeyName, attrs.CustomerKeySHA256), targetKeyDescription(obj.BucketName(), obj.ObjectName())
+		if class != NormalizeStorageClass(attrs.StorageClass) {
+			from += " " + NormalizeStorageClass(attrs.StorageClass)
+			to += " " + class
+		}
+	} else if copier, err = sameKeyCopier(dst, obj.Generation(attrs.Generation), attrs); err != nil {
+		return "", "", err
+	}
+	// A rewrite with destination attributes replaces the object's metadata,
+	// so carry the editable fields over explicitly.
+	copier.ContentType = attrs.ContentType
+	copier.ContentEncoding = attrs.ContentEncoding
+	copier.ContentLanguage = attrs.ContentLanguage
+	copier.ContentDisposition = attrs.ContentDisposition
+	copier.CacheControl = attrs.CacheControl
+	copier.Metadata = attrs.Metadata
+	copier.StorageClass = class
+
+	apilog.Logf("[GCS] Object.Rewrite(gs://%s/%s, storageClass=%s, rotateKey=%v)", obj.BucketName(), obj.ObjectName(), class, rotateKey)
+	if _, err := copier.Run(ctx); err != nil {
+		return "", "", err
+	}
+	return from, to, nil
+}
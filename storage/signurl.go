@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/iam"
+	"github.com/thieso2/cio/resolver"
+	"golang.org/x/oauth2/google"
+)
+
+// MaxSignedURLExpiry is the longest validity V4 signed URLs support.
+const MaxSignedURLExpiry = 7 * 24 * time.Hour
+
+// SignURLOptions configures signed URL generation.
+type SignURLOptions struct {
+	// Method is GET (download) or PUT (upload)
+	Method  string
+	Expires time.Duration
+	// ContentType, if set, must be sent by the client as Content-Type
+	ContentType string
+	// CredentialsFile is a service account key file to sign with
+	CredentialsFile string
+	// ServiceAccount is signed for via IAM signBlob, impersonating it with
+	// the current credentials
+	ServiceAccount string
+	// Recursive selects every object under a prefix
+	Recursive bool
+}
+
+// SignedURL is a signed URL for one object.
+type SignedURL struct {
+	Path    string    `json:"path"`
+	Method  string    `json:"method"`
+	Expires time.Time `json:"expires"`
+	URL     string    `json:"url"`
+}
+
+// SignURLs creates a V4 signed URL for each object object selects: a single
+// object, every object under a prefix (recursive only), or every object
+// matching a wildcard. A PUT URL for a single object does not require the
+// object to exist, so it can be handed out for uploads.
+//
+// Without CredentialsFile or ServiceAccount the client's own credentials
+// sign: a service account key from ADC, or IAM signBlob for the attached
+// service account on GCE/Cloud Run.
+func SignURLs(ctx context.Context, client *storage.Client, bucket, object string, opts *SignURLOptions) ([]SignedURL, error) {
+	method := strings.ToUpper(opts.Method)
+	if method != "GET" && method != "PUT" {
+		return nil, fmt.Errorf("unsupported method %q (use GET or PUT)", opts.Method)
+	}
+	if opts.Expires <= 0 || opts.Expires > MaxSignedURLExpiry {
+		return nil, fmt.Errorf("expiry must be between 1s and 7d, got %s", opts.Expires)
+	}
+	if _, generation := resolver.SplitGeneration(object); generation != 0 {
+		return nil, fmt.Errorf("signed URLs for a specific generation are not supported")
+	}
+
+	signOpts, err := signingOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	signOpts.Scheme = storage.SigningSchemeV4
+	signOpts.Method = method
+	signOpts.ContentType = opts.ContentType
+	expires := time.Now().Add(opts.Expires)
+	signOpts.Expires = expires
+
+	var names []string
+	if method == "PUT" && object != "" && !strings.HasSuffix(object, "/") && !resolver.HasWildcard(object) {
+		names = []string{object}
+	} else {
+		objects, err := expandObjects(ctx, client, bucket, object, opts.Recursive)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			names = append(names, objectName(bucket, obj))
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no objects found matching gs://%s/%s", bucket, object)
+	}
+
+	bkt := client.Bucket(bucket)
+	urls := make([]SignedURL, 0, len(names))
+	for _, name := range names {
+		apilog.Logf("[GCS] SignedURL(gs://%s/%s, method=%s)", bucket, name, method)
+		url, err := bkt.SignedURL(name, signOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign gs://%s/%s: %w", bucket, name, err)
+		}
+		urls = append(urls, SignedURL{
+			Path:    fmt.Sprintf("gs://%s/%s", bucket, name),
+			Method:  method,
+			Expires: expires,
+			URL:     url,
+		})
+	}
+	return urls, nil
+}
+
+// signingOptions returns the signer part of the signed URL options: a key
+// file's private key, IAM signBlob for an impersonated service account, or
+// nothing (the client detects a signer from its credentials).
+func signingOptions(ctx context.Context, opts *SignURLOptions) (*storage.SignedURLOptions, error) {
+	switch {
+	case opts.CredentialsFile != "" && opts.ServiceAccount != "":
+		return nil, fmt.Errorf("use either a credentials file or a service account to impersonate, not both")
+
+	case opts.CredentialsFile != "":
+		creds, err := iam.CredentialsFromFile(ctx, opts.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		conf, err := google.JWTConfigFromJSON(creds.JSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse credentials (a service account key file is required): %w", err)
+		}
+		return &storage.SignedURLOptions{GoogleAccessID: conf.Email, PrivateKey: conf.PrivateKey}, nil
+
+	case opts.ServiceAccount != "":
+		sign := func(b []byte) ([]byte, error) {
+			return iam.SignBlob(ctx, opts.ServiceAccount, b)
+		}
+		return &storage.SignedURLOptions{GoogleAccessID: opts.ServiceAccount, SignBytes: sign}, nil
+	}
+	return &storage.SignedURLOptions{}, nil
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
+)
+
+// UploadStream uploads everything read from r (typically stdin) to a single
+// GCS object. gcsPath must name an object, since there is no local filename
+// to append. The size is unknown up front, so the data is sent as one
+// resumable upload rather than as composite parts.
+//
+// Status is written to stderr so the command can sit in a shell pipeline.
+func UploadStream(ctx context.Context, client *storage.Client, r io.Reader, gcsPath string, verbose bool, formatter PathFormatter, opts *UploadOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	bucket, objectPath, err := resolver.ParseGCSPath(gcsPath)
+	if err != nil {
+		return err
+	}
+	if objectPath == "" || objectPath[len(objectPath)-1] == '/' {
+		return fmt.Errorf("destination must be an object name when copying from stdin: %s", formatter(gcsPath))
+	}
+
+	fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, objectPath)
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Uploading stdin to %s\n", formatter(fullGCSPath))
+	}
+
+	obj := client.Bucket(bucket).Object(objectPath)
+	apilog.Logf("[GCS] Object.NewWriter(gs://%s/%s)", bucket, objectPath)
+	writer := opts.destination(obj).NewWriter(ctx)
+	encryptWriter(writer)
+	if opts != nil {
+		opts.Metadata.applyTo(&writer.ObjectAttrs)
+	}
+
+	h := newTransferHash()
+	written, err := io.Copy(writer, opts.limit(ctx, io.TeeReader(r, h)))
+	if err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to upload stdin: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close writer: %w", err)
+	}
+
+	verify := opts == nil || !opts.NoVerify
+	if verify {
+		if err := verifyUploadedObject(writer.Attrs(), h); err != nil {
+			return fmt.Errorf("upload of stdin failed verification: %w", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Uploaded: - → %s (%s%s)\n", formatter(fullGCSPath), FormatSize(written), verifiedSuffix(verify))
+	return nil
+}
+
+// DownloadStream writes a GCS object to w (typically stdout).
+// An object of the form "name#generation" streams that object version.
+//
+// Objects at or above opts.ParallelThreshold are fetched as parallel range
+// reads, like downloadFileParallel, but reassembled in order so w receives a
+// sequential stream. At most opts.MaxChunks chunks are buffered at a time.
+// Content is verified against the object's CRC32C/MD5 as it is written; since
+// bytes already sent to w cannot be taken back, a mismatch is reported as an
+// error after the fact.
+//
+// Status is written to stderr (verbose only) so stdout carries just the data.
+func DownloadStream(ctx context.Context, client *storage.Client, bucket, object string, w io.Writer, verbose bool, formatter PathFormatter, opts *DownloadOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, object)
+
+	name, generation := resolver.SplitGeneration(object)
+	obj := client.Bucket(bucket).Object(name)
+	if generation != 0 {
+		obj = obj.Generation(generation)
+	}
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s)", bucket, object)
+	attrs, err := objectAttrs(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("failed to get object attributes: %w", err)
+	}
+	if obj, err = readHandle(obj, attrs); err != nil {
+		return err
+	}
+
+	verify := opts == nil || !opts.NoVerify
+	h := newTransferHash()
+	out := io.MultiWriter(w, h)
+
+	// Gzip-encoded objects go through the single reader so decompressive
+	// transcoding applies consistently to the whole stream.
+	useParallel := opts != nil && attrs.Size >= opts.ParallelThreshold && attrs.ContentEncoding != "gzip"
+
+	if useParallel {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Streaming %s to stdout (parallel mode, %d bytes)\n", formatter(fullGCSPath), attrs.Size)
+		}
+		apilog.Logf("[GCS] Object.NewRangeReader(gs://%s/%s, chunk size=%d)", bucket, object, opts.ChunkSize)
+		fetch := func(ctx context.Context, offset, length int64) ([]byte, error) {
+			reader, err := obj.NewRangeReader(ctx, offset, length)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create range reader: %w", err)
+			}
+			defer reader.Close()
+			buf := make([]byte, length)
+			if _, err := io.ReadFull(opts.limit(ctx, reader), buf); err != nil {
+				return nil, err
+			}
+			return buf, nil
+		}
+		if err := streamChunksInOrder(ctx, attrs.Size, out, opts.ChunkSize, opts.MaxChunks, fetch); err != nil {
+			return fmt.Errorf("failed to stream %s: %w", formatter(fullGCSPath), err)
+		}
+	} else {
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Streaming %s to stdout\n", formatter(fullGCSPath))
+		}
+		apilog.Logf("[GCS] Object.NewReader(gs://%s/%s)", bucket, object)
+		reader, err := obj.NewReader(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read from GCS: %w", err)
+		}
+		defer reader.Close()
+
+		if _, err := io.Copy(out, opts.limit(ctx, reader)); err != nil {
+			return fmt.Errorf("failed to stream %s: %w", formatter(fullGCSPath), err)
+		}
+		if reader.Attrs.Decompressed {
+			verify = false
+		}
+	}
+
+	if verify {
+		if err := h.verify(attrs.CRC32C, attrs.MD5); err != nil {
+			return fmt.Errorf("stream of %s failed verification: %w", formatter(fullGCSPath), err)
+		}
+	}
+
+	if verbose {
+		fmt.Fprintf(os.Stderr, "Downloaded: %s → - (%d bytes%s)\n", formatter(fullGCSPath), h.n, verifiedSuffix(verify))
+	}
+	return nil
+}
+
+// streamChunksInOrder fetches size bytes as parallel chunkSize range reads
+// and writes them to w in order. A worker slot is released only once its
+// chunk has been written, which bounds memory to workers * chunkSize.
+func streamChunksInOrder(ctx context.Context, size int64, w io.Writer, chunkSize int64, workers int, fetch func(ctx context.Context, offset, length int64) ([]byte, error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	numChunks := int((size + chunkSize - 1) / chunkSize)
+	if workers < 1 {
+		workers = 1
+	}
+
+	type chunkResult struct {
+		data []byte
+		err  error
+	}
+	results := make([]chan chunkResult, numChunks)
+	for i := range results {
+		results[i] = make(chan chunkResult, 1)
+	}
+
+	sem := make(chan struct{}, workers)
+	go func() {
+		for i := 0; i < numChunks; i++ {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			offset := int64(i) * chunkSize
+			length := chunkSize
+			if offset+length > size {
+				length = size - offset
+			}
+			go func(i int, offset, length int64) {
+				buf, err := fetch(ctx, offset, length)
+				if err != nil {
+					err = fmt.Errorf("failed to read chunk %d: %w", i, err)
+				}
+				results[i] <- chunkResult{data: buf, err: err}
+			}(i, offset, length)
+		}
+	}()
+
+	for i := 0; i < numChunks; i++ {
+		res := <-results[i]
+		if res.err != nil {
+			return res.err
+		}
+		if _, err := w.Write(res.data); err != nil {
+			return err
+		}
+		<-sem
+	}
+	return nil
+}
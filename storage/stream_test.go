@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamChunksInOrder(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	tests := []struct {
+		name      string
+		size      int
+		chunkSize int64
+		workers   int
+	}{
+		{"single chunk", len(data), 64, 4},
+		{"exact multiple", 40, 8, 3},
+		{"short last chunk", len(data), 5, 4},
+		{"one worker", len(data), 3, 1},
+		{"no workers configured", len(data), 7, 0},
+		{"more workers than chunks", len(data), 16, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := data[:tt.size]
+			maxWorkers := max(tt.workers, 1)
+
+			var mu sync.Mutex
+			inFlight, peak := 0, 0
+			fetch := func(ctx context.Context, offset, length int64) ([]byte, error) {
+				mu.Lock()
+				inFlight++
+				peak = max(peak, inFlight)
+				mu.Unlock()
+				// Later chunks finish first, so the output only comes out
+				// in order if the writer waits for each chunk in turn.
+				time.Sleep(time.Duration(int64(tt.size)-offset) * 100 * time.Microsecond)
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+				return append([]byte(nil), want[offset:offset+length]...), nil
+			}
+
+			var out bytes.Buffer
+			if err := streamChunksInOrder(context.Background(), int64(tt.size), &out, tt.chunkSize, tt.workers, fetch); err != nil {
+				t.Fatalf("streamChunksInOrder() = %v", err)
+			}
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Errorf("streamChunksInOrder() wrote %q, want %q", out.Bytes(), want)
+			}
+			if peak > maxWorkers {
+				t.Errorf("%d chunks fetched at once, want at most %d", peak, maxWorkers)
+			}
+		})
+	}
+}
+
+func TestStreamChunksInOrderStopsOnError(t *testing.T) {
+	errRead := errors.New("read failed")
+	fetch := func(ctx context.Context, offset, length int64) ([]byte, error) {
+		if offset == 4 {
+			return nil, errRead
+		}
+		return bytes.Repeat([]byte("x"), int(length)), nil
+	}
+
+	var out bytes.Buffer
+	err := streamChunksInOrder(context.Background(), 12, &out, 4, 2, fetch)
+	if !errors.Is(err, errRead) {
+		t.Fatalf("streamChunksInOrder() = %v, want %v", err, errRead)
+	}
+	if got := out.String(); got != "xxxx" {
+		t.Errorf("streamChunksInOrder() wrote %q before the failed chunk, want %q", got, "xxxx")
+	}
+}
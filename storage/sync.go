@@ -0,0 +1,515 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"google.golang.org/api/iterator"
+)
+
+// MtimeMetadataKey is the custom metadata key holding a file's original
+// modification time (Unix seconds). It is the same key gsutil rsync uses, so
+// objects uploaded by either tool compare by mtime without re-hashing.
+const MtimeMetadataKey = "goog-reserved-file-mtime"
+
+// crc32cTable is the Castagnoli table GCS uses for object CRC32C checksums.
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// SyncOptions configures a sync run
+type SyncOptions struct {
+	// Delete removes destination files that do not exist in the source
+	Delete bool
+	// DryRun prints the plan without copying or deleting anything
+	DryRun bool
+	// Checksum always compares checksums, even when size and mtime match
+	Checksum bool
+}
+
+// syncEntry describes one file on either side of a sync, keyed by its path
+// relative to the sync root.
+type syncEntry struct {
+	relPath string
+	size    int64
+	mtime   time.Time // zero if unknown
+	crc32c  uint32
+	hasCRC  bool   // true for GCS objects (CRC32C is always present)
+	md5     []byte // GCS objects only; nil for composite objects
+	local   string // absolute local path for local entries
+	// generation of GCS objects, so deletes only remove what was compared
+	generation int64
+	keySHA256  string // CSEK of GCS objects, "" for none
+}
+
+// syncCopy is one planned transfer together with the reason it is needed.
+type syncCopy struct {
+	src    *syncEntry
+	reason string
+}
+
+// syncPlan is the result of comparing a source and destination tree.
+type syncPlan struct {
+	copies    []syncCopy
+	deletes   []*syncEntry
+	unchanged int
+}
+
+// SyncLocalToGCS mirrors a local directory to a GCS prefix, uploading only
+// new or changed files. Uploaded objects carry the file mtime in metadata so
+// later runs can skip unchanged files without hashing them.
+func SyncLocalToGCS(ctx context.Context, client *storage.Client, localDir, bucket, prefix string, verbose bool, formatter PathFormatter, maxWorkers int, opts *SyncOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	prefix = syncPrefix(prefix)
+
+	src, err := listLocalTree(localDir, true)
+	if err != nil {
+		return err
+	}
+	dst, err := listGCSTree(ctx, client, bucket, prefix)
+	if err != nil {
+		return err
+	}
+
+	plan, err := planSync(src, dst, opts, maxWorkers)
+	if err != nil {
+		return err
+	}
+
+	gcsPath := func(rel string) string { return fmt.Sprintf("gs://%s/%s%s", bucket, prefix, rel) }
+
+	if opts.DryRun {
+		for _, c := range plan.copies {
+			fmt.Printf("Would upload: %s → %s (%s)\n", c.src.local, formatter(gcsPath(c.src.relPath)), c.reason)
+		}
+		for _, d := range plan.deletes {
+			fmt.Printf("Would delete: %s\n", formatter(gcsPath(d.relPath)))
+		}
+		printSyncSummary(plan, true)
+		return nil
+	}
+
+	if len(plan.copies) > 0 {
+		files := make([]fileUpload, len(plan.copies))
+		for i, c := range plan.copies {
+			files[i] = fileUpload{
+				localPath:   c.src.local,
+				objectPath:  prefix + c.src.relPath,
+				fullGCSPath: gcsPath(c.src.relPath),
+				mtime:       c.src.mtime,
+				size:        c.src.size,
+			}
+		}
+		if err := uploadFilesParallel(ctx, client, bucket, files, len(files), verbose, formatter, maxWorkers, nil); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.deletes) > 0 {
+		enumerate := func(ctx context.Context, send func(item workItem)) error {
+			for _, d := range plan.deletes {
+				send(workItem{name: prefix + d.relPath, ifGeneration: d.generation, size: d.size})
+			}
+			return nil
+		}
+		if err := deleteObjectsStream(ctx, client, bucket, enumerate, "nothing to delete", formatter, maxWorkers); err != nil {
+			return err
+		}
+	}
+
+	printSyncSummary(plan, false)
+	return nil
+}
+
+// SyncGCSToLocal mirrors a GCS prefix to a local directory, downloading only
+// new or changed objects. Downloaded files get the object's mtime so later
+// runs can skip them without hashing.
+func SyncGCSToLocal(ctx context.Context, client *storage.Client, bucket, prefix, localDir string, verbose bool, formatter PathFormatter, maxWorkers int, opts *SyncOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	prefix = syncPrefix(prefix)
+
+	src, err := listGCSTree(ctx, client, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	dst, err := listLocalTree(localDir, false)
+	if err != nil {
+		return err
+	}
+
+	plan, err := planSync(src, dst, opts, maxWorkers)
+	if err != nil {
+		return err
+	}
+
+	gcsPath := func(rel string) string { return fmt.Sprintf("gs://%s/%s%s", bucket, prefix, rel) }
+	localPath := func(rel string) string { return filepath.Join(localDir, filepath.FromSlash(rel)) }
+
+	if opts.DryRun {
+		for _, c := range plan.copies {
+			fmt.Printf("Would download: %s → %s (%s)\n", formatter(gcsPath(c.src.relPath)), localPath(c.src.relPath), c.reason)
+		}
+		for _, d := range plan.deletes {
+			fmt.Printf("Would delete: %s\n", d.local)
+		}
+		printSyncSummary(plan, true)
+		return nil
+	}
+
+	if len(plan.copies) > 0 {
+		files := make([]fileDownload, len(plan.copies))
+		for i, c := range plan.copies {
+			files[i] = fileDownload{
+				objectName:    prefix + c.src.relPath,
+				localFilePath: localPath(c.src.relPath),
+				fullGCSPath:   gcsPath(c.src.relPath),
+				size:          c.src.size,
+				mtime:         c.src.mtime,
+				keySHA256:     c.src.keySHA256,
+				keyListed:     true,
+			}
+		}
+		// Force: the plan already decided what needs transferring.
+		if err := downloadFilesParallel(ctx, client, bucket, files, len(files), verbose, formatter, maxWorkers, &DownloadOptions{Force: true}); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range plan.deletes {
+		if err := os.Remove(d.local); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", d.local, err)
+		}
+		fmt.Printf("Deleted: %s\n", d.local)
+	}
+
+	printSyncSummary(plan, false)
+	return nil
+}
+
+// SyncGCSToGCS mirrors one GCS prefix to another using server-side rewrites.
+// Objects are compared by size and CRC32C (both always known on GCS).
+func SyncGCSToGCS(ctx context.Context, client *storage.Client, srcBucket, srcPrefix, dstBucket, dstPrefix string, verbose bool, formatter PathFormatter, maxWorkers int, opts *SyncOptions) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	srcPrefix = syncPrefix(srcPrefix)
+	dstPrefix = syncPrefix(dstPrefix)
+
+	src, err := listGCSTree(ctx, client, srcBucket, srcPrefix)
+	if err != nil {
+		return err
+	}
+	dst, err := listGCSTree(ctx, client, dstBucket, dstPrefix)
+	if err != nil {
+		return err
+	}
+
+	plan, err := planSync(src, dst, opts, maxWorkers)
+	if err != nil {
+		return err
+	}
+
+	srcPath := func(rel string) string { return fmt.Sprintf("gs://%s/%s%s", srcBucket, srcPrefix, rel) }
+	dstPath := func(rel string) string { return fmt.Sprintf("gs://%s/%s%s", dstBucket, dstPrefix, rel) }
+
+	if opts.DryRun {
+		for _, c := range plan.copies {
+			fmt.Printf("Would copy: %s → %s (%s)\n", formatter(srcPath(c.src.relPath)), formatter(dstPath(c.src.relPath)), c.reason)
+		}
+		for _, d := range plan.deletes {
+			fmt.Printf("Would delete: %s\n", formatter(dstPath(d.relPath)))
+		}
+		printSyncSummary(plan, true)
+		return nil
+	}
+
+	if len(plan.copies) > 0 {
+		files := make([]fileCopy, len(plan.copies))
+		for i, c := range plan.copies {
+			files[i] = fileCopy{
+				srcObject:  srcPrefix + c.src.relPath,
+				dstObject:  dstPrefix + c.src.relPath,
+				srcGCSPath: srcPath(c.src.relPath),
+				dstGCSPath: dstPath(c.src.relPath),
+			}
+		}
+		if err := copyObjectsParallel(ctx, client, srcBucket, dstBucket, files, len(files), verbose, formatter, maxWorkers); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.deletes) > 0 {
+		enumerate := func(ctx context.Context, send func(item workItem)) error {
+			for _, d := range plan.deletes {
+				send(workItem{name: dstPrefix + d.relPath, ifGeneration: d.generation, size: d.size})
+			}
+			return nil
+		}
+		if err := deleteObjectsStream(ctx, client, dstBucket, enumerate, "nothing to delete", formatter, maxWorkers); err != nil {
+			return err
+		}
+	}
+
+	printSyncSummary(plan, false)
+	return nil
+}
+
+// syncPrefix normalizes a GCS sync root so relative paths never start with "/".
+func syncPrefix(prefix string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		return prefix + "/"
+	}
+	return prefix
+}
+
+// listLocalTree walks root and returns every regular file keyed by its
+// slash-separated path relative to root. A missing destination root yields
+// an empty tree so that syncing into a new directory works; a missing source
+// root is an error, so a mistyped path never looks like an empty source.
+// Unfinished downloads are left out, both the journal and the partial file
+// it describes: they are never uploaded or deleted, and a download into the
+// tree resumes them.
+func listLocalTree(root string, isSource bool) (map[string]*syncEntry, error) {
+	entries := make(map[string]*syncEntry)
+	info, err := os.Stat(root)
+	switch {
+	case os.IsNotExist(err) && !isSource:
+		return entries, nil
+	case os.IsNotExist(err):
+		return nil, fmt.Errorf("source directory %s does not exist", root)
+	case err != nil:
+		return nil, err
+	case !info.IsDir():
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	var partial []string
+	err = filepath.Walk(root, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+		relPath = filepath.ToSlash(relPath)
+		if isDownloadJournal(relPath) {
+			partial = append(partial, strings.TrimSuffix(relPath, DownloadJournalSuffix))
+			return nil
+		}
+		entries[relPath] = &syncEntry{
+			relPath: relPath,
+			size:    info.Size(),
+			mtime:   info.ModTime(),
+			local:   path,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, relPath := range partial {
+		delete(entries, relPath)
+	}
+	return entries, nil
+}
+
+// listGCSTree lists every object under prefix and returns them keyed by their
+// path relative to prefix. Directory markers and, at the bucket root, staged
+// composite upload parts are skipped: a sync must neither copy the parts nor
+// delete those of an upload that is still running or can be resumed.
+func listGCSTree(ctx context.Context, client *storage.Client, bucket, prefix string) (map[string]*syncEntry, error) {
+	entries := make(map[string]*syncEntry)
+
+	query := &storage.Query{Prefix: prefix}
+	if err := query.SetAttrSelection([]string{"Name", "Size", "CRC32C", "MD5", "Metadata", "Updated", "Generation", "CustomerKeySHA256"}); err != nil {
+		return nil, fmt.Errorf("SetAttrSelection: %w", err)
+	}
+
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for sync", bucket, prefix)
+	it := client.Bucket(bucket).Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		if strings.HasSuffix(attrs.Name, "/") || isStagedPart(prefix, attrs.Name) {
+			continue
+		}
+		relPath := strings.TrimPrefix(attrs.Name, prefix)
+		if relPath == "" {
+			continue
+		}
+		entries[relPath] = &syncEntry{
+			relPath: relPath,
+			size:    attrs.Size,
+			mtime:   objectMtime(attrs),
+			crc32c:  attrs.CRC32C,
+			hasCRC:  true,
+			md5:     attrs.MD5,
+
+			generation: attrs.Generation,
+			keySHA256:  attrs.CustomerKeySHA256,
+		}
+	}
+	return entries, nil
+}
+
+// objectMtime returns the original file mtime recorded in object metadata,
+// falling back to the object's last update time.
+func objectMtime(attrs *storage.ObjectAttrs) time.Time {
+	if v, ok := attrs.Metadata[MtimeMetadataKey]; ok {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(secs, 0)
+		}
+	}
+	return attrs.Updated
+}
+
+// planSync compares src against dst. A file is copied when it is missing or
+// its size differs; same-size files are skipped when their mtimes match
+// (to the second) and otherwise compared by CRC32C/MD5. Checksums of local
+// files are computed in parallel, bounded by maxWorkers.
+//
+// With opts.Delete an empty source is refused: it would delete the whole
+// destination, which is far more likely a wrong source path than intended.
+func planSync(src, dst map[string]*syncEntry, opts *SyncOptions, maxWorkers int) (*syncPlan, error) {
+	if opts.Delete && len(src) == 0 && len(dst) > 0 {
+		return nil, fmt.Errorf("source is empty: refusing to delete all %d destination file(s) with --delete (use 'cio rm -r' to clear the destination)", len(dst))
+	}
+	plan := &syncPlan{}
+
+	// Entries that need a content comparison, resolved in parallel below.
+	var toHash []*syncEntry
+
+	for rel, s := range src {
+		d, ok := dst[rel]
+		switch {
+		case !ok:
+			plan.copies = append(plan.copies, syncCopy{src: s, reason: "new"})
+		case s.size != d.size:
+			plan.copies = append(plan.copies, syncCopy{src: s, reason: "size differs"})
+		case s.hasCRC && d.hasCRC:
+			// Both sides on GCS: checksums are free, always compare.
+			if s.crc32c != d.crc32c || (s.md5 != nil && d.md5 != nil && !bytes.Equal(s.md5, d.md5)) {
+				plan.copies = append(plan.copies, syncCopy{src: s, reason: "checksum differs"})
+			} else {
+				plan.unchanged++
+			}
+		case !opts.Checksum && !s.mtime.IsZero() && s.mtime.Unix() == d.mtime.Unix():
+			plan.unchanged++
+		default:
+			toHash = append(toHash, s)
+		}
+	}
+
+	if len(toHash) > 0 {
+		if maxWorkers < 1 {
+			maxWorkers = 1
+		}
+		differs := make([]bool, len(toHash))
+		errs := make([]error, len(toHash))
+		sem := make(chan struct{}, maxWorkers)
+		var wg sync.WaitGroup
+		for i, s := range toHash {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, s *syncEntry) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				differs[i], errs[i] = contentDiffers(s, dst[s.relPath])
+			}(i, s)
+		}
+		wg.Wait()
+
+		for i, s := range toHash {
+			if errs[i] != nil {
+				return nil, errs[i]
+			}
+			if differs[i] {
+				plan.copies = append(plan.copies, syncCopy{src: s, reason: "checksum differs"})
+			} else {
+				plan.unchanged++
+			}
+		}
+	}
+
+	if opts.Delete {
+		for rel, d := range dst {
+			if _, ok := src[rel]; !ok {
+				plan.deletes = append(plan.deletes, d)
+			}
+		}
+	}
+
+	sort.Slice(plan.copies, func(i, j int) bool { return plan.copies[i].src.relPath < plan.copies[j].src.relPath })
+	sort.Slice(plan.deletes, func(i, j int) bool { return plan.deletes[i].relPath < plan.deletes[j].relPath })
+	return plan, nil
+}
+
+// contentDiffers compares a local file against a GCS object (in either
+// order) by computing the local file's CRC32C.
+func contentDiffers(a, b *syncEntry) (bool, error) {
+	local, remote := a, b
+	if local.local == "" {
+		local, remote = b, a
+	}
+	sum, err := fileCRC32C(local.local)
+	if err != nil {
+		return false, err
+	}
+	return sum != remote.crc32c, nil
+}
+
+// fileCRC32C computes the CRC32C (Castagnoli) checksum of a local file.
+func fileCRC32C(path string) (uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := crc32.New(crc32cTable)
+	if _, err := io.Copy(h, f); err != nil {
+		return 0, fmt.Errorf("failed to checksum %s: %w", path, err)
+	}
+	return h.Sum32(), nil
+}
+
+// printSyncSummary prints the final copied/deleted/unchanged counts.
+func printSyncSummary(plan *syncPlan, dryRun bool) {
+	if dryRun {
+		fmt.Printf("\nDry run: %d to copy, %d to delete, %d unchanged\n", len(plan.copies), len(plan.deletes), plan.unchanged)
+		return
+	}
+	fmt.Printf("\nSync complete: %d copied, %d deleted, %d unchanged\n", len(plan.copies), len(plan.deletes), plan.unchanged)
+}
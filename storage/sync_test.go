@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanSync(t *testing.T) {
+	mtime := time.Unix(1700000000, 0)
+	gcs := func(rel string, size int64, crc uint32) *syncEntry {
+		return &syncEntry{relPath: rel, size: size, crc32c: crc, hasCRC: true, mtime: mtime}
+	}
+	local := func(rel string, size int64, mt time.Time) *syncEntry {
+		return &syncEntry{relPath: rel, size: size, mtime: mt, local: "/nonexistent/" + rel}
+	}
+	tree := func(entries ...*syncEntry) map[string]*syncEntry {
+		m := make(map[string]*syncEntry)
+		for _, e := range entries {
+			m[e.relPath] = e
+		}
+		return m
+	}
+
+	tests := []struct {
+		name      string
+		src, dst  map[string]*syncEntry
+		opts      SyncOptions
+		copies    []string
+		deletes   []string
+		unchanged int
+		wantErr   string
+	}{
+		{
+			name:   "new file",
+			src:    tree(gcs("a", 1, 1)),
+			dst:    tree(),
+			copies: []string{"a: new"},
+		},
+		{
+			name:   "size differs",
+			src:    tree(gcs("a", 1, 1)),
+			dst:    tree(gcs("a", 2, 1)),
+			copies: []string{"a: size differs"},
+		},
+		{
+			name:   "checksum differs on GCS",
+			src:    tree(gcs("a", 1, 1)),
+			dst:    tree(gcs("a", 1, 2)),
+			copies: []string{"a: checksum differs"},
+		},
+		{
+			name:      "same checksum on GCS",
+			src:       tree(gcs("a", 1, 1)),
+			dst:       tree(gcs("a", 1, 1)),
+			unchanged: 1,
+		},
+		{
+			name:      "same size and mtime",
+			src:       tree(local("a", 1, mtime)),
+			dst:       tree(gcs("a", 1, 7)),
+			unchanged: 1,
+		},
+		{
+			name:      "extra destination file kept without --delete",
+			src:       tree(gcs("a", 1, 1)),
+			dst:       tree(gcs("a", 1, 1), gcs("b", 1, 1)),
+			unchanged: 1,
+		},
+		{
+			name:      "extra destination file deleted with --delete",
+			src:       tree(gcs("a", 1, 1)),
+			dst:       tree(gcs("a", 1, 1), gcs("c", 1, 1), gcs("b", 1, 1)),
+			opts:      SyncOptions{Delete: true},
+			deletes:   []string{"b", "c"},
+			unchanged: 1,
+		},
+		{
+			name:    "empty source with --delete is refused",
+			src:     tree(),
+			dst:     tree(gcs("a", 1, 1), gcs("b", 1, 1)),
+			opts:    SyncOptions{Delete: true},
+			wantErr: "refusing to delete all 2",
+		},
+		{
+			name: "empty source without --delete does nothing",
+			src:  tree(),
+			dst:  tree(gcs("a", 1, 1)),
+		},
+		{
+			name: "empty source and destination with --delete",
+			src:  tree(),
+			dst:  tree(),
+			opts: SyncOptions{Delete: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := planSync(tt.src, tt.dst, &tt.opts, 2)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var copies, deletes []string
+			for _, c := range plan.copies {
+				copies = append(copies, c.src.relPath+": "+c.reason)
+			}
+			for _, d := range plan.deletes {
+				deletes = append(deletes, d.relPath)
+			}
+			if !reflect.DeepEqual(copies, tt.copies) {
+				t.Errorf("copies = %v, want %v", copies, tt.copies)
+			}
+			if !reflect.DeepEqual(deletes, tt.deletes) {
+				t.Errorf("deletes = %v, want %v", deletes, tt.deletes)
+			}
+			if plan.unchanged != tt.unchanged {
+				t.Errorf("unchanged = %d, want %d", plan.unchanged, tt.unchanged)
+			}
+		})
+	}
+}
+
+func TestPlanSyncHashesLocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum := crc32.Checksum([]byte("hello"), crc32cTable)
+
+	src := map[string]*syncEntry{"a": {relPath: "a", size: 5, mtime: time.Unix(1, 0), local: path}}
+	for _, tt := range []struct {
+		crc    uint32
+		copies int
+	}{{sum, 0}, {sum + 1, 1}} {
+		dst := map[string]*syncEntry{"a": {relPath: "a", size: 5, mtime: time.Unix(2, 0), crc32c: tt.crc, hasCRC: true}}
+		plan, err := planSync(src, dst, &SyncOptions{}, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(plan.copies) != tt.copies {
+			t.Errorf("crc %08x: %d copies, want %d", tt.crc, len(plan.copies), tt.copies)
+		}
+	}
+}
+
+func TestListLocalTreeMissingRoot(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := listLocalTree(missing, true); err == nil {
+		t.Error("missing source: want error")
+	}
+	entries, err := listLocalTree(missing, false)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("missing destination = %v, %v; want empty tree", entries, err)
+	}
+}
+
+func TestListLocalTreeSkipsUnfinishedDownloads(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"done.bin", "part.bin", "part.bin" + DownloadJournalSuffix, "sub/orphan" + DownloadJournalSuffix} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := listLocalTree(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for rel := range entries {
+		got = append(got, rel)
+	}
+	if !reflect.DeepEqual(got, []string{"done.bin"}) {
+		t.Errorf("listLocalTree = %v, want [done.bin]", got)
+	}
+
+	// The partial file is missing from the destination, so it is downloaded
+	// (resuming from its journal), and --delete removes neither file
+	sum := crc32.Checksum([]byte("x"), crc32cTable)
+	src := map[string]*syncEntry{
+		"done.bin": {relPath: "done.bin", size: 1, crc32c: sum, hasCRC: true},
+		"part.bin": {relPath: "part.bin", size: 1, crc32c: sum, hasCRC: true},
+	}
+	plan, err := planSync(src, entries, &SyncOptions{Delete: true}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.copies) != 1 || plan.copies[0].src.relPath != "part.bin" || len(plan.deletes) != 0 {
+		t.Errorf("plan copies %d, deletes %d; want part.bin copied, nothing deleted", len(plan.copies), len(plan.deletes))
+	}
+}
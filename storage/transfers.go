@@ -0,0 +1,406 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// Transfer directions.
+const (
+	TransferUpload   = "upload"
+	TransferDownload = "download"
+)
+
+// TransferStore keeps the manifests of recursive copies (cp -r) so an
+// interrupted or partly failed transfer can be resumed without relisting.
+// Each transfer has three files in Dir:
+//
+//	<id>.json        the Transfer header
+//	<id>.items.jsonl the source snapshot, one TransferItem per line
+//	<id>.log         an append-only log of finished items
+//
+// The log is appended to as items finish, so it survives a killed process;
+// the last entry for an item wins.
+type TransferStore struct {
+	Dir string
+}
+
+// Transfer is the header of a recorded transfer.
+type Transfer struct {
+	ID          string    `json:"id"`
+	Direction   string    `json:"direction"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Bucket      string    `json:"bucket"`
+	Created     time.Time `json:"created"`
+	Total       int       `json:"total"`
+	TotalBytes  int64     `json:"total_bytes"`
+}
+
+// TransferItem is one file/object of a transfer snapshot. Downloads record
+// the generation listed, so a resumed download fetches exactly that version.
+type TransferItem struct {
+	Object     string `json:"object"`
+	Local      string `json:"local"`
+	Size       int64  `json:"size"`
+	Generation int64  `json:"generation,omitempty"`
+}
+
+// TransferStatus is a transfer with the state of its items replayed from
+// the log.
+type TransferStatus struct {
+	Transfer
+	Done    int       `json:"done"`
+	Failed  int       `json:"failed"`
+	Pending int       `json:"pending"`
+	Updated time.Time `json:"updated"`
+	// Errors maps the local path of each failed item to its last error
+	Errors map[string]string `json:"-"`
+
+	done map[string]bool // local paths of the items done
+}
+
+// State summarizes the transfer: "complete", "failed" (every item was tried,
+// some failed) or "incomplete" (items were never tried, e.g. interrupted).
+func (s *TransferStatus) State() string {
+	switch {
+	case s.Pending > 0:
+		return "incomplete"
+	case s.Failed > 0:
+		return "failed"
+	}
+	return "complete"
+}
+
+// transferLogEntry is one line of <id>.log.
+type transferLogEntry struct {
+	Local string `json:"local"`
+	Error string `json:"error,omitempty"`
+}
+
+func (s *TransferStore) path(id, suffix string) string {
+	return filepath.Join(s.Dir, id+suffix)
+}
+
+// newRunID returns a sortable, unique ID for a transfer or trash batch, such as
+// "20261016-153012-3fa9".
+func newRunID() string {
+	b := make([]byte, 2)
+	rand.Read(b)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// create records a new transfer and returns a recorder for its log.
+func (s *TransferStore) create(direction, source, destination, bucket string, items []TransferItem) (*transferRecorder, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create transfer directory: %w", err)
+	}
+
+	t := Transfer{
+		ID:          newRunID(),
+		Direction:   direction,
+		Source:      source,
+		Destination: destination,
+		Bucket:      bucket,
+		Created:     time.Now(),
+		Total:       len(items),
+	}
+	for _, item := range items {
+		t.TotalBytes += item.Size
+	}
+
+	f, err := os.Create(s.path(t.ID, ".items.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write transfer manifest: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for i := range items {
+		if err := enc.Encode(&items[i]); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to write transfer manifest: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write transfer manifest: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write transfer manifest: %w", err)
+	}
+
+	// The header goes last: a transfer without one was never started
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.path(t.ID, ".json"), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write transfer manifest: %w", err)
+	}
+	return s.openRecorder(t.ID)
+}
+
+// openRecorder opens the log of transfer id for appending.
+func (s *TransferStore) openRecorder(id string) (*transferRecorder, error) {
+	f, err := os.OpenFile(s.path(id, ".log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transfer log: %w", err)
+	}
+	return &transferRecorder{store: s, id: id, f: f}, nil
+}
+
+// Load reads transfer id and replays its log.
+func (s *TransferStore) Load(id string) (*TransferStatus, error) {
+	data, err := os.ReadFile(s.path(id, ".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("transfer %q not found (see 'cio transfers ls')", id)
+		}
+		return nil, fmt.Errorf("failed to read transfer %s: %w", id, err)
+	}
+	status := &TransferStatus{Errors: make(map[string]string), done: make(map[string]bool)}
+	if err := json.Unmarshal(data, &status.Transfer); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer %s: %w", id, err)
+	}
+	status.Updated = status.Created
+
+	results := make(map[string]string) // local path -> last error ("" = done)
+	if f, err := os.Open(s.path(id, ".log")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var e transferLogEntry
+			if json.Unmarshal(scanner.Bytes(), &e) != nil {
+				continue // a line cut short by a crash
+			}
+			results[e.Local] = e.Error
+		}
+		if info, err := f.Stat(); err == nil && info.ModTime().After(status.Updated) {
+			status.Updated = info.ModTime()
+		}
+		f.Close()
+	}
+
+	for local, errMsg := range results {
+		if errMsg == "" {
+			status.Done++
+			status.done[local] = true
+		} else {
+			status.Failed++
+			status.Errors[local] = errMsg
+		}
+	}
+	status.Pending = status.Total - status.Done - status.Failed
+	return status, nil
+}
+
+// Items reads the snapshot of transfer id.
+func (s *TransferStore) Items(id string) ([]TransferItem, error) {
+	f, err := os.Open(s.path(id, ".items.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transfer manifest: %w", err)
+	}
+	defer f.Close()
+
+	var items []TransferItem
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var item TransferItem
+		if err := dec.Decode(&item); err != nil {
+			return nil, fmt.Errorf("failed to parse transfer manifest: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// List returns all recorded transfers, newest first.
+func (s *TransferStore) List() ([]*TransferStatus, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read transfer directory: %w", err)
+	}
+
+	var transfers []*TransferStatus
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		status, err := s.Load(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, status)
+	}
+	sort.Slice(transfers, func(i, j int) bool { return transfers[i].Created.After(transfers[j].Created) })
+	return transfers, nil
+}
+
+// Remove deletes the files of transfer id.
+func (s *TransferStore) Remove(id string) error {
+	if _, err := os.Stat(s.path(id, ".json")); err != nil {
+		return fmt.Errorf("transfer %q not found", id)
+	}
+	for _, suffix := range []string{".json", ".items.jsonl", ".log"} {
+		if err := os.Remove(s.path(id, suffix)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove transfer %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// transferRecorder appends finished items to a transfer's log. A nil
+// *transferRecorder records nothing.
+type transferRecorder struct {
+	store *TransferStore
+	id    string
+	mu    sync.Mutex
+	f     *os.File
+}
+
+// record logs the outcome of the item at localPath.
+func (r *transferRecorder) record(localPath string, err error) {
+	if r == nil {
+		return
+	}
+	e := transferLogEntry{Local: localPath}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	data, _ := json.Marshal(e)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.f.Write(append(data, '\n'))
+}
+
+// close closes the log.
+func (r *transferRecorder) close() {
+	if r != nil {
+		r.f.Close()
+	}
+}
+
+// startTransfer records a new transfer in store (if any) and prints its ID.
+func startTransfer(store *TransferStore, direction, source, destination, bucket string, items []TransferItem) *transferRecorder {
+	if store == nil {
+		return nil
+	}
+	rec, err := store.create(direction, source, destination, bucket, items)
+	if err != nil {
+		// The copy itself can go ahead; only resuming will not be possible
+		fmt.Fprintf(os.Stderr, "Warning: transfer not recorded: %v\n", err)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Transfer %s: %d item(s) (resume with 'cio cp --resume %s')\n", rec.id, len(items), rec.id)
+	return rec
+}
+
+// finishTransfer closes rec and, if items failed, says how to retry them.
+// The record of a transfer whose items have all been done is removed, as
+// there is nothing left to resume.
+func finishTransfer(rec *transferRecorder, failed bool) {
+	if rec == nil {
+		return
+	}
+	rec.close()
+	if failed {
+		fmt.Fprintf(os.Stderr, "Retry the failed items with 'cio transfers retry %s'\n", rec.id)
+		return
+	}
+	if status, err := rec.store.Load(rec.id); err == nil && status.State() == "complete" {
+		if err := rec.store.Remove(rec.id); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+}
+
+// ResumeTransfer continues transfer id: it runs the items that have not
+// finished yet or, with onlyFailed, just those that failed. Outcomes are
+// appended to the same log. dlOpts or ulOpts applies depending on the
+// transfer's direction.
+func ResumeTransfer(ctx context.Context, client *storage.Client, store *TransferStore, id string, onlyFailed, verbose bool, formatter PathFormatter, maxWorkers int, dlOpts *DownloadOptions, ulOpts *UploadOptions) error {
+	status, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	items, err := store.Items(id)
+	if err != nil {
+		return err
+	}
+
+	var selected []TransferItem
+	for _, item := range items {
+		_, failed := status.Errors[item.Local]
+		if (onlyFailed && !failed) || status.done[item.Local] {
+			continue
+		}
+		selected = append(selected, item)
+	}
+	if len(selected) == 0 {
+		if onlyFailed {
+			fmt.Printf("Transfer %s has no failed items\n", id)
+		} else {
+			fmt.Printf("Transfer %s is complete; nothing to resume\n", id)
+		}
+		return nil
+	}
+
+	rec, err := store.openRecorder(id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Transfer %s: %d of %d item(s) remaining\n", id, len(selected), status.Total)
+
+	switch status.Direction {
+	case TransferDownload:
+		files := make([]fileDownload, len(selected))
+		for i, item := range selected {
+			files[i] = fileDownload{
+				objectName:    item.Object,
+				localFilePath: item.Local,
+				fullGCSPath:   fmt.Sprintf("gs://%s/%s", status.Bucket, item.Object),
+				size:          item.Size,
+				generation:    item.Generation,
+			}
+		}
+		var o DownloadOptions
+		if dlOpts != nil {
+			o = *dlOpts
+		}
+		o.transfer = rec
+		return downloadFilesParallel(ctx, client, status.Bucket, files, len(files), verbose, formatter, maxWorkers, &o)
+	case TransferUpload:
+		files := make([]fileUpload, len(selected))
+		for i, item := range selected {
+			files[i] = fileUpload{
+				localPath:   item.Local,
+				objectPath:  item.Object,
+				fullGCSPath: fmt.Sprintf("gs://%s/%s", status.Bucket, item.Object),
+				size:        item.Size,
+			}
+		}
+		var o UploadOptions
+		if ulOpts != nil {
+			o = *ulOpts
+		}
+		o.transfer = rec
+		return uploadFilesParallel(ctx, client, status.Bucket, files, len(files), verbose, formatter, maxWorkers, &o)
+	}
+	rec.close()
+	return fmt.Errorf("transfer %s has unknown direction %q", id, status.Direction)
+}
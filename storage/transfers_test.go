@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestFinishTransfer(t *testing.T) {
+	items := []TransferItem{{Object: "a", Local: "/tmp/a"}, {Object: "b", Local: "/tmp/b"}}
+
+	tests := []struct {
+		name     string
+		results  map[string]error
+		failed   bool
+		wantKept bool
+	}{
+		{name: "all done", results: map[string]error{"/tmp/a": nil, "/tmp/b": nil}},
+		{name: "item failed", results: map[string]error{"/tmp/a": nil, "/tmp/b": errors.New("boom")}, failed: true, wantKept: true},
+		{name: "interrupted", results: map[string]error{"/tmp/a": nil}, wantKept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &TransferStore{Dir: t.TempDir()}
+			rec, err := store.create(TransferDownload, "gs://b/p/", "/tmp", "b", items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for local, err := range tt.results {
+				rec.record(local, err)
+			}
+			finishTransfer(rec, tt.failed)
+
+			_, err = store.Load(rec.id)
+			if kept := err == nil; kept != tt.wantKept {
+				t.Errorf("record kept = %v, want %v", kept, tt.wantKept)
+			}
+			if !tt.wantKept {
+				if entries, _ := os.ReadDir(store.Dir); len(entries) != 0 {
+					t.Errorf("left %d file(s) behind", len(entries))
+				}
+			}
+		})
+	}
+}
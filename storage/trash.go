@@ -0,0 +1,411 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/thieso2/cio/apilog"
+	"github.com/thieso2/cio/resolver"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+// Custom metadata recorded on every trashed object.
+const (
+	// TrashSourceMetadataKey holds the object's original gs:// path
+	TrashSourceMetadataKey = "cio-trash-source"
+	// TrashDeletedMetadataKey holds the time it was removed (RFC 3339)
+	TrashDeletedMetadataKey = "cio-trash-deleted"
+)
+
+// Trash is a GCS prefix that rm moves objects into instead of deleting them
+// (config rm.trash). Each rm run is one batch, stored as
+//
+//	<prefix><batch id>/<source bucket>/<source object>
+//
+// so a batch can be listed, restored or purged as a whole.
+type Trash struct {
+	Bucket string
+	Prefix string // ends with "/"
+}
+
+// ParseTrash parses a trash location such as "gs://bucket/.cio-trash/".
+func ParseTrash(path string) (*Trash, error) {
+	bucket, prefix, err := resolver.ParseGCSPath(path)
+	if err != nil || bucket == "" || strings.HasSuffix(bucket, ":") {
+		return nil, fmt.Errorf("invalid trash location %q: must be gs://bucket/prefix/", path)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &Trash{Bucket: bucket, Prefix: prefix}, nil
+}
+
+// String returns the gs:// path of the trash.
+func (t *Trash) String() string {
+	return fmt.Sprintf("gs://%s/%s", t.Bucket, t.Prefix)
+}
+
+// Contains reports whether bucket/object lies inside the trash.
+func (t *Trash) Contains(bucket, object string) bool {
+	return bucket == t.Bucket && strings.HasPrefix(object, t.Prefix)
+}
+
+// batchPrefix is the object prefix of batch id.
+func (t *Trash) batchPrefix(id string) string {
+	return t.Prefix + id + "/"
+}
+
+// objectName is the name bucket/object is stored under in batch id.
+func (t *Trash) objectName(id, bucket, object string) string {
+	return t.batchPrefix(id) + bucket + "/" + object
+}
+
+// batchID returns the batch a trash object name belongs to; ok is false for
+// names that are not inside a batch.
+func (t *Trash) batchID(name string) (id string, ok bool) {
+	id, _, ok = strings.Cut(strings.TrimPrefix(name, t.Prefix), "/")
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// TrashBatch summarizes one rm run in the trash.
+type TrashBatch struct {
+	ID      string    `json:"id"`
+	Deleted time.Time `json:"deleted"`
+	Objects int       `json:"objects"`
+	Bytes   int64     `json:"bytes"`
+	// Source is the longest common prefix of the original paths, i.e. what
+	// was removed
+	Source string `json:"source"`
+}
+
+// trashBatch moves the objects of one rm run into the trash.
+type trashBatch struct {
+	trash   *Trash
+	id      string
+	deleted time.Time
+}
+
+// newTrashBatch starts a new batch in t.
+func newTrashBatch(t *Trash) *trashBatch {
+	return &trashBatch{trash: t, id: newRunID(), deleted: time.Now()}
+}
+
+// printRestoreHint tells how to undo the batch.
+func (b *trashBatch) printRestoreHint() {
+	fmt.Printf("Moved to trash %s as batch %s (undo with 'cio trash restore %s')\n", b.trash, b.id, b.id)
+}
+
+// move copies bucket/object into the batch, recording its original path in
+// the copy's metadata, then deletes it. Like a move, both steps are pinned to
+// the generation read up front.
+func (b *trashBatch) move(ctx context.Context, client *storage.Client, bucket, object string) error {
+	src := client.Bucket(bucket).Object(object)
+	apilog.Logf("[GCS] Object.Attrs(gs://%s/%s) for trash", bucket, object)
+	attrs, err := src.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkHolds(attrs); err != nil {
+		return err
+	}
+
+	metadata := make(map[string]string, len(attrs.Metadata)+2)
+	for k, v := range attrs.Metadata {
+		metadata[k] = v
+	}
+	metadata[TrashSourceMetadataKey] = fmt.Sprintf("gs://%s/%s", bucket, object)
+	metadata[TrashDeletedMetadataKey] = b.deleted.UTC().Format(time.RFC3339)
+
+	dstName := b.trash.objectName(b.id, bucket, object)
+	copier, err := sameKeyCopier(client.Bucket(b.trash.Bucket).Object(dstName), src.Generation(attrs.Generation), attrs)
+	if err != nil {
+		return err
+	}
+	copier.ObjectAttrs = copyableAttrs(attrs, metadata)
+	apilog.Logf("[GCS] Object.Rewrite(gs://%s/%s → gs://%s/%s) for trash", bucket, object, b.trash.Bucket, dstName)
+	if _, err := copier.Run(ctx); err != nil {
+		return fmt.Errorf("failed to copy to trash: %w", err)
+	}
+
+	apilog.Logf("[GCS] Object.Delete(gs://%s/%s) for trash", bucket, object)
+	err = src.If(storage.Conditions{GenerationMatch: attrs.Generation}).Delete(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		// The copy is in the trash and the source is gone, e.g. a retried
+		// delete that had succeeded
+		return nil
+	}
+	return holdError(err, bucket, object)
+}
+
+// copyableAttrs returns the attributes a rewrite must set to keep the
+// object's headers, storage class and (replaced) custom metadata: a rewrite
+// that sets any attribute resets all the others.
+func copyableAttrs(attrs *storage.ObjectAttrs, metadata map[string]string) storage.ObjectAttrs {
+	return storage.ObjectAttrs{
+		ContentType:        attrs.ContentType,
+		ContentEncoding:    attrs.ContentEncoding,
+		ContentLanguage:    attrs.ContentLanguage,
+		ContentDisposition: attrs.ContentDisposition,
+		CacheControl:       attrs.CacheControl,
+		StorageClass:       attrs.StorageClass,
+		Metadata:           metadata,
+	}
+}
+
+// trashSource returns the original path of a trashed object, from its
+// metadata or else from its name below the batch prefix.
+func trashSource(t *Trash, id string, attrs *storage.ObjectAttrs) string {
+	if src := attrs.Metadata[TrashSourceMetadataKey]; src != "" {
+		return src
+	}
+	return "gs://" + strings.TrimPrefix(attrs.Name, t.batchPrefix(id))
+}
+
+// ListTrash returns the batches in t, newest first.
+func ListTrash(ctx context.Context, client *storage.Client, t *Trash) ([]*TrashBatch, error) {
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for trash", t.Bucket, t.Prefix)
+	it := client.Bucket(t.Bucket).Objects(ctx, &storage.Query{Prefix: t.Prefix})
+
+	batches := make(map[string]*TrashBatch)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list trash: %w", err)
+		}
+		id, ok := t.batchID(attrs.Name)
+		if !ok {
+			continue
+		}
+		source := trashSource(t, id, attrs)
+		b := batches[id]
+		if b == nil {
+			b = &TrashBatch{ID: id, Deleted: attrs.Created, Source: source}
+			batches[id] = b
+		}
+		b.Objects++
+		b.Bytes += attrs.Size
+		if attrs.Created.Before(b.Deleted) {
+			b.Deleted = attrs.Created
+		}
+		b.Source = commonPathPrefix(b.Source, source)
+	}
+
+	result := make([]*TrashBatch, 0, len(batches))
+	for _, b := range batches {
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Deleted.After(result[j].Deleted) })
+	return result, nil
+}
+
+// commonPathPrefix returns the longest common prefix of a and b that ends
+// at a "/" (or all of a if a == b).
+func commonPathPrefix(a, b string) string {
+	if a == b {
+		return a
+	}
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:strings.LastIndex(a[:n], "/")+1]
+}
+
+// TrashedObject is one object of a trash batch.
+type TrashedObject struct {
+	Source string    `json:"source"`
+	Size   int64     `json:"size"`
+	Trash  string    `json:"trash"`
+	Stored time.Time `json:"stored"`
+}
+
+// ListTrashBatch returns the objects of batch id.
+func ListTrashBatch(ctx context.Context, client *storage.Client, t *Trash, id string) ([]TrashedObject, error) {
+	prefix := t.batchPrefix(id)
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for trash", t.Bucket, prefix)
+	it := client.Bucket(t.Bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+
+	var objects []TrashedObject
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list trash: %w", err)
+		}
+		objects = append(objects, TrashedObject{
+			Source: trashSource(t, id, attrs),
+			Size:   attrs.Size,
+			Trash:  fmt.Sprintf("gs://%s/%s", t.Bucket, attrs.Name),
+			Stored: attrs.Created,
+		})
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("trash batch %q not found in %s (see 'cio trash ls')", id, t)
+	}
+	return objects, nil
+}
+
+// RestoreTrash moves the objects of batch id back to their original paths.
+// An object that has been recreated at its original path since is not
+// overwritten unless force is set; it stays in the trash.
+func RestoreTrash(ctx context.Context, client *storage.Client, t *Trash, id string, force bool, formatter PathFormatter, maxWorkers int) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	prefix := t.batchPrefix(id)
+	apilog.Logf("[GCS] Objects.List(bucket=%s, prefix=%q) for restore", t.Bucket, prefix)
+	it := client.Bucket(t.Bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	var trashed []*storage.ObjectAttrs
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list trash: %w", err)
+		}
+		trashed = append(trashed, attrs)
+	}
+	totalCount := len(trashed)
+	if totalCount == 0 {
+		return fmt.Errorf("trash batch %q not found in %s (see 'cio trash ls')", id, t)
+	}
+
+	type restored struct {
+		source string
+		size   int64
+		err    error
+	}
+	results := make(chan restored, totalCount)
+	sem := make(chan struct{}, maxWorkers)
+	var wg sync.WaitGroup
+	var completedCount, failedCount int32
+	var restoredBytes int64
+	var firstErr error
+
+	done := make(chan struct{})
+	go func() {
+		for r := range results {
+			count := atomic.AddInt32(&completedCount, 1)
+			if r.err != nil {
+				atomic.AddInt32(&failedCount, 1)
+				fmt.Printf("Failed %d/%d: %s - %v\n", count, totalCount, formatter(r.source), r.err)
+				if firstErr == nil {
+					firstErr = r.err
+				}
+				continue
+			}
+			restoredBytes += r.size
+			fmt.Printf("Restored %d/%d: %s (%s)\n", count, totalCount, formatter(r.source), FormatSize(r.size))
+		}
+		close(done)
+	}()
+
+	for _, attrs := range trashed {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(attrs *storage.ObjectAttrs) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			source := trashSource(t, id, attrs)
+			results <- restored{source: source, size: attrs.Size, err: restoreOne(ctx, client, t, attrs, source, force)}
+		}(attrs)
+	}
+
+	wg.Wait()
+	close(results)
+	<-done
+
+	if failed := atomic.LoadInt32(&failedCount); failed > 0 {
+		return fmt.Errorf("restore failed: %d of %d object(s) not restored (still in the trash): %w", failed, totalCount, firstErr)
+	}
+	if totalCount > 1 {
+		fmt.Printf("\nTotal: %d objects restored (%s)\n", totalCount, FormatSize(restoredBytes))
+	}
+	return nil
+}
+
+// errRestoreExists explains a restore skipped because of a newer object.
+var errRestoreExists = errors.New("an object exists at the original path (use --force to overwrite)")
+
+// isPreconditionFailed reports whether err is an HTTP 412 from GCS.
+func isPreconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == 412
+}
+
+// restoreOne copies a trashed object back to source without the trash
+// metadata, then deletes it from the trash.
+func restoreOne(ctx context.Context, client *storage.Client, t *Trash, attrs *storage.ObjectAttrs, source string, force bool) error {
+	bucket, object, err := resolver.ParseGCSPath(source)
+	if err != nil || bucket == "" || object == "" {
+		return fmt.Errorf("invalid original path %q", source)
+	}
+
+	metadata := make(map[string]string, len(attrs.Metadata))
+	for k, v := range attrs.Metadata {
+		if k != TrashSourceMetadataKey && k != TrashDeletedMetadataKey {
+			metadata[k] = v
+		}
+	}
+
+	trashed := client.Bucket(t.Bucket).Object(attrs.Name)
+	dst := client.Bucket(bucket).Object(object)
+	if !force {
+		dst = dst.If(storage.Conditions{DoesNotExist: true})
+	}
+	copier, err := sameKeyCopier(dst, trashed.Generation(attrs.Generation), attrs)
+	if err != nil {
+		return err
+	}
+	copier.ObjectAttrs = copyableAttrs(attrs, metadata)
+	apilog.Logf("[GCS] Object.Rewrite(gs://%s/%s → %s) for restore", t.Bucket, attrs.Name, source)
+	if _, err := copier.Run(ctx); err != nil {
+		if isPreconditionFailed(err) {
+			return errRestoreExists
+		}
+		return err
+	}
+
+	apilog.Logf("[GCS] Object.Delete(gs://%s/%s) after restore", t.Bucket, attrs.Name)
+	if err := trashed.If(storage.Conditions{GenerationMatch: attrs.Generation}).Delete(ctx); err != nil {
+		return fmt.Errorf("restored, but not removed from the trash: %w", err)
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes the given batches.
+func PurgeTrash(ctx context.Context, client *storage.Client, t *Trash, ids []string, formatter PathFormatter, maxWorkers int) error {
+	if formatter == nil {
+		formatter = DefaultPathFormatter
+	}
+
+	enumerate := func(ctx context.Context, send func(item workItem)) error {
+		for _, id := range ids {
+			if err := enumerateForDelete(ctx, client, t.Bucket, t.batchPrefix(id), LiveVersions, nil, send); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return deleteObjectsStream(ctx, client, t.Bucket, enumerate,
+		fmt.Sprintf("nothing to purge in %s", t), formatter, maxWorkers)
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"regexp"
+	"testing"
+
+	"cloud.google.com/go/storage"
+)
+
+func TestParseTrash(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantBucket string
+		wantPrefix string
+		wantErr    bool
+	}{
+		{"gs://bucket/.cio-trash/", "bucket", ".cio-trash/", false},
+		{"gs://bucket/.cio-trash", "bucket", ".cio-trash/", false},
+		{"gs://bucket/a/b/trash", "bucket", "a/b/trash/", false},
+		{"gs://bucket/", "bucket", "", false},
+		{"gs://bucket", "bucket", "", false},
+		{"gs://", "", "", true},
+		{"/local/trash/", "", "", true},
+		{":am/trash/", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			trash, err := ParseTrash(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseTrash(%q) = %+v, want error", tt.path, trash)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseTrash(%q) = %v", tt.path, err)
+			}
+			if trash.Bucket != tt.wantBucket || trash.Prefix != tt.wantPrefix {
+				t.Errorf("ParseTrash(%q) = %q, %q, want %q, %q", tt.path, trash.Bucket, trash.Prefix, tt.wantBucket, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestTrashContains(t *testing.T) {
+	trash := &Trash{Bucket: "b", Prefix: ".cio-trash/"}
+	tests := []struct {
+		bucket, object string
+		want           bool
+	}{
+		{"b", ".cio-trash/20240101-120000-ab12/b/x", true},
+		{"b", ".cio-trash/", true},
+		{"b", ".cio-trash", false},
+		{"b", ".cio-trash-old/x", false},
+		{"b", "data/x", false},
+		{"other", ".cio-trash/20240101-120000-ab12/b/x", false},
+	}
+	for _, tt := range tests {
+		if got := trash.Contains(tt.bucket, tt.object); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.bucket, tt.object, got, tt.want)
+		}
+	}
+
+	// A trash at the bucket root holds the whole bucket
+	root := &Trash{Bucket: "b"}
+	if !root.Contains("b", "data/x") {
+		t.Errorf("Contains(%q, %q) = false for a trash at the bucket root", "b", "data/x")
+	}
+}
+
+func TestTrashLayout(t *testing.T) {
+	trash := &Trash{Bucket: "t", Prefix: ".cio-trash/"}
+	batch := newTrashBatch(trash)
+	if !regexp.MustCompile(`^\d{8}-\d{6}-[0-9a-f]{4}$`).MatchString(batch.id) {
+		t.Errorf("batch id %q is not <date>-<time>-<hex>", batch.id)
+	}
+
+	tests := []struct {
+		bucket, object string
+		want           string
+	}{
+		{"data", "logs/a.log", ".cio-trash/ID/data/logs/a.log"},
+		{"data", "a.log", ".cio-trash/ID/data/a.log"},
+		{"t", "dir/", ".cio-trash/ID/t/dir/"},
+	}
+	for _, tt := range tests {
+		name := trash.objectName("ID", tt.bucket, tt.object)
+		if name != tt.want {
+			t.Errorf("objectName(%q, %q) = %q, want %q", tt.bucket, tt.object, name, tt.want)
+		}
+		if id, ok := trash.batchID(name); id != "ID" || !ok {
+			t.Errorf("batchID(%q) = %q, %v, want %q, true", name, id, ok, "ID")
+		}
+		// Without the source metadata the path is recovered from the name
+		want := "gs://" + tt.bucket + "/" + tt.object
+		if got := trashSource(trash, "ID", &storage.ObjectAttrs{Name: name}); got != want {
+			t.Errorf("trashSource(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestTrashBatchID(t *testing.T) {
+	trash := &Trash{Bucket: "t", Prefix: ".cio-trash/"}
+	tests := []struct {
+		name   string
+		wantID string
+		wantOK bool
+	}{
+		{".cio-trash/20240101-120000-ab12/b/x", "20240101-120000-ab12", true},
+		{".cio-trash/20240101-120000-ab12/", "20240101-120000-ab12", true},
+		{".cio-trash/stray-object", "", false},
+		{".cio-trash/", "", false},
+		{".cio-trash//b/x", "", false},
+	}
+	for _, tt := range tests {
+		id, ok := trash.batchID(tt.name)
+		if id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("batchID(%q) = %q, %v, want %q, %v", tt.name, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestTrashSourceMetadata(t *testing.T) {
+	trash := &Trash{Bucket: "t", Prefix: ".cio-trash/"}
+	attrs := &storage.ObjectAttrs{
+		Name:     ".cio-trash/ID/data/renamed",
+		Metadata: map[string]string{TrashSourceMetadataKey: "gs://data/original"},
+	}
+	if got := trashSource(trash, "ID", attrs); got != "gs://data/original" {
+		t.Errorf("trashSource() = %q, want the path recorded in metadata", got)
+	}
+}
+
+func TestCommonPathPrefix(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"gs://b/logs/a.log", "gs://b/logs/a.log", "gs://b/logs/a.log"},
+		{"gs://b/logs/a.log", "gs://b/logs/b.log", "gs://b/logs/"},
+		{"gs://b/logs/2024/a", "gs://b/logs/2023/a", "gs://b/logs/"},
+		{"gs://b/logs/", "gs://b/logs/x", "gs://b/logs/"},
+		{"gs://b/abc", "gs://b/abd", "gs://b/"},
+		{"gs://a/x", "gs://b/x", "gs://"},
+	}
+	for _, tt := range tests {
+		if got := commonPathPrefix(tt.a, tt.b); got != tt.want {
+			t.Errorf("commonPathPrefix(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
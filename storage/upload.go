@@ -6,8 +6,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/thieso2/cio/apilog"
@@ -24,6 +27,8 @@ type fileUpload struct {
 	localPath   string
 	objectPath  string
 	fullGCSPath string
+	mtime       time.Time // if set, recorded in object metadata (see MtimeMetadataKey)
+	size        int64     // local file size, for progress reporting
 }
 
 // PathFormatter is a function that formats GCS paths for display
@@ -35,8 +40,10 @@ func DefaultPathFormatter(gcsPath string) string {
 	return gcsPath
 }
 
-// UploadFile uploads a single file to GCS
-func UploadFile(ctx context.Context, client *storage.Client, localPath, gcsPath string, verbose bool, formatter PathFormatter) error {
+// UploadFile uploads a single file to GCS.
+// Files at or above opts.ParallelThreshold are uploaded as parallel composite
+// parts (see uploadFileComposite); pass nil opts to always upload in one stream.
+func UploadFile(ctx context.Context, client *storage.Client, localPath, gcsPath string, verbose bool, formatter PathFormatter, opts *UploadOptions) error {
 	if formatter == nil {
 		formatter = DefaultPathFormatter
 	}
@@ -72,13 +79,35 @@ func UploadFile(ctx context.Context, client *storage.Client, localPath, gcsPath
 		fmt.Printf("Uploading %s to %s (%d bytes)\n", localPath, formatter(fullGCSPath), fileInfo.Size())
 	}
 
+	verify := opts == nil || !opts.NoVerify
+
+	if opts.useComposite(fileInfo.Size()) {
+		numParts, err := uploadFileComposite(ctx, client, bucket, objectPath, localPath, nil, verbose, opts)
+		if err != nil {
+			return fmt.Errorf("failed to upload file: %w", err)
+		}
+		fmt.Printf("Uploaded: %s → %s (%s, %d parts%s)\n", localPath, formatter(fullGCSPath), FormatSize(fileInfo.Size()), numParts, verifiedSuffix(verify))
+		return nil
+	}
+
 	// Create GCS object writer
 	obj := client.Bucket(bucket).Object(objectPath)
 	apilog.Logf("[GCS] Object.NewWriter(gs://%s/%s)", bucket, objectPath)
-	writer := obj.NewWriter(ctx)
+	writer := opts.destination(obj).NewWriter(ctx)
+	encryptWriter(writer)
+	if opts != nil {
+		opts.Metadata.applyTo(&writer.ObjectAttrs)
+	}
+	if verify {
+		if err := sendFileCRC32C(writer, localPath); err != nil {
+			writer.Close()
+			return fmt.Errorf("failed to checksum file: %w", err)
+		}
+	}
 
-	// Copy file contents to GCS
-	if _, err := io.Copy(writer, file); err != nil {
+	// Copy file contents to GCS, hashing what is sent
+	h := newTransferHash()
+	if _, err := io.Copy(writer, opts.limit(ctx, io.TeeReader(file, h))); err != nil {
 		writer.Close()
 		return fmt.Errorf("failed to upload file: %w", err)
 	}
@@ -88,12 +117,18 @@ func UploadFile(ctx context.Context, client *storage.Client, localPath, gcsPath
 		return fmt.Errorf("failed to close writer: %w", err)
 	}
 
-	fmt.Printf("Uploaded: %s → %s (%s)\n", localPath, formatter(fullGCSPath), FormatSize(fileInfo.Size()))
+	if verify {
+		if err := verifyUploadedObject(writer.Attrs(), h); err != nil {
+			return fmt.Errorf("upload of %s failed verification: %w", localPath, err)
+		}
+	}
+
+	fmt.Printf("Uploaded: %s → %s (%s%s)\n", localPath, formatter(fullGCSPath), FormatSize(fileInfo.Size()), verifiedSuffix(verify))
 	return nil
 }
 
 // UploadDirectory uploads a directory recursively to GCS
-func UploadDirectory(ctx context.Context, client *storage.Client, localPath, gcsPath string, verbose bool, formatter PathFormatter, maxWorkers int) error {
+func UploadDirectory(ctx context.Context, client *storage.Client, localPath, gcsPath string, verbose bool, formatter PathFormatter, maxWorkers int, opts *UploadOptions) error {
 	if formatter == nil {
 		formatter = DefaultPathFormatter
 	}
@@ -112,27 +147,39 @@ func UploadDirectory(ctx context.Context, client *storage.Client, localPath, gcs
 	// Get the directory name
 	dirName := filepath.Base(localPath)
 
-	// First pass: count total files
+	// First pass: count total files, leaving out unfinished downloads (the
+	// journal and the partial file it describes)
 	var filesToUpload []fileUpload
+	partial := make(map[string]bool)
 
 	err = filepath.Walk(localPath, func(path string, info os.FileInfo, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
 
-		// Skip directories themselves
-		if info.IsDir() {
-			return nil
-		}
-
 		// Calculate relative path
 		relPath, err := filepath.Rel(localPath, path)
 		if err != nil {
 			return fmt.Errorf("failed to get relative path: %w", err)
 		}
+		relPath = filepath.ToSlash(relPath)
+
+		// Skip directories themselves, and excluded trees entirely
+		if info.IsDir() {
+			if opts != nil && relPath != "." && opts.Filter.SkipDir(relPath) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isDownloadJournal(path) {
+			partial[strings.TrimSuffix(path, DownloadJournalSuffix)] = true
+			return nil
+		}
+		if opts != nil && !opts.Filter.Match(relPath) {
+			return nil
+		}
 
 		// Convert to GCS path (use forward slashes)
-		relPath = filepath.ToSlash(relPath)
 		objectPath := basePrefix + dirName + "/" + relPath
 		fullGCSPath := fmt.Sprintf("gs://%s/%s", bucket, objectPath)
 
@@ -140,6 +187,7 @@ func UploadDirectory(ctx context.Context, client *storage.Client, localPath, gcs
 			localPath:   path,
 			objectPath:  objectPath,
 			fullGCSPath: fullGCSPath,
+			size:        info.Size(),
 		})
 
 		return nil
@@ -148,21 +196,44 @@ func UploadDirectory(ctx context.Context, client *storage.Client, localPath, gcs
 	if err != nil {
 		return err
 	}
+	if len(partial) > 0 {
+		kept := filesToUpload[:0]
+		for _, fu := range filesToUpload {
+			if !partial[fu.localPath] {
+				kept = append(kept, fu)
+			}
+		}
+		filesToUpload = kept
+	}
 
 	totalCount := len(filesToUpload)
 
+	if opts != nil && opts.Transfers != nil && totalCount > 0 {
+		items := make([]TransferItem, len(filesToUpload))
+		for i, fu := range filesToUpload {
+			items[i] = TransferItem{Object: fu.objectPath, Local: fu.localPath, Size: fu.size}
+		}
+		o := *opts
+		o.transfer = startTransfer(opts.Transfers, TransferUpload, localPath, gcsPath, bucket, items)
+		opts = &o
+	}
+
 	// Second pass: upload in parallel with progress counter
-	return uploadFilesParallel(ctx, client, bucket, filesToUpload, totalCount, verbose, formatter, maxWorkers)
+	return uploadFilesParallel(ctx, client, bucket, filesToUpload, totalCount, verbose, formatter, maxWorkers, opts)
 }
 
-// uploadFilesParallel uploads files in parallel with controlled concurrency
-func uploadFilesParallel(ctx context.Context, client *storage.Client, bucket string, filesToUpload []fileUpload, totalCount int, verbose bool, formatter PathFormatter, maxWorkers int) error {
+// uploadFilesParallel uploads files in parallel with controlled concurrency.
+// Large files are uploaded as parallel composite parts when opts allows it.
+func uploadFilesParallel(ctx context.Context, client *storage.Client, bucket string, filesToUpload []fileUpload, totalCount int, verbose bool, formatter PathFormatter, maxWorkers int, opts *UploadOptions) error {
 	// Create a semaphore to limit concurrent uploads
 	sem := make(chan struct{}, maxWorkers)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	var firstErr error
 	var completedCount int32
+	var verifiedBytes int64
+	var verifiedCount int32
+	verify := opts == nil || !opts.NoVerify
 
 	// Channel for completed uploads (for progress tracking)
 	type upload struct {
@@ -173,14 +244,24 @@ func uploadFilesParallel(ctx context.Context, client *storage.Client, bucket str
 	}
 	uploads := make(chan upload, totalCount)
 
+	var totalSize int64
+	for _, fu := range filesToUpload {
+		totalSize += fu.size
+	}
+	progress := newProgressTracker("upload", int64(totalCount), totalSize)
+
 	// Start progress reporter goroutine
 	done := make(chan struct{})
 	go func() {
 		for u := range uploads {
 			count := atomic.AddInt32(&completedCount, 1)
+			progress.objectDone(u.err)
+			if opts != nil {
+				opts.transfer.record(u.localPath, u.err)
+			}
 
 			if u.err != nil {
-				fmt.Printf("Failed %d/%d: %s - %v\n", count, totalCount, u.localPath, u.err)
+				progress.Printf("Failed %d/%d: %s - %v\n", count, totalCount, u.localPath, u.err)
 
 				// Store first error
 				mu.Lock()
@@ -189,11 +270,15 @@ func uploadFilesParallel(ctx context.Context, client *storage.Client, bucket str
 				}
 				mu.Unlock()
 			} else {
+				if verify {
+					verifiedBytes += u.bytesWritten
+					verifiedCount++
+				}
 				size := FormatSize(u.bytesWritten)
 				if verbose {
-					fmt.Printf("Uploaded %d/%d: %s to %s (%s)\n", count, totalCount, u.localPath, formatter(u.fullGCSPath), size)
+					progress.Printf("Uploaded %d/%d: %s to %s (%s)\n", count, totalCount, u.localPath, formatter(u.fullGCSPath), size)
 				} else {
-					fmt.Printf("Uploaded %d/%d: %s → %s (%s)\n", count, totalCount, u.localPath, formatter(u.fullGCSPath), size)
+					progress.Printf("Uploaded %d/%d: %s → %s (%s)\n", count, totalCount, u.localPath, formatter(u.fullGCSPath), size)
 				}
 			}
 		}
@@ -227,13 +312,44 @@ func uploadFilesParallel(ctx context.Context, client *storage.Client, bucket str
 				return
 			}
 
+			var metadata map[string]string
+			if !fileUpload.mtime.IsZero() {
+				metadata = map[string]string{MtimeMetadataKey: strconv.FormatInt(fileUpload.mtime.Unix(), 10)}
+			}
+
+			if opts.useComposite(info.Size()) {
+				if _, err := uploadFileComposite(ctx, client, bucket, fileUpload.objectPath, fileUpload.localPath, metadata, verbose, opts); err != nil {
+					uploads <- upload{localPath: fileUpload.localPath, fullGCSPath: fileUpload.fullGCSPath, err: err}
+					return
+				}
+				// Parts are not streamed through the progress reader
+				progress.addBytes(info.Size())
+				uploads <- upload{localPath: fileUpload.localPath, fullGCSPath: fileUpload.fullGCSPath, bytesWritten: info.Size(), err: nil}
+				return
+			}
+
 			// Create GCS object writer
 			obj := bkt.Object(fileUpload.objectPath)
 			apilog.Logf("[GCS] Object.NewWriter(%s)", fileUpload.fullGCSPath)
-			writer := obj.NewWriter(ctx)
+			writer := retryUpload(withWriteKey(obj)).NewWriter(ctx)
+			encryptWriter(writer)
+			if metadata != nil {
+				writer.Metadata = metadata
+			}
+			if opts != nil {
+				opts.Metadata.applyTo(&writer.ObjectAttrs)
+			}
+			if verify {
+				if err := sendFileCRC32C(writer, fileUpload.localPath); err != nil {
+					writer.Close()
+					uploads <- upload{localPath: fileUpload.localPath, fullGCSPath: fileUpload.fullGCSPath, err: err}
+					return
+				}
+			}
 
-			// Copy file contents
-			if _, err := io.Copy(writer, file); err != nil {
+			// Copy file contents, hashing what is sent
+			h := newTransferHash()
+			if _, err := io.Copy(writer, progress.reader(opts.limit(ctx, io.TeeReader(file, h)))); err != nil {
 				writer.Close()
 				uploads <- upload{localPath: fileUpload.localPath, fullGCSPath: fileUpload.fullGCSPath, err: err}
 				return
@@ -245,6 +361,13 @@ func uploadFilesParallel(ctx context.Context, client *storage.Client, bucket str
 				return
 			}
 
+			if verify {
+				if err := verifyUploadedObject(writer.Attrs(), h); err != nil {
+					uploads <- upload{localPath: fileUpload.localPath, fullGCSPath: fileUpload.fullGCSPath, err: err}
+					return
+				}
+			}
+
 			// Send result to progress reporter
 			uploads <- upload{localPath: fileUpload.localPath, fullGCSPath: fileUpload.fullGCSPath, bytesWritten: info.Size(), err: nil}
 		}(fu)
@@ -256,6 +379,10 @@ func uploadFilesParallel(ctx context.Context, client *storage.Client, bucket str
 
 	// Wait for progress reporter to finish
 	<-done
+	progress.finish()
+	if opts != nil {
+		finishTransfer(opts.transfer, firstErr != nil)
+	}
 
 	if firstErr != nil {
 		return fmt.Errorf("upload failed: %w", firstErr)
@@ -263,6 +390,7 @@ func uploadFilesParallel(ctx context.Context, client *storage.Client, bucket str
 
 	if totalCount > 1 {
 		fmt.Printf("\nTotal files uploaded: %d\n", totalCount)
+		printVerifiedSummary(verifiedBytes, verifiedCount)
 	}
 	return nil
 }
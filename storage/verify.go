@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"cloud.google.com/go/storage"
+)
+
+// ErrChecksumMismatch is returned when transferred content does not match the
+// checksums GCS stores for the object.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// transferHash computes CRC32C and MD5 over content as it is streamed, so a
+// transfer can be verified against the object's stored checksums without a
+// second pass.
+type transferHash struct {
+	crc hash.Hash32
+	md5 hash.Hash
+	n   int64
+}
+
+func newTransferHash() *transferHash {
+	return &transferHash{crc: crc32.New(crc32cTable), md5: md5.New()}
+}
+
+// Write implements io.Writer.
+func (h *transferHash) Write(p []byte) (int, error) {
+	h.crc.Write(p)
+	h.md5.Write(p)
+	h.n += int64(len(p))
+	return len(p), nil
+}
+
+// verify compares the hashed content against the expected CRC32C and, when
+// wantMD5 is non-empty, the expected MD5. Composite objects have no MD5, so
+// only CRC32C is guaranteed to be available.
+func (h *transferHash) verify(wantCRC uint32, wantMD5 []byte) error {
+	if got := h.crc.Sum32(); got != wantCRC {
+		return fmt.Errorf("%w: CRC32C is %08x, expected %08x", ErrChecksumMismatch, got, wantCRC)
+	}
+	if len(wantMD5) > 0 {
+		if got := h.md5.Sum(nil); !bytes.Equal(got, wantMD5) {
+			return fmt.Errorf("%w: MD5 is %x, expected %x", ErrChecksumMismatch, got, wantMD5)
+		}
+	}
+	return nil
+}
+
+// hashLocalFile hashes a local file. Used where content is not streamed in
+// order, e.g. after reassembling a chunked download.
+func hashLocalFile(path string) (*transferHash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := newTransferHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// verifyUploadedObject checks a just-written object against the hash of the
+// bytes that were sent and returns an error wrapping ErrChecksumMismatch if
+// they differ. The object is kept: it has already replaced the previous live
+// object, so deleting it would leave nothing at the path. File uploads send
+// their CRC32C up front (sendFileCRC32C) so GCS rejects corrupt data before
+// the commit; this check covers streams, whose checksum is not known early.
+func verifyUploadedObject(attrs *storage.ObjectAttrs, h *transferHash) error {
+	if err := h.verify(attrs.CRC32C, attrs.MD5); err != nil {
+		return fmt.Errorf("%w (object gs://%s/%s#%d kept; upload it again)", err, attrs.Bucket, attrs.Name, attrs.Generation)
+	}
+	return nil
+}
+
+// verifyUploadedCRC32C is verifyUploadedObject for content known only by
+// its CRC32C, e.g. a composite upload, whose checksum is combined from those
+// of its parts.
+func verifyUploadedCRC32C(attrs *storage.ObjectAttrs, crc uint32) error {
+	if crc != attrs.CRC32C {
+		return fmt.Errorf("%w: CRC32C is %08x, expected %08x (object gs://%s/%s#%d kept; upload it again)",
+			ErrChecksumMismatch, crc, attrs.CRC32C, attrs.Bucket, attrs.Name, attrs.Generation)
+	}
+	return nil
+}
+
+// crc32cCombine returns the CRC32C of the concatenation of two byte strings
+// from their CRC32Cs and the length of the second one, without reading the
+// bytes again (zlib's crc32_combine, for the Castagnoli polynomial).
+func crc32cCombine(crc1, crc2 uint32, len2 int64) uint32 {
+	if len2 <= 0 {
+		return crc1
+	}
+
+	// odd is the operator that appends one zero bit to the message
+	var even, odd [32]uint32
+	odd[0] = crc32.Castagnoli
+	row := uint32(1)
+	for i := 1; i < 32; i++ {
+		odd[i] = row
+		row <<= 1
+	}
+	gf2MatrixSquare(&even, &odd) // two zero bits
+	gf2MatrixSquare(&odd, &even) // four zero bits
+
+	// Append len2 zero bytes to crc1, squaring the operator for each bit
+	// of len2
+	for {
+		gf2MatrixSquare(&even, &odd)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&even, crc1)
+		}
+		if len2 >>= 1; len2 == 0 {
+			break
+		}
+		gf2MatrixSquare(&odd, &even)
+		if len2&1 != 0 {
+			crc1 = gf2MatrixTimes(&odd, crc1)
+		}
+		if len2 >>= 1; len2 == 0 {
+			break
+		}
+	}
+	return crc1 ^ crc2
+}
+
+func gf2MatrixTimes(mat *[32]uint32, vec uint32) uint32 {
+	var sum uint32
+	for i := 0; vec != 0; i, vec = i+1, vec>>1 {
+		if vec&1 != 0 {
+			sum ^= mat[i]
+		}
+	}
+	return sum
+}
+
+func gf2MatrixSquare(square, mat *[32]uint32) {
+	for i := range square {
+		square[i] = gf2MatrixTimes(mat, mat[i])
+	}
+}
+
+// sendFileCRC32C computes the CRC32C of the file at path and sets it on w,
+// so GCS refuses to commit the upload if the bytes it receives differ.
+func sendFileCRC32C(w *storage.Writer, path string) error {
+	sum, err := fileCRC32C(path)
+	if err != nil {
+		return err
+	}
+	w.CRC32C = sum
+	w.SendCRC32C = true
+	return nil
+}
+
+// verifiedSuffix annotates a size in transfer output when the content was verified.
+func verifiedSuffix(verified bool) string {
+	if verified {
+		return ", verified"
+	}
+	return ""
+}
+
+// printVerifiedSummary prints the total verified by a multi-file transfer.
+func printVerifiedSummary(total int64, files int32) {
+	if files > 0 {
+		fmt.Printf("Verified: %s in %d file(s) (CRC32C)\n", FormatSize(total), files)
+	}
+}
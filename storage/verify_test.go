@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"bytes"
+	"hash/crc32"
+	"testing"
+)
+
+func TestCRC32CCombine(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdefghijklmnopqrstuvwxyz"), 300)
+	tests := []struct {
+		name  string
+		split []int // lengths of the parts
+	}{
+		{"two parts", []int{1000, len(data) - 1000}},
+		{"empty second part", []int{len(data), 0}},
+		{"empty first part", []int{0, len(data)}},
+		{"single bytes", []int{1, 1, 1, len(data) - 3}},
+		{"many parts", []int{3000, 3000, 3000, 1800}},
+	}
+
+	want := crc32.Checksum(data, crc32cTable)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var crc uint32
+			offset := 0
+			for i, n := range tt.split {
+				sum := crc32.Checksum(data[offset:offset+n], crc32cTable)
+				if i == 0 {
+					crc = sum
+				} else {
+					crc = crc32cCombine(crc, sum, int64(n))
+				}
+				offset += n
+			}
+			if offset != len(data) {
+				t.Fatalf("parts cover %d bytes, want %d", offset, len(data))
+			}
+			if crc != want {
+				t.Errorf("combined CRC32C = %08x, want %08x", crc, want)
+			}
+		})
+	}
+}